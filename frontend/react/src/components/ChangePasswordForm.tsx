@@ -0,0 +1,53 @@
+import React, { useState } from 'react';
+import { apiService } from '../services/api';
+
+interface ChangePasswordFormProps {
+  playerId: number;
+}
+
+// ChangePasswordForm sets a new password; other devices are logged out
+export const ChangePasswordForm: React.FC<ChangePasswordFormProps> = ({ playerId }) => {
+  const [oldPassword, setOldPassword] = useState('');
+  const [newPassword, setNewPassword] = useState('');
+  const [message, setMessage] = useState('');
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      await apiService.changePassword(playerId, oldPassword, newPassword);
+      setOldPassword('');
+      setNewPassword('');
+      setMessage('Password changed. Your other devices have been logged out.');
+    } catch (error: any) {
+      setMessage(error.message);
+    }
+  };
+
+  return (
+    <div className="card">
+      <h3 className="card-title">Change Password</h3>
+      <form onSubmit={handleSubmit}>
+        <input
+          type="password"
+          value={oldPassword}
+          onChange={e => setOldPassword(e.target.value)}
+          className="input"
+          placeholder="Current password"
+          autoComplete="current-password"
+          required
+        />
+        <input
+          type="password"
+          value={newPassword}
+          onChange={e => setNewPassword(e.target.value)}
+          className="input"
+          placeholder="New password"
+          autoComplete="new-password"
+          required
+        />
+        <button type="submit" className="btn-primary">Change Password</button>
+      </form>
+      {message && <p style={{ fontSize: '0.875rem', marginTop: '8px' }}>{message}</p>}
+    </div>
+  );
+};
@@ -0,0 +1,70 @@
+import React, { useEffect, useState } from 'react';
+import { apiService } from '../services/api';
+import type { BattleEvent } from '../types';
+
+interface LiveBattleLogProps {
+  battleId: number;
+  onEnd?: (winnerId?: number) => void;
+}
+
+const statusApplied: Record<string, string> = {
+  burn: 'was burned!',
+  poison: 'was poisoned!',
+  paralysis: 'is paralyzed! It may be unable to move!',
+  sleep: 'fell asleep!',
+  freeze: 'was frozen solid!',
+  confusion: 'became confused!'
+};
+
+const formatEvent = (e: BattleEvent): string | null => {
+  switch (e.type) {
+    case 'battle_start':
+      return '⚔️ Battle Start!';
+    case 'switch_in':
+      return `${e.actor_name} enters the battle! (HP: ${e.remaining_hp})`;
+    case 'switch_out':
+      return `${e.actor_name}, come back!`;
+    case 'round_start':
+      return `=== Round ${e.round} ===`;
+    case 'move':
+      if (e.effectiveness === undefined) return `${e.actor_name} used ${e.move}!`;
+      return `${e.actor_name} used ${e.move} for ${e.damage ?? 0} damage! ${e.target_name} HP: ${e.remaining_hp}`;
+    case 'miss':
+      return `${e.actor_name} used ${e.move}, but it missed!`;
+    case 'move_failed':
+      return 'But it failed!';
+    case 'recoil':
+    case 'status_damage':
+      return `${e.actor_name} took ${e.damage} damage! HP: ${e.remaining_hp}`;
+    case 'status_applied':
+      return `${e.actor_name} ${statusApplied[e.status ?? ''] ?? ''}`;
+    case 'status_cured':
+      return `${e.actor_name} is no longer affected by ${e.status}.`;
+    case 'cant_move':
+      return `${e.actor_name} can't move! (${e.status})`;
+    case 'faint':
+      return `${e.actor_name} fainted!`;
+    case 'battle_end':
+      return `🏆 ${e.actor_name} wins!`;
+    default:
+      return null;
+  }
+};
+
+export const LiveBattleLog: React.FC<LiveBattleLogProps> = ({ battleId, onEnd }) => {
+  const [events, setEvents] = useState<BattleEvent[]>([]);
+
+  useEffect(() => {
+    setEvents([]);
+    return apiService.streamBattle(battleId, message => {
+      setEvents(prev => [...prev.slice(0, message.offset), ...message.events]);
+      if (message.type === 'end' && onEnd) onEnd(message.winner_id);
+    });
+  }, [battleId, onEnd]);
+
+  return (
+    <pre className="battle-log">
+      {events.map(formatEvent).filter(line => line !== null).join('\n')}
+    </pre>
+  );
+};
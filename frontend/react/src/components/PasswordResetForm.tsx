@@ -0,0 +1,81 @@
+import React, { useState } from 'react';
+import { apiService } from '../services/api';
+
+interface PasswordResetFormProps {
+  onDone: () => void;
+}
+
+// PasswordResetForm asks for a reset token, then sets a new password with it
+export const PasswordResetForm: React.FC<PasswordResetFormProps> = ({ onDone }) => {
+  const [username, setUsername] = useState('');
+  const [token, setToken] = useState('');
+  const [newPassword, setNewPassword] = useState('');
+  const [requested, setRequested] = useState(false);
+  const [message, setMessage] = useState('');
+
+  const handleRequest = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      await apiService.requestPasswordReset(username);
+      setRequested(true);
+      setMessage('If that player exists, a reset token is on its way.');
+    } catch (error: any) {
+      setMessage(error.message);
+    }
+  };
+
+  const handleReset = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      await apiService.resetPassword(token, newPassword);
+      onDone();
+    } catch (error: any) {
+      setMessage(error.message);
+    }
+  };
+
+  return (
+    <div>
+      {!requested ? (
+        <form onSubmit={handleRequest}>
+          <input
+            type="text"
+            value={username}
+            onChange={e => setUsername(e.target.value)}
+            className="input"
+            placeholder="Username"
+            autoComplete="username"
+            required
+          />
+          <button type="submit" className="btn-primary" style={{ width: '100%' }}>
+            Send Reset Token
+          </button>
+        </form>
+      ) : (
+        <form onSubmit={handleReset}>
+          <input
+            type="text"
+            value={token}
+            onChange={e => setToken(e.target.value)}
+            className="input"
+            placeholder="Reset token"
+            required
+          />
+          <input
+            type="password"
+            value={newPassword}
+            onChange={e => setNewPassword(e.target.value)}
+            className="input"
+            placeholder="New password"
+            autoComplete="new-password"
+            required
+          />
+          <button type="submit" className="btn-primary" style={{ width: '100%' }}>
+            Set New Password
+          </button>
+        </form>
+      )}
+      {message && <p style={{ fontSize: '0.875rem', marginTop: '8px' }}>{message}</p>}
+    </div>
+  );
+};
@@ -0,0 +1,64 @@
+import React, { useEffect, useState } from 'react';
+import { apiService } from '../services/api';
+import type { Session } from '../types';
+
+interface SessionListProps {
+  playerId: number;
+  onLoggedOut: () => void;
+}
+
+// SessionList shows the devices a player is logged in on and lets them log
+// any of them out
+export const SessionList: React.FC<SessionListProps> = ({ playerId, onLoggedOut }) => {
+  const [sessions, setSessions] = useState<Session[]>([]);
+
+  const load = async () => {
+    try {
+      setSessions(await apiService.getSessions(playerId));
+    } catch (error) {
+      console.error('Error loading sessions:', error);
+    }
+  };
+
+  useEffect(() => {
+    load();
+  }, [playerId]);
+
+  const revoke = async (session?: Session) => {
+    try {
+      await apiService.revokeSession(playerId, session?.id);
+      if (!session || session.current) {
+        apiService.logout();
+        onLoggedOut();
+        return;
+      }
+      await load();
+    } catch (error) {
+      console.error('Error revoking session:', error);
+    }
+  };
+
+  return (
+    <div className="card">
+      <div className="card-header">
+        <h3 className="card-title">Logged In Devices</h3>
+        <button onClick={() => revoke()} className="btn-secondary">
+          Log Out Everywhere
+        </button>
+      </div>
+      {sessions.map(session => (
+        <div key={session.id} style={{ display: 'flex', justifyContent: 'space-between', padding: '8px 0' }}>
+          <div>
+            <p>{session.device || 'Unknown device'}{session.current && ' (this device)'}</p>
+            <p style={{ fontSize: '0.75rem', color: '#666' }}>
+              {session.ip} · logged in {new Date(session.issued_at).toLocaleString()}
+            </p>
+          </div>
+          <button onClick={() => revoke(session)} className="btn-secondary">
+            Log Out
+          </button>
+        </div>
+      ))}
+    </div>
+  );
+};
@@ -3,4 +3,8 @@ export { HomeView } from './HomeView';
 export { ProfileView } from './ProfileView';
 export { BattleView } from './BattleView';
 export { BattleResultView } from './BattleResultView';
-export { LeaderboardView } from './LeaderboardView';
\ No newline at end of file
+export { LiveBattleLog } from './LiveBattleLog';
+export { LeaderboardView } from './LeaderboardView';
+export { SessionList } from './SessionList';
+export { ChangePasswordForm } from './ChangePasswordForm';
+export { PasswordResetForm } from './PasswordResetForm';
\ No newline at end of file
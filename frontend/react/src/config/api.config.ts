@@ -4,6 +4,7 @@ export const API_CONFIG = {
     PLAYERS: '/api/players',
     MONSTERS: '/api/monster',
     BATTLES: '/api/battles',
+    MATCHMAKING: '/api/matchmaking',
     RANKINGS: '/api/rankings'
   }
 };
\ No newline at end of file
@@ -10,10 +10,14 @@ export const AdminLayout: React.FC = () => {
     const [monsters, setMonsters] = useState<Monster[]>([]);
     const [leaderboard, setLeaderboard] = useState<LeaderboardEntry[]>([]);
     const [loading, setLoading] = useState(true);
+    const [currentPlayer] = useState<Player | null>(() => {
+        const saved = localStorage.getItem('currentPlayer');
+        return saved ? JSON.parse(saved) : null;
+    });
 
     useEffect(() => {
-        loadData();
-    }, []);
+        if (currentPlayer && currentPlayer.role !== 'player') loadData();
+    }, [currentPlayer]);
 
     const loadData = async () => {
         setLoading(true);
@@ -35,6 +39,23 @@ export const AdminLayout: React.FC = () => {
 
     const isActive = (path: string) => location.pathname === path;
 
+    // The APIs refuse admin actions from anyone else, so don't offer them
+    if (!currentPlayer || currentPlayer.role === 'player') {
+        return (
+            <div className="app">
+                <div className="container">
+                    <div className="view">
+                        <h2>Admins only</h2>
+                        <p>Log in as a moderator or admin to use the admin pages.</p>
+                        <button onClick={() => navigate('/player/login')} className="btn-primary">
+                            Log In
+                        </button>
+                    </div>
+                </div>
+            </div>
+        );
+    }
+
     return (
         <div className="app">
             <nav className="navbar">
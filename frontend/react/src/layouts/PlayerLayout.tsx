@@ -1,6 +1,7 @@
 import React, { useState } from 'react';
 import { Outlet, useNavigate, useLocation } from 'react-router-dom';
 import type { Player } from '../types';
+import { apiService } from '../services/api';
 
 export const PlayerLayout: React.FC = () => {
     const navigate = useNavigate();
@@ -12,8 +13,8 @@ export const PlayerLayout: React.FC = () => {
 
     const isActive = (path: string) => location.pathname === path;
 
-    const handleLogout = () => {
-        localStorage.removeItem('currentPlayer');
+    const handleLogout = async () => {
+        await apiService.logOutSession();
         setCurrentPlayer(null);
         navigate('/player/login');
     };
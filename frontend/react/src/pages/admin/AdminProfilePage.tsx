@@ -1,7 +1,7 @@
 import React, { useState, useEffect } from 'react';
 import { useParams, useNavigate, useOutletContext } from 'react-router-dom';
 import { apiService } from '../../services/api';
-import type { AdminContextType, Player, PlayerMonster } from '../../types';
+import type { AdminContextType, Player, PlayerMonster, Role } from '../../types';
 
 export const AdminProfilePage: React.FC = () => {
     const { playerId } = useParams<{ playerId: string }>();
@@ -66,6 +66,29 @@ export const AdminProfilePage: React.FC = () => {
         };
     };
 
+    const handleRoleChange = async (role: Role) => {
+        if (!player) return;
+
+        try {
+            setPlayer(await apiService.setPlayerRole(player.id, role));
+        } catch (error) {
+            console.error('Error changing role:', error);
+            alert('Failed to change role. Only admins can change roles, and not their own.');
+        }
+    };
+
+    const handleUnlock = async () => {
+        if (!player) return;
+
+        try {
+            await apiService.unlockPlayer(player.id);
+            alert(`${player.username} can log in again.`);
+        } catch (error) {
+            console.error('Error unlocking player:', error);
+            alert('Failed to unlock player. Only admins can unlock players.');
+        }
+    };
+
     const handleDelete = async () => {
         if (!player) return;
 
@@ -95,6 +118,14 @@ export const AdminProfilePage: React.FC = () => {
                     <h2 className="profile-name">{player.username}</h2>
                     <p className="profile-points">⭐ {player.points} Points</p>
                     <p style={{ fontSize: '0.875rem', color: '#666' }}>Player ID: {player.id}</p>
+                    <label style={{ fontSize: '0.875rem', color: '#666' }}>
+                        Role:{' '}
+                        <select value={player.role} onChange={e => handleRoleChange(e.target.value as Role)}>
+                            <option value="player">Player</option>
+                            <option value="moderator">Moderator</option>
+                            <option value="admin">Admin</option>
+                        </select>
+                    </label>
                 </div>
                 <button
                     onClick={handleDelete}
@@ -107,6 +138,13 @@ export const AdminProfilePage: React.FC = () => {
                 >
                     🗑️ Delete Player
                 </button>
+                <button
+                    onClick={handleUnlock}
+                    className="btn-secondary"
+                    style={{ alignSelf: 'flex-start', marginTop: '8px' }}
+                >
+                    🔓 Unlock Login
+                </button>
             </div>
 
             <div className="card">
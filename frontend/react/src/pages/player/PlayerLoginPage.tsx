@@ -1,6 +1,7 @@
 import React, { useState } from 'react';
 import { useNavigate, useOutletContext } from 'react-router-dom';
 import { apiService } from '../../services/api';
+import { PasswordResetForm } from '../../components';
 import type { PlayerContextType } from '../../types';
 
 export const PlayerLoginPage: React.FC = () => {
@@ -11,6 +12,7 @@ export const PlayerLoginPage: React.FC = () => {
     const [isLogin, setIsLogin] = useState(true);
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState('');
+    const [resetting, setResetting] = useState(false);
 
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
@@ -61,72 +63,94 @@ export const PlayerLoginPage: React.FC = () => {
                         {isLogin ? '🎮 Player Login' : '🎮 Create Account'}
                     </h2>
 
-                    <form onSubmit={handleSubmit}>
-                        <div style={{ marginBottom: '16px' }}>
-                            <label style={{ display: 'block', marginBottom: '8px', fontWeight: 500 }}>
-                                Username
-                            </label>
-                            <input
-                                type="text"
-                                value={username}
-                                onChange={e => setUsername(e.target.value)}
-                                className="input"
-                                required
-                                disabled={loading}
-                                autoComplete="username"
-                            />
-                        </div>
-
-                        <div style={{ marginBottom: '16px' }}>
-                            <label style={{ display: 'block', marginBottom: '8px', fontWeight: 500 }}>
-                                Password
-                            </label>
-                            <input
-                                type="password"
-                                value={password}
-                                onChange={e => setPassword(e.target.value)}
-                                className="input"
-                                required
-                                disabled={loading}
-                                autoComplete={isLogin ? 'current-password' : 'new-password'}
-                            />
-                        </div>
+                    {resetting ? (
+                        <PasswordResetForm
+                            onDone={() => {
+                                setResetting(false);
+                                setIsLogin(true);
+                                setError('Password reset. Log in with your new password.');
+                            }}
+                        />
+                    ) : (
+                        <form onSubmit={handleSubmit}>
+                            <div style={{ marginBottom: '16px' }}>
+                                <label style={{ display: 'block', marginBottom: '8px', fontWeight: 500 }}>
+                                    Username
+                                </label>
+                                <input
+                                    type="text"
+                                    value={username}
+                                    onChange={e => setUsername(e.target.value)}
+                                    className="input"
+                                    required
+                                    disabled={loading}
+                                    autoComplete="username"
+                                />
+                            </div>
 
-                        {error && (
-                            <div style={{
-                                padding: '12px',
-                                background: '#fee2e2',
-                                color: '#dc2626',
-                                borderRadius: '6px',
-                                marginBottom: '16px',
-                                fontSize: '0.875rem'
-                            }}>
-                                {error}
+                            <div style={{ marginBottom: '16px' }}>
+                                <label style={{ display: 'block', marginBottom: '8px', fontWeight: 500 }}>
+                                    Password
+                                </label>
+                                <input
+                                    type="password"
+                                    value={password}
+                                    onChange={e => setPassword(e.target.value)}
+                                    className="input"
+                                    required
+                                    disabled={loading}
+                                    autoComplete={isLogin ? 'current-password' : 'new-password'}
+                                />
                             </div>
-                        )}
 
-                        <button
-                            type="submit"
-                            className="btn-primary"
-                            style={{ width: '100%', marginBottom: '12px' }}
-                            disabled={loading}
-                        >
-                            {loading ? 'Processing...' : (isLogin ? 'Login' : 'Create Account')}
-                        </button>
+                            {error && (
+                                <div style={{
+                                    padding: '12px',
+                                    background: '#fee2e2',
+                                    color: '#dc2626',
+                                    borderRadius: '6px',
+                                    marginBottom: '16px',
+                                    fontSize: '0.875rem'
+                                }}>
+                                    {error}
+                                </div>
+                            )}
 
-                        <button
-                            type="button"
-                            onClick={() => {
-                                setIsLogin(!isLogin);
-                                setError('');
-                            }}
-                            className="btn-secondary"
-                            style={{ width: '100%' }}
-                            disabled={loading}
-                        >
-                            {isLogin ? 'Need an account? Register' : 'Have an account? Login'}
-                        </button>
-                    </form>
+                            <button
+                                type="submit"
+                                className="btn-primary"
+                                style={{ width: '100%', marginBottom: '12px' }}
+                                disabled={loading}
+                            >
+                                {loading ? 'Processing...' : (isLogin ? 'Login' : 'Create Account')}
+                            </button>
+
+                            <button
+                                type="button"
+                                onClick={() => {
+                                    setIsLogin(!isLogin);
+                                    setError('');
+                                }}
+                                className="btn-secondary"
+                                style={{ width: '100%' }}
+                                disabled={loading}
+                            >
+                                {isLogin ? 'Need an account? Register' : 'Have an account? Login'}
+                            </button>
+
+                            {isLogin && (
+                                <button
+                                    type="button"
+                                    onClick={() => setResetting(true)}
+                                    className="btn-secondary"
+                                    style={{ width: '100%', marginTop: '12px' }}
+                                    disabled={loading}
+                                >
+                                    Forgot password?
+                                </button>
+                            )}
+                        </form>
+                    )}
 
                     <div style={{
                         marginTop: '24px',
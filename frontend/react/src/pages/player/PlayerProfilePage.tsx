@@ -1,11 +1,12 @@
 import React, { useState, useEffect } from 'react';
 import { useNavigate, useOutletContext, Navigate } from 'react-router-dom';
 import { apiService } from '../../services/api';
+import { SessionList, ChangePasswordForm } from '../../components';
 import type { PlayerContextType, PlayerMonster, Monster } from '../../types';
 
 export const PlayerProfilePage: React.FC = () => {
     const navigate = useNavigate();
-    const { currentPlayer } = useOutletContext<PlayerContextType>();
+    const { currentPlayer, setCurrentPlayer } = useOutletContext<PlayerContextType>();
     const [myMonsters, setMyMonsters] = useState<PlayerMonster[]>([]);
     const [availableMonsters, setAvailableMonsters] = useState<Monster[]>([]);
     const [showAdd, setShowAdd] = useState(false);
@@ -136,6 +137,16 @@ export const PlayerProfilePage: React.FC = () => {
                     </div>
                 )}
             </div>
+
+            <ChangePasswordForm playerId={currentPlayer.id} />
+
+            <SessionList
+                playerId={currentPlayer.id}
+                onLoggedOut={() => {
+                    setCurrentPlayer(null);
+                    navigate('/player/login');
+                }}
+            />
         </div>
     );
 };
\ No newline at end of file
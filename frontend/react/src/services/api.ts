@@ -1,24 +1,71 @@
 import { API_CONFIG } from '../config/api.config';
-import type { Player, Monster, PlayerMonster, Battle, LeaderboardEntry } from '../types';
+import type { Player, LoginResponse, Role, Session, Monster, PlayerMonster, Battle, LeaderboardEntry, TurnAction, TurnStatus, LiveBattle, StreamMessage, Trainer, AIDifficulty, SimulationReport, MatchStatus, LeaderboardSort, LeaderboardWindow, Season, SeasonStandings, RankHistory } from '../types';
 
 const { BASE_URL, ENDPOINTS } = API_CONFIG;
 
+const ACCESS_TOKEN_KEY = 'accessToken';
+const REFRESH_TOKEN_KEY = 'refreshToken';
+
 class ApiService {
+  // authFetch sends the access token with a request, refreshing it once if
+  // it has expired. If the refresh fails too the player is logged out.
+  private async authFetch(url: string, init: RequestInit = {}, retry: boolean = true): Promise<Response> {
+    const token = localStorage.getItem(ACCESS_TOKEN_KEY);
+    const headers = new Headers(init.headers);
+    if (token) headers.set('Authorization', `Bearer ${token}`);
+
+    const response = await fetch(url, { ...init, headers });
+    if (response.status !== 401 || !token || !retry) return response;
+
+    if (await this.refreshTokens()) {
+      return this.authFetch(url, init, false);
+    }
+    this.logout();
+    return response;
+  }
+
+  private async refreshTokens(): Promise<boolean> {
+    const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+    if (!refreshToken) return false;
+
+    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/refresh`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ refresh_token: refreshToken })
+    });
+    if (!response.ok) return false;
+
+    this.storeTokens(await response.json());
+    return true;
+  }
+
+  private storeTokens(tokens: LoginResponse) {
+    localStorage.setItem(ACCESS_TOKEN_KEY, tokens.access_token);
+    localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+  }
+
+  // logout forgets the tokens; logOutSession also ends the session on the server
+  logout() {
+    localStorage.removeItem(ACCESS_TOKEN_KEY);
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+    localStorage.removeItem('currentPlayer');
+  }
+
   // Players
   async getPlayers(): Promise<Player[]> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}`);
     if (!response.ok) throw new Error('Failed to fetch players');
     return response.json();
   }
 
   async getPlayer(id: number): Promise<Player> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}`);
     if (!response.ok) throw new Error('Failed to fetch player');
     return response.json();
   }
 
   async createPlayer(username: string, password: string): Promise<Player> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}`, {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({ username, password })
@@ -31,7 +78,7 @@ class ApiService {
   }
 
   async deletePlayer(id: number): Promise<void> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}`, {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}`, {
       method: 'DELETE'
     });
     if (!response.ok) {
@@ -39,6 +86,76 @@ class ApiService {
     }
   }
 
+  async setPlayerRole(id: number, role: Role): Promise<Player> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}/role`, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ role })
+    });
+    if (!response.ok) throw new Error('Failed to change role');
+    return response.json();
+  }
+
+  async unlockPlayer(id: number): Promise<void> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${id}/lockout`, {
+      method: 'DELETE'
+    });
+    if (!response.ok) throw new Error('Failed to unlock player');
+  }
+
+  async logOutSession(): Promise<void> {
+    if (localStorage.getItem(ACCESS_TOKEN_KEY)) {
+      await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/logout`, { method: 'POST' }, false).catch(() => undefined);
+    }
+    this.logout();
+  }
+
+  async getSessions(playerId: number): Promise<Session[]> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/sessions`);
+    if (!response.ok) throw new Error('Failed to fetch sessions');
+    return response.json();
+  }
+
+  // revokeSession logs out one session, or all of them if none is given
+  async revokeSession(playerId: number, sessionId?: string): Promise<void> {
+    const path = sessionId ? `/${playerId}/sessions/${sessionId}` : `/${playerId}/sessions`;
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}${path}`, { method: 'DELETE' });
+    if (!response.ok) throw new Error('Failed to revoke session');
+  }
+
+  async changePassword(playerId: number, oldPassword: string, newPassword: string): Promise<void> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/password`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ old_password: oldPassword, new_password: newPassword })
+    });
+    if (!response.ok) {
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.error || 'Failed to change password');
+    }
+  }
+
+  async requestPasswordReset(username: string): Promise<void> {
+    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/password-reset`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ username })
+    });
+    if (!response.ok) throw new Error('Failed to request password reset');
+  }
+
+  async resetPassword(token: string, newPassword: string): Promise<void> {
+    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/password-reset/confirm`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token, new_password: newPassword })
+    });
+    if (!response.ok) {
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.error || 'Failed to reset password');
+    }
+  }
+
   async login(username: string, password: string): Promise<Player> {
     const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/login`, {
       method: 'POST',
@@ -46,20 +163,24 @@ class ApiService {
       body: JSON.stringify({ username, password })
     });
     if (!response.ok) {
-      throw new Error('Invalid username or password');
+      // Tells a wrong password apart from a lockout
+      const body = await response.json().catch(() => null);
+      throw new Error(body?.error || 'Invalid username or password');
     }
-    return response.json();
+    const tokens: LoginResponse = await response.json();
+    this.storeTokens(tokens);
+    return tokens.player;
   }
 
   // Monsters
   async getMonsters(): Promise<Monster[]> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.MONSTERS}`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.MONSTERS}`);
     if (!response.ok) throw new Error('Failed to fetch monsters');
     return response.json();
   }
 
   async getPlayerMonsters(playerId: number): Promise<PlayerMonster[]> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/monster`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/monster`);
     if (!response.ok) throw new Error('Failed to fetch player monsters');
     return response.json();
   }
@@ -76,7 +197,7 @@ class ApiService {
       speed: number;
     }
   ): Promise<PlayerMonster> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/monster`, {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.PLAYERS}/${playerId}/monster`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(monsterData)
@@ -92,7 +213,7 @@ class ApiService {
     monster1Id: number,
     monster2Id: number
   ): Promise<Battle> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.BATTLES}`, {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify({
@@ -106,22 +227,163 @@ class ApiService {
     return response.json();
   }
 
+  async createTeamBattle(
+    player1Id: number,
+    player2Id: number,
+    party1: number[],
+    party2: number[]
+  ): Promise<Battle> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        player1_id: player1Id,
+        player2_id: player2Id,
+        party1,
+        party2
+      })
+    });
+    if (!response.ok) throw new Error('Failed to create battle');
+    return response.json();
+  }
+
+  async createInteractiveBattle(
+    player1Id: number,
+    player2Id: number,
+    party1: number[],
+    party2: number[]
+  ): Promise<Battle> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        player1_id: player1Id,
+        player2_id: player2Id,
+        party1,
+        party2,
+        interactive: true
+      })
+    });
+    if (!response.ok) throw new Error('Failed to create battle');
+    return response.json();
+  }
+
+  async simulateBattle(
+    player1Id: number,
+    player2Id: number,
+    party1: number[],
+    party2: number[],
+    iterations?: number
+  ): Promise<SimulationReport> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/simulate`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        player1_id: player1Id,
+        player2_id: player2Id,
+        party1,
+        party2,
+        iterations
+      })
+    });
+    if (!response.ok) throw new Error('Failed to simulate battle');
+    return response.json();
+  }
+
+  async createPvEBattle(
+    playerId: number,
+    party: number[],
+    opponent: string = 'wild',
+    options: { difficulty?: AIDifficulty; interactive?: boolean } = {}
+  ): Promise<Battle> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/pve`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        player_id: playerId,
+        party,
+        opponent,
+        difficulty: options.difficulty,
+        interactive: options.interactive ?? false
+      })
+    });
+    if (!response.ok) throw new Error('Failed to create battle');
+    return response.json();
+  }
+
+  async getTrainers(): Promise<Trainer[]> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/trainers`);
+    if (!response.ok) throw new Error('Failed to fetch trainers');
+    return response.json();
+  }
+
+  async joinMatchmaking(playerId: number, party: number[], interactive: boolean = false): Promise<MatchStatus> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.MATCHMAKING}/queue`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ player_id: playerId, party, interactive })
+    });
+    if (!response.ok) throw new Error('Failed to join matchmaking');
+    return response.json();
+  }
+
+  async getMatchmakingStatus(playerId: number): Promise<MatchStatus> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.MATCHMAKING}/queue/${playerId}`);
+    if (!response.ok) throw new Error('Failed to fetch matchmaking status');
+    return response.json();
+  }
+
+  async leaveMatchmaking(playerId: number): Promise<void> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.MATCHMAKING}/queue/${playerId}`, {
+      method: 'DELETE'
+    });
+    if (!response.ok) throw new Error('Failed to leave matchmaking');
+  }
+
+  async submitTurn(battleId: number, playerId: number, action: TurnAction): Promise<TurnStatus> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/${battleId}/turns`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ player_id: playerId, ...action })
+    });
+    if (!response.ok) throw new Error('Failed to submit turn');
+    return response.json();
+  }
+
+  async getLiveBattle(battleId: number): Promise<LiveBattle> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/${battleId}/state`);
+    if (!response.ok) throw new Error('Failed to fetch battle state');
+    return response.json();
+  }
+
+  // streamBattle follows a battle live; call the returned function to stop
+  streamBattle(battleId: number, onMessage: (message: StreamMessage) => void): () => void {
+    const source = new EventSource(`${BASE_URL}${ENDPOINTS.BATTLES}/${battleId}/stream`);
+    const handle = (event: MessageEvent) => onMessage(JSON.parse(event.data));
+    source.addEventListener('events', handle as EventListener);
+    source.addEventListener('end', (event) => {
+      handle(event as MessageEvent);
+      source.close();
+    });
+    return () => source.close();
+  }
+
   async getBattle(id: number): Promise<Battle> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.BATTLES}/${id}`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}/${id}`);
     if (!response.ok) throw new Error('Failed to fetch battle result');
     return response.json();
   }
 
   async getBattles(): Promise<Battle[]> {
-    const response = await fetch(`${BASE_URL}${ENDPOINTS.BATTLES}`);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.BATTLES}`);
     if (!response.ok) throw new Error('Failed to fetch battle history');
     return response.json();
   }
 
   // Rankings
-  async getLeaderboard(): Promise<LeaderboardEntry[]> {
+  async getLeaderboard(sort: LeaderboardSort = 'combat_power', window: LeaderboardWindow = 'all'): Promise<LeaderboardEntry[]> {
     try {
-      const response = await fetch(`${BASE_URL}${ENDPOINTS.RANKINGS}`);
+      const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.RANKINGS}?sort=${sort}&window=${window}`);
       if (!response.ok) return [];
       const data = await response.json();
       return data.leaderboard || [];
@@ -129,6 +391,33 @@ class ApiService {
       return [];
     }
   }
+
+  async getRankHistory(playerId: number, from?: string, to?: string): Promise<RankHistory> {
+    const params = new URLSearchParams();
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.RANKINGS}/player/${playerId}/history?${params}`);
+    if (!response.ok) throw new Error('Failed to fetch rank history');
+    return response.json();
+  }
+
+  async getSeasons(): Promise<Season[]> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.RANKINGS}/seasons`);
+    if (!response.ok) throw new Error('Failed to fetch seasons');
+    return response.json();
+  }
+
+  async getCurrentSeason(): Promise<Season> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.RANKINGS}/seasons/current`);
+    if (!response.ok) throw new Error('Failed to fetch current season');
+    return response.json();
+  }
+
+  async getSeasonStandings(seasonId: number, limit: number = 100): Promise<SeasonStandings> {
+    const response = await this.authFetch(`${BASE_URL}${ENDPOINTS.RANKINGS}/seasons/${seasonId}?limit=${limit}`);
+    if (!response.ok) throw new Error('Failed to fetch season standings');
+    return response.json();
+  }
 }
 
 export const apiService = new ApiService();
\ No newline at end of file
@@ -1,11 +1,32 @@
+export type Role = 'player' | 'moderator' | 'admin';
+
 export interface Player {
   id: number;
   username: string;
   points: number;
+  role: Role;
   created_at: string;
   updated_at: string;
 }
 
+export interface Session {
+  id: string;
+  player_id: number;
+  device: string;
+  ip: string;
+  issued_at: string;
+  last_used_at: string;
+  current: boolean;
+}
+
+export interface LoginResponse {
+  player: Player;
+  access_token: string;
+  refresh_token: string;
+  token_type: string;
+  expires_at: string;
+}
+
 export interface Monster {
   id: number;
   name: string;
@@ -23,11 +44,33 @@ export interface PlayerMonster {
   player_id: number;
   monster_id: number;
   nickname: string;
+  type1: string;
+  type2?: string;
   level: number;
   hp: number;
   attack: number;
   defense: number;
   speed: number;
+  moves?: PlayerMonsterMove[];
+}
+
+export interface PlayerMonsterMove {
+  move_id: number;
+  slot: number;
+}
+
+export interface Move {
+  id: number;
+  name: string;
+  type: string;
+  category: 'physical' | 'special' | 'status';
+  power: number;
+  accuracy: number;
+  pp: number;
+  priority: number;
+  effect?: StatusCondition | '';
+  effect_chance?: number;
+  description?: string;
 }
 
 export interface Battle {
@@ -36,14 +79,83 @@ export interface Battle {
   player2_id: number;
   monster1_id: number;
   monster2_id: number;
+  mode?: 'single' | 'team';
+  interactive?: boolean;
+  ai_difficulty?: AIDifficulty;
+  opponent?: string;
   winner_id: number;
   status: string;
   battle_log: string;
   points_won: number;
   points_lost: number;
+  rating_model?: 'elo' | 'glicko';
+  player1_rating?: number;
+  player2_rating?: number;
+  participants?: BattleParticipant[];
   created_at: string;
 }
 
+export type AIDifficulty = 'random' | 'greedy' | 'minimax';
+
+export interface Trainer {
+  id: string;
+  name: string;
+  difficulty: AIDifficulty;
+  party: { species: string; level: number; nickname?: string }[];
+}
+
+export interface BattleParticipant {
+  side: 1 | 2;
+  slot: number;
+  player_id: number;
+  player_monster_id: number;
+  remaining_hp: number;
+  damage_dealt: number;
+  damage_taken: number;
+  knockouts: number;
+  fainted: boolean;
+}
+
+export type StatusCondition =
+  | 'burn'
+  | 'poison'
+  | 'paralysis'
+  | 'sleep'
+  | 'freeze'
+  | 'confusion';
+
+export type BattleEventType =
+  | 'battle_start'
+  | 'switch_in'
+  | 'switch_out'
+  | 'round_start'
+  | 'move'
+  | 'miss'
+  | 'recoil'
+  | 'faint'
+  | 'status_applied'
+  | 'status_damage'
+  | 'status_cured'
+  | 'cant_move'
+  | 'move_failed'
+  | 'battle_end';
+
+export interface BattleEvent {
+  round: number;
+  type: BattleEventType;
+  actor?: number;
+  actor_name?: string;
+  target?: number;
+  target_name?: string;
+  move?: string;
+  move_type?: string;
+  damage?: number;
+  critical?: boolean;
+  effectiveness?: number;
+  remaining_hp: number;
+  status?: StatusCondition;
+}
+
 export interface LeaderboardEntry {
   player_id: number;
   username: string;
@@ -52,6 +164,59 @@ export interface LeaderboardEntry {
   losses: number;
   win_rate: number;
   rank: number;
+  rating?: number;
+  rating_deviation?: number;
+  conservative_rating?: number;
+  points_gained?: number;
+}
+
+export type LeaderboardSort = 'combat_power' | 'rating';
+
+export type LeaderboardWindow = 'all' | 'daily' | 'weekly' | 'monthly';
+
+export interface RankSnapshot {
+  season_id: number;
+  rank: number;
+  combat_power: number;
+  total_points: number;
+  conservative_rating: number;
+  taken_at: string;
+}
+
+export interface RankHistory {
+  player_id: number;
+  from: string;
+  to: string;
+  snapshots: RankSnapshot[];
+}
+
+export interface Season {
+  id: number;
+  name: string;
+  starts_at: string;
+  ends_at: string;
+  archived_at?: string;
+}
+
+export interface SeasonStanding {
+  season_id: number;
+  rank: number;
+  player_id: number;
+  username: string;
+  combat_power: number;
+  total_points: number;
+  wins: number;
+  losses: number;
+  win_rate: number;
+  rating: number;
+  rating_deviation: number;
+  conservative_rating: number;
+}
+
+export interface SeasonStandings {
+  season: Season;
+  final: boolean;
+  standings: SeasonStanding[];
 }
 
 export interface AdminContextType {
@@ -67,4 +232,106 @@ export interface PlayerContextType {
   setCurrentPlayer: (player: Player | null) => void;
 }
 
-export type View = 'home' | 'profile' | 'battle' | 'battle-result' | 'leaderboard';
\ No newline at end of file
+export type View = 'home' | 'profile' | 'battle' | 'battle-result' | 'leaderboard';
+export interface TurnAction {
+  type: 'move' | 'switch';
+  slot: number;
+}
+
+export interface TurnStatus {
+  battle_id: number;
+  status: string;
+  round: number;
+  waiting_for: number[];
+  deadline?: string;
+  events: BattleEvent[];
+  winner_id?: number;
+}
+
+export interface LiveFighter {
+  slot: number;
+  monster: string;
+  hp: number;
+  max_hp: number;
+  status?: StatusCondition;
+  confused?: boolean;
+  moves: string[];
+  move_pp: number[];
+  player_monster_id: number;
+}
+
+export interface LiveSide {
+  player_id: number;
+  active: number;
+  party: LiveFighter[];
+}
+
+export interface LiveBattle {
+  battle_id: number;
+  status: string;
+  round: number;
+  sides: [LiveSide, LiveSide];
+  waiting_for: number[];
+  deadline?: string;
+  spectators: number;
+}
+
+export interface StreamMessage {
+  type: 'events' | 'end';
+  battle_id: number;
+  offset: number;
+  events: BattleEvent[];
+  status?: string;
+  winner_id?: number;
+}
+
+export interface DamageDistribution {
+  min: number;
+  max: number;
+  mean: number;
+  p10: number;
+  median: number;
+  p90: number;
+  bucket_width: number;
+  histogram: number[];
+}
+
+export interface SimulationSide {
+  team: string[];
+  wins: number;
+  win_probability: number;
+  damage_dealt: DamageDistribution;
+}
+
+export interface SimulatedMonster {
+  side: 1 | 2;
+  slot: number;
+  monster: string;
+  average_damage_dealt: number;
+  average_damage_taken: number;
+  average_knockouts: number;
+  fainted_rate: number;
+}
+
+export interface SimulationReport {
+  iterations: number;
+  seed: number;
+  sides: [SimulationSide, SimulationSide];
+  average_rounds: number;
+  rounds_min: number;
+  rounds_max: number;
+  monsters: SimulatedMonster[];
+  engine_version: number;
+}
+
+export interface MatchStatus {
+  player_id: number;
+  status: 'queued' | 'matched' | 'failed';
+  combat_power: number;
+  enqueued_at: string;
+  window?: number;
+  queue_size?: number;
+  battle_id?: number;
+  opponent_id?: number;
+  error?: string;
+}
@@ -94,6 +94,16 @@ curl -s -X POST $KONG_ADMIN_URL/services/battle-service/routes \
   --data "methods[]=GET" \
   --data "methods[]=POST" \
   --data "methods[]=OPTIONS" \
+  --data "response_buffering=false" \
+  --data "strip_path=false"
+
+curl -s -X POST $KONG_ADMIN_URL/services/battle-service/routes \
+  --data "name=matchmaking" \
+  --data "paths[]=/api/matchmaking" \
+  --data "methods[]=GET" \
+  --data "methods[]=POST" \
+  --data "methods[]=DELETE" \
+  --data "methods[]=OPTIONS" \
   --data "strip_path=false"
 
 # Create Ranking Service
@@ -132,6 +142,7 @@ echo "You can now access your APIs through:"
 echo "  - Players: http://localhost:8000/api/players"
 echo "  - Monsters: http://localhost:8000/api/monster"
 echo "  - Battles: http://localhost:8000/api/battles"
+echo "  - Matchmaking: http://localhost:8000/api/matchmaking"
 echo "  - Rankings: http://localhost:8000/api/rankings"
 
 echo ""
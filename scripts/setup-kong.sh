@@ -44,6 +44,19 @@ BATTLE_SERVICE_ID=$(curl -s -X POST $KONG_ADMIN_URL/services/ \
 echo "Creating Battle Service route..."
 BATTLE_ROUTE_ID=$(curl -s -X POST $KONG_ADMIN_URL/services/battle-service/routes \
   --data "paths[]=/api/battles" \
+  --data "response_buffering=false" \
+  --data "strip_path=true" | jq -r '.id')
+
+# Create Matchmaking Service (served by battle-service)
+echo "Creating Matchmaking Service..."
+MATCHMAKING_SERVICE_ID=$(curl -s -X POST $KONG_ADMIN_URL/services/ \
+  --data "name=matchmaking-service" \
+  --data "url=http://battle-service:8003/matchmaking" | jq -r '.id')
+
+# Create Matchmaking Service Route
+echo "Creating Matchmaking Service route..."
+MATCHMAKING_ROUTE_ID=$(curl -s -X POST $KONG_ADMIN_URL/services/matchmaking-service/routes \
+  --data "paths[]=/api/matchmaking" \
   --data "strip_path=true" | jq -r '.id')
 
 # Create Ranking Service
@@ -78,6 +91,7 @@ echo "You can now access your APIs through:"
 echo "  - Players: http://localhost:8000/api/players"
 echo "  - Monsters: http://localhost:8000/api/monster"
 echo "  - Battles: http://localhost:8000/api/battles"
+echo "  - Matchmaking: http://localhost:8000/api/matchmaking"
 echo "  - Rankings: http://localhost:8000/api/rankings"
 
 echo ""
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey struct{}
+
+// Middleware verifies the bearer token and its session on every request
+// and puts the caller's claims in its context. Reads may be anonymous, since services
+// read each other's data without tokens; anything else needs a valid access
+// token unless its route is listed in public as "METHOD /path/template".
+// Public routes ignore bad tokens so a stale one can't block logging in.
+func (k *Keys) Middleware(public ...string) mux.MiddlewareFunc {
+	open := make(map[string]bool, len(public))
+	for _, route := range public {
+		open[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			isPublic := open[routeKey(r)]
+
+			token, ok := bearerToken(r)
+			if !ok {
+				if isPublic || isRead(r.Method) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				respondError(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+
+			claims, err := k.Verify(token, TokenAccess)
+			if err != nil {
+				if isPublic {
+					next.ServeHTTP(w, r)
+					return
+				}
+				respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+
+			active, err := k.sessions.Active(claims.SessionID)
+			if err != nil {
+				respondError(w, http.StatusServiceUnavailable, "Could not check session")
+				return
+			}
+			if !active {
+				if isPublic {
+					next.ServeHTTP(w, r)
+					return
+				}
+				respondError(w, http.StatusUnauthorized, "Session has been revoked")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, claims)))
+		})
+	}
+}
+
+// FromContext returns the claims of the player making a request, if it was
+// authenticated
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+// RequirePlayer checks a request was made by the given player or an admin.
+// If not, it writes a 401 or 403 response and returns false.
+func RequirePlayer(w http.ResponseWriter, r *http.Request, playerID uint) bool {
+	return RequirePlayerOr(w, r, playerID, RoleAdmin)
+}
+
+// RequirePlayerOr is RequirePlayer, also letting through callers with at
+// least the given role
+func RequirePlayerOr(w http.ResponseWriter, r *http.Request, playerID uint, role Role) bool {
+	claims, ok := RequireCaller(w, r)
+	if !ok {
+		return false
+	}
+	if claims.PlayerID != playerID && !claims.Role.AtLeast(role) {
+		respondError(w, http.StatusForbidden, "Not allowed to act for another player")
+		return false
+	}
+	return true
+}
+
+// RequireCaller returns the authenticated player making a request. If there
+// is none, it writes a 401 response and returns false.
+func RequireCaller(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	claims, ok := FromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Authentication required")
+	}
+	return claims, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return r.Method + " " + path
+}
+
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Role is what a player may do beyond acting for themselves. Each role can
+// do everything the ones before it can.
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+var roleRanks = map[Role]int{
+	RolePlayer:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ParseRole checks a role name is one of the known roles
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := roleRanks[role]; !ok {
+		return "", ErrInvalidRole
+	}
+	return role, nil
+}
+
+// AtLeast reports whether r includes min. Unknown roles include nothing.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[min]
+}
+
+// RequireRole only lets callers with at least the given role through to
+// next. It relies on Middleware having authenticated the request, so reads
+// that need a role are refused when anonymous too.
+func RequireRole(role Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := RequireCaller(w, r)
+		if !ok {
+			return
+		}
+		if !claims.Role.AtLeast(role) {
+			respondError(w, http.StatusForbidden, "Requires the "+string(role)+" role")
+			return
+		}
+		next(w, r)
+	}
+}
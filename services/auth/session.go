@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Every login starts a session, which its tokens name in their sid claim.
+// Sessions live in Redis until they are revoked or go unused for as long as
+// a refresh token lasts, and every service refuses tokens whose session is
+// gone.
+const (
+	sessionKey        = "session:%s"
+	playerSessionsKey = "sessions:player:%d"
+	rotatedSessionKey = "session:rotated:%s" // player ID, for as long as its refresh token lasts
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionReused   = errors.New("refresh token was already used")
+)
+
+// Session is one device a player is logged in on
+type Session struct {
+	ID         string    `json:"id"`
+	PlayerID   uint      `json:"player_id"`
+	Device     string    `json:"device"` // the client's User-Agent
+	IP         string    `json:"ip"`
+	IssuedAt   time.Time `json:"issued_at"`
+	LastUsedAt time.Time `json:"last_used_at"` // last time its tokens were refreshed
+	Current    bool      `json:"current"`      // set when listing, for the caller's own
+}
+
+// Sessions stores sessions in the Redis every service shares
+type Sessions struct {
+	redis *redis.Client
+	ctx   context.Context
+}
+
+func NewSessions(client *redis.Client) *Sessions {
+	return &Sessions{redis: client, ctx: context.Background()}
+}
+
+// Start records a new session for a player
+func (s *Sessions) Start(playerID uint, device, ip string) (*Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:         hex.EncodeToString(id),
+		PlayerID:   playerID,
+		Device:     device,
+		IP:         ip,
+		IssuedAt:   now,
+		LastUsedAt: now,
+	}
+	if err := s.save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Rotate replaces a session with a new one under a fresh ID, so the refresh
+// token naming the old one can only be used once, and keeps it alive for
+// another RefreshTokenTTL. Presenting a refresh token for a session that was
+// already rotated means it leaked, so every session of the player is revoked
+// and ErrSessionReused returned. It returns ErrSessionNotFound if the session
+// was revoked or has expired.
+func (s *Sessions) Rotate(sessionID string) (*Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf(sessionKey, sessionID)
+	var rotated *Session
+	err := s.redis.Watch(s.ctx, func(tx *redis.Tx) error {
+		session, err := s.getWith(tx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		old := session.ID
+		session.ID = hex.EncodeToString(id)
+		session.LastUsedAt = time.Now()
+		data, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		listKey := fmt.Sprintf(playerSessionsKey, session.PlayerID)
+		_, err = tx.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(s.ctx, key)
+			pipe.SRem(s.ctx, listKey, old)
+			pipe.Set(s.ctx, fmt.Sprintf(rotatedSessionKey, old), session.PlayerID, RefreshTokenTTL)
+			pipe.Set(s.ctx, fmt.Sprintf(sessionKey, session.ID), data, RefreshTokenTTL)
+			pipe.SAdd(s.ctx, listKey, session.ID)
+			pipe.Expire(s.ctx, listKey, RefreshTokenTTL)
+			return nil
+		})
+		rotated = session
+		return err
+	}, key)
+
+	// Losing a race with another refresh of the same token is reuse too
+	if errors.Is(err, ErrSessionNotFound) || errors.Is(err, redis.TxFailedErr) {
+		return nil, s.checkReuse(sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
+
+// checkReuse revokes every session of the player a rotated session belonged
+// to. It returns ErrSessionReused if it was rotated, ErrSessionNotFound if it
+// never existed or has expired.
+func (s *Sessions) checkReuse(sessionID string) error {
+	playerID, err := s.redis.Get(s.ctx, fmt.Sprintf(rotatedSessionKey, sessionID)).Uint64()
+	if err == redis.Nil {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.RevokeAll(uint(playerID)); err != nil {
+		return err
+	}
+	return ErrSessionReused
+}
+
+func (s *Sessions) save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	listKey := fmt.Sprintf(playerSessionsKey, session.PlayerID)
+	pipe := s.redis.TxPipeline()
+	pipe.Set(s.ctx, fmt.Sprintf(sessionKey, session.ID), data, RefreshTokenTTL)
+	pipe.SAdd(s.ctx, listKey, session.ID)
+	pipe.Expire(s.ctx, listKey, RefreshTokenTTL)
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
+
+func (s *Sessions) get(sessionID string) (*Session, error) {
+	return s.getWith(s.redis, sessionID)
+}
+
+func (s *Sessions) getWith(client redis.Cmdable, sessionID string) (*Session, error) {
+	data, err := client.Get(s.ctx, fmt.Sprintf(sessionKey, sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Active reports whether a session is still live
+func (s *Sessions) Active(sessionID string) (bool, error) {
+	n, err := s.redis.Exists(s.ctx, fmt.Sprintf(sessionKey, sessionID)).Result()
+	return n == 1, err
+}
+
+// List returns a player's live sessions, newest first, and forgets expired
+// ones
+func (s *Sessions) List(playerID uint) ([]Session, error) {
+	listKey := fmt.Sprintf(playerSessionsKey, playerID)
+	ids, err := s.redis.SMembers(s.ctx, listKey).Result()
+	if err != nil || len(ids) == 0 {
+		return []Session{}, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = fmt.Sprintf(sessionKey, id)
+	}
+	values, err := s.redis.MGet(s.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(values))
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		var session Session
+		if !ok || json.Unmarshal([]byte(data), &session) != nil {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		s.redis.SRem(s.ctx, listKey, expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IssuedAt.After(sessions[j].IssuedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke ends one of a player's sessions. It returns ErrSessionNotFound if
+// the player has no such session.
+func (s *Sessions) Revoke(playerID uint, sessionID string) error {
+	session, err := s.get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.PlayerID != playerID {
+		return ErrSessionNotFound
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Del(s.ctx, fmt.Sprintf(sessionKey, sessionID))
+	pipe.SRem(s.ctx, fmt.Sprintf(playerSessionsKey, playerID), sessionID)
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
+
+// RevokeAll ends every session of a player except any given to keep
+func (s *Sessions) RevokeAll(playerID uint, keep ...string) error {
+	listKey := fmt.Sprintf(playerSessionsKey, playerID)
+	ids, err := s.redis.SMembers(s.ctx, listKey).Result()
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+
+	pipe := s.redis.TxPipeline()
+	for _, id := range ids {
+		if kept[id] {
+			continue
+		}
+		pipe.Del(s.ctx, fmt.Sprintf(sessionKey, id))
+		pipe.SRem(s.ctx, listKey, id)
+	}
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestSessions(t *testing.T) *Sessions {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewSessions(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+}
+
+func TestRotateReplacesSession(t *testing.T) {
+	sessions := newTestSessions(t)
+	session, err := sessions.Start(7, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := sessions.Rotate(session.ID)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if rotated.ID == session.ID {
+		t.Errorf("Rotate() kept session ID %s", session.ID)
+	}
+	if rotated.PlayerID != 7 || !rotated.IssuedAt.Equal(session.IssuedAt) {
+		t.Errorf("Rotate() = %+v, want player 7 issued at %v", rotated, session.IssuedAt)
+	}
+
+	if active, _ := sessions.Active(session.ID); active {
+		t.Errorf("old session %s still active", session.ID)
+	}
+	if active, _ := sessions.Active(rotated.ID); !active {
+		t.Errorf("new session %s not active", rotated.ID)
+	}
+	list, err := sessions.List(7)
+	if err != nil || len(list) != 1 || list[0].ID != rotated.ID {
+		t.Errorf("List() = %+v, %v, want only %s", list, err, rotated.ID)
+	}
+}
+
+func TestRotateRejectsReuse(t *testing.T) {
+	sessions := newTestSessions(t)
+	session, _ := sessions.Start(7, "test", "127.0.0.1")
+	other, _ := sessions.Start(7, "other", "127.0.0.1")
+	rotated, err := sessions.Rotate(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sessions.Rotate(session.ID); !errors.Is(err, ErrSessionReused) {
+		t.Fatalf("Rotate() of a rotated session error = %v, want %v", err, ErrSessionReused)
+	}
+	for _, id := range []string{rotated.ID, other.ID} {
+		if active, _ := sessions.Active(id); active {
+			t.Errorf("session %s still active after reuse", id)
+		}
+	}
+}
+
+func TestRotateUnknownSession(t *testing.T) {
+	sessions := newTestSessions(t)
+	if _, err := sessions.Rotate("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Rotate() error = %v, want %v", err, ErrSessionNotFound)
+	}
+}
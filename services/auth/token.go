@@ -0,0 +1,140 @@
+// Package auth issues and verifies the signed tokens players authenticate
+// with. player-service issues them; every service verifies them with the same
+// shared secret.
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+
+	tokenIssuer     = "player-service"
+	minSecretLength = 32
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims identify the player a token was issued to. Role is as of when the
+// token was issued, so a role change takes effect on the next refresh.
+type Claims struct {
+	PlayerID  uint   `json:"pid"`
+	Username  string `json:"name"`
+	Role      Role   `json:"role"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair is what a player gets on login. The access token authenticates
+// requests; the refresh token gets a new pair, once, when it expires.
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"` // of the access token
+}
+
+// Keys signs and verifies tokens with HMAC-SHA256, and checks the sessions
+// they belong to haven't been revoked
+type Keys struct {
+	secret   []byte
+	sessions *Sessions
+}
+
+func NewKeys(secret string, sessions *Sessions) (*Keys, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("JWT secret must be at least %d bytes", minSecretLength)
+	}
+	return &Keys{secret: []byte(secret), sessions: sessions}, nil
+}
+
+// Sessions returns the store of the sessions tokens belong to
+func (k *Keys) Sessions() *Sessions {
+	return k.sessions
+}
+
+// Issue signs a new access and refresh token for a player's session
+func (k *Keys) Issue(playerID uint, username string, role Role, sessionID string) (*TokenPair, error) {
+	now := time.Now()
+	claims := Claims{PlayerID: playerID, Username: username, Role: role, SessionID: sessionID}
+	access, err := k.sign(claims, TokenAccess, now, AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := k.sign(claims, TokenRefresh, now, RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresAt:    now.Add(AccessTokenTTL),
+	}, nil
+}
+
+// Refresh verifies a refresh token and rotates the session it belongs to, so
+// the token can't be used again. Tokens issued for the returned session
+// replace it; those of the old session stop working. It returns
+// ErrSessionReused if the token was already used.
+func (k *Keys) Refresh(refreshToken string) (*Claims, *Session, error) {
+	claims, err := k.Verify(refreshToken, TokenRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := k.sessions.Rotate(claims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claims, session, nil
+}
+
+func (k *Keys) sign(claims Claims, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	claims.TokenType = tokenType
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        hex.EncodeToString(id),
+		Issuer:    tokenIssuer,
+		Subject:   strconv.FormatUint(uint64(claims.PlayerID), 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+}
+
+// Verify checks a token's signature, expiry and type and returns its claims
+func (k *Keys) Verify(token, tokenType string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return k.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.TokenType != tokenType || claims.PlayerID == 0 || claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: expected a %s token", ErrInvalidToken, tokenType)
+	}
+	return &claims, nil
+}
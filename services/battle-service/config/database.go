@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"maushold/battle-service/model"
+	"maushold/outbox"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -20,7 +21,7 @@ func InitDB(cfg *Config) *gorm.DB {
 	}
 
 	// Auto migrate
-	err = db.AutoMigrate(&model.Battle{})
+	err = db.AutoMigrate(&model.Battle{}, &model.BattleParticipant{}, &outbox.Message{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"os"
+
+	"maushold/battle-service/model"
+)
+
+//go:embed trainers.json
+var defaultTrainers []byte
+
+// LoadTrainers reads the PvE trainer roster from path, or the built-in
+// roster when no path is configured
+func LoadTrainers(path string) []model.Trainer {
+	data := defaultTrainers
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			log.Fatal("Failed to read trainer roster:", err)
+		}
+	}
+
+	var trainers []model.Trainer
+	if err := json.Unmarshal(data, &trainers); err != nil {
+		log.Fatal("Failed to parse trainer roster:", err)
+	}
+
+	log.Printf("Loaded %d trainers", len(trainers))
+	return trainers
+}
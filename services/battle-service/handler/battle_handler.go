@@ -2,10 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
-	"maushold/battle-service/messaging"
+	"maushold/auth"
 	"maushold/battle-service/model"
 	"maushold/battle-service/service"
 
@@ -14,48 +16,287 @@ import (
 
 type BattleHandler struct {
 	battleService    service.BattleService
-	messageProducer  *messaging.Producer
 	serviceDiscovery *service.ServiceDiscovery
 }
 
-func NewBattleHandler(battleService service.BattleService, messageProducer *messaging.Producer, serviceDiscovery *service.ServiceDiscovery) *BattleHandler {
+func NewBattleHandler(battleService service.BattleService, serviceDiscovery *service.ServiceDiscovery) *BattleHandler {
 	return &BattleHandler{
 		battleService:    battleService,
-		messageProducer:  messageProducer,
 		serviceDiscovery: serviceDiscovery,
 	}
 }
 
 func (h *BattleHandler) CreateBattle(w http.ResponseWriter, r *http.Request) {
+	// Either a single monster per side or an ordered party of monster IDs
 	var req struct {
-		Player1ID  uint `json:"player1_id"`
-		Player2ID  uint `json:"player2_id"`
-		Monster1ID uint `json:"monster1_id"`
-		Monster2ID uint `json:"monster2_id"`
+		Player1ID  uint   `json:"player1_id"`
+		Player2ID  uint   `json:"player2_id"`
+		Monster1ID uint   `json:"monster1_id"`
+		Monster2ID uint   `json:"monster2_id"`
+		Party1     []uint `json:"party1"`
+		Party2     []uint `json:"party2"`
+		// Interactive battles start pending and are fought turn by turn
+		Interactive bool `json:"interactive"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if !auth.RequirePlayer(w, r, req.Player1ID) {
+		return
+	}
+
+	party1, party2 := req.Party1, req.Party2
+	if len(party1) == 0 && req.Monster1ID != 0 {
+		party1 = []uint{req.Monster1ID}
+	}
+	if len(party2) == 0 && req.Monster2ID != 0 {
+		party2 = []uint{req.Monster2ID}
+	}
 
-	battle, err := h.battleService.CreateBattle(req.Player1ID, req.Player2ID, req.Monster1ID, req.Monster2ID)
+	create := h.battleService.CreateTeamBattle
+	if req.Interactive {
+		create = h.battleService.CreateInteractiveBattle
+	}
+
+	battle, err := create(req.Player1ID, req.Player2ID, party1, party2)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidParty) || errors.Is(err, service.ErrSamePlayer) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.messageProducer.PublishBattleEvent("battle.completed", map[string]interface{}{
-		"battle_id":   battle.ID,
-		"winner_id":   battle.WinnerID,
-		"loser_id":    getLoserID(battle),
-		"points_won":  battle.PointsWon,
-		"points_lost": battle.PointsLost,
+	respondJSON(w, http.StatusCreated, battle)
+}
+
+// SimulateBattle runs a matchup many times without saving anything and
+// returns the odds. Each side is a player's party or a list of species IDs.
+func (h *BattleHandler) SimulateBattle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Player1ID  uint   `json:"player1_id"`
+		Player2ID  uint   `json:"player2_id"`
+		Monster1ID uint   `json:"monster1_id"`
+		Monster2ID uint   `json:"monster2_id"`
+		Party1     []uint `json:"party1"`
+		Party2     []uint `json:"party2"`
+		Species1   []int  `json:"species1"`
+		Species2   []int  `json:"species2"`
+		Level      int    `json:"level"` // level of species sides, default 50
+		Iterations int    `json:"iterations"`
+		Seed       *int64 `json:"seed"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	party1, party2 := req.Party1, req.Party2
+	if len(party1) == 0 && req.Monster1ID != 0 {
+		party1 = []uint{req.Monster1ID}
+	}
+	if len(party2) == 0 && req.Monster2ID != 0 {
+		party2 = []uint{req.Monster2ID}
+	}
+
+	report, err := h.battleService.SimulateMatchup(service.SimulateOptions{
+		Sides: [2]service.MatchupSide{
+			{PlayerID: req.Player1ID, Party: party1, Species: req.Species1, Level: req.Level},
+			{PlayerID: req.Player2ID, Party: party2, Species: req.Species2, Level: req.Level},
+		},
+		Iterations: req.Iterations,
+		Seed:       req.Seed,
 	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSimulation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// CreatePvEBattle starts a battle against a wild monster or a trainer from
+// the roster, played by the AI
+func (h *BattleHandler) CreatePvEBattle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID    uint   `json:"player_id"`
+		MonsterID   uint   `json:"monster_id"`
+		Party       []uint `json:"party"`
+		Opponent    string `json:"opponent"`   // "wild" or a trainer ID
+		TrainerID   string `json:"trainer_id"` // shorthand for opponent
+		Difficulty  string `json:"difficulty"` // random, greedy or minimax
+		Interactive bool   `json:"interactive"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !auth.RequirePlayer(w, r, req.PlayerID) {
+		return
+	}
+
+	party := req.Party
+	if len(party) == 0 && req.MonsterID != 0 {
+		party = []uint{req.MonsterID}
+	}
+
+	opponent := req.Opponent
+	if opponent == "" {
+		opponent = req.TrainerID
+	}
+	if opponent == "" {
+		opponent = service.OpponentWild
+	}
+
+	battle, err := h.battleService.CreatePvEBattle(service.PvEOptions{
+		PlayerID:    req.PlayerID,
+		Party:       party,
+		Opponent:    opponent,
+		Difficulty:  req.Difficulty,
+		Interactive: req.Interactive,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidParty), errors.Is(err, service.ErrInvalidDifficulty):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrUnknownTrainer):
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
 
 	respondJSON(w, http.StatusCreated, battle)
 }
 
+// GetTrainers lists the trainers players can challenge
+func (h *BattleHandler) GetTrainers(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.battleService.GetTrainers())
+}
+
+// SubmitTurn takes a player's action for the current round of an interactive battle
+func (h *BattleHandler) SubmitTurn(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battle ID")
+		return
+	}
+
+	var req struct {
+		PlayerID uint `json:"player_id"`
+		model.TurnAction
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !auth.RequirePlayer(w, r, req.PlayerID) {
+		return
+	}
+
+	status, err := h.battleService.SubmitTurn(uint(id), req.PlayerID, req.TurnAction)
+	if err != nil {
+		respondTurnError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// GetLiveBattle returns the current state of an interactive battle
+func (h *BattleHandler) GetLiveBattle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battle ID")
+		return
+	}
+
+	view, err := h.battleService.GetLiveBattle(uint(id))
+	if err != nil {
+		respondTurnError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, view)
+}
+
+// StreamBattle pushes a battle's events over Server-Sent Events as they are
+// resolved. Anyone can watch, so spectators use the same endpoint as players.
+func (h *BattleHandler) StreamBattle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battle ID")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	started := false
+	err = h.battleService.WatchBattle(r.Context(), uint(id), func(msg model.StreamMessage) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+
+		if msg.Type == model.StreamPing {
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+			return err
+		}
+
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+
+	if err != nil && !started {
+		respondError(w, http.StatusNotFound, "Battle not found")
+	}
+}
+
+func respondTurnError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrBattleNotLive):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNotParticipant):
+		respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidAction):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrAlreadyActed), errors.Is(err, service.ErrBattleBusy), errors.Is(err, service.ErrBattleOver):
+		respondError(w, http.StatusConflict, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 func (h *BattleHandler) GetBattle(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -73,6 +314,44 @@ func (h *BattleHandler) GetBattle(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, battle)
 }
 
+func (h *BattleHandler) ReplayBattle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battle ID")
+		return
+	}
+
+	replay, err := h.battleService.ReplayBattle(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrReplayUnavailable) {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		respondError(w, http.StatusNotFound, "Battle not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, replay)
+}
+
+func (h *BattleHandler) GetBattleEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid battle ID")
+		return
+	}
+
+	events, err := h.battleService.GetBattleEvents(uint(id))
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Battle not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, events)
+}
+
 func (h *BattleHandler) GetPlayerBattles(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["playerId"], 10, 32)
@@ -104,13 +383,6 @@ func (h *BattleHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "battle-service"})
 }
 
-func getLoserID(battle *model.Battle) uint {
-	if battle.WinnerID == battle.Player1ID {
-		return battle.Player2ID
-	}
-	return battle.Player1ID
-}
-
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
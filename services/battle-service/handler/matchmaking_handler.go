@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"maushold/auth"
+	"maushold/battle-service/service"
+
+	"github.com/gorilla/mux"
+)
+
+type MatchmakingHandler struct {
+	matchmakingService service.MatchmakingService
+}
+
+func NewMatchmakingHandler(matchmakingService service.MatchmakingService) *MatchmakingHandler {
+	return &MatchmakingHandler{matchmakingService: matchmakingService}
+}
+
+// JoinQueue puts a player in the matchmaking queue with a monster or party
+func (h *MatchmakingHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID    uint   `json:"player_id"`
+		MonsterID   uint   `json:"monster_id"`
+		Party       []uint `json:"party"`
+		Interactive bool   `json:"interactive"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !auth.RequirePlayer(w, r, req.PlayerID) {
+		return
+	}
+
+	party := req.Party
+	if len(party) == 0 && req.MonsterID != 0 {
+		party = []uint{req.MonsterID}
+	}
+
+	status, err := h.matchmakingService.Enqueue(req.PlayerID, party, req.Interactive)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidParty):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrAlreadyQueued):
+			respondError(w, http.StatusConflict, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, status)
+}
+
+// GetQueueStatus tells a player whether they are still waiting or which
+// battle they were matched into
+func (h *MatchmakingHandler) GetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["playerId"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+
+	status, err := h.matchmakingService.GetStatus(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrNotQueued) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// LeaveQueue takes a player out of the matchmaking queue
+func (h *MatchmakingHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["playerId"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
+
+	if err := h.matchmakingService.Leave(uint(id)); err != nil {
+		if errors.Is(err, service.ErrNotQueued) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Left the matchmaking queue"})
+}
@@ -7,10 +7,10 @@ import (
 
 	"maushold/battle-service/config"
 	"maushold/battle-service/handler"
-	"maushold/battle-service/messaging"
 	"maushold/battle-service/repository"
 	"maushold/battle-service/routes"
 	"maushold/battle-service/service"
+	"maushold/outbox"
 
 	"github.com/gorilla/mux"
 )
@@ -20,9 +20,10 @@ func main() {
 
 	db := config.InitDB(cfg)
 	redisClient := config.InitRedis(cfg)
-	rabbitConn, rabbitCh := config.InitRabbitMQ(cfg)
+	rabbitConn := config.InitRabbitMQ(cfg)
 	defer rabbitConn.Close()
-	defer rabbitCh.Close()
+
+	authKeys := config.InitAuth(cfg, redisClient)
 
 	consulClient := config.InitConsul(cfg)
 	err := config.RegisterService(consulClient, "battle-service", cfg.ServicePort)
@@ -36,14 +37,29 @@ func main() {
 
 	battleRepo := repository.NewBattleRepository(db)
 	playerClient := service.NewPlayerClient(cfg.PlayerServiceURL)
+	monsterClient := service.NewMonsterClient(cfg.MonsterServiceURL)
+	rankingClient := service.NewRankingClient(cfg.RankingServiceURL)
+	ratingModel, err := service.NewRatingModel(cfg.RatingModel)
+	if err != nil {
+		log.Fatal("Invalid RATING_MODEL:", err)
+	}
 	battleEngine := service.NewBattleEngine()
-	battleService := service.NewBattleService(battleRepo, playerClient, battleEngine, redisClient)
+	trainers := config.LoadTrainers(cfg.TrainerRosterPath)
+	battleService := service.NewBattleService(battleRepo, playerClient, monsterClient, rankingClient, ratingModel, battleEngine, redisClient, cfg.TurnTimeout, trainers)
+	battleService.StartTurnTimer()
+
+	outboxRelay := outbox.NewRelay(rabbitConn, outbox.NewRepository(db), "battle.events")
+	go outboxRelay.Start()
+
+	matchmakingService := service.NewMatchmakingService(redisClient, battleService, playerClient, rankingClient)
+	matchmakingService.StartMatcher()
 
-	messageProducer := messaging.NewProducer(rabbitCh)
-	battleHandler := handler.NewBattleHandler(battleService, messageProducer, serviceDiscovery)
+	battleHandler := handler.NewBattleHandler(battleService, serviceDiscovery)
+	matchmakingHandler := handler.NewMatchmakingHandler(matchmakingService)
 
 	router := mux.NewRouter()
-	routes.SetupBattleRoutes(router, battleHandler)
+	routes.SetupBattleRoutes(router, battleHandler, authKeys)
+	routes.SetupMatchmakingRoutes(router, matchmakingHandler)
 
 	port := os.Getenv("SERVICE_PORT")
 	if port == "" {
@@ -2,28 +2,163 @@ package model
 
 import "time"
 
+const (
+	MoveCategoryPhysical = "physical"
+	MoveCategorySpecial  = "special"
+	MoveCategoryStatus   = "status"
+)
+
+// Status conditions. Burn, poison, paralysis, sleep and freeze are persistent
+// and a monster can only have one at a time; confusion stacks on top of them.
+const (
+	StatusBurn      = "burn"
+	StatusPoison    = "poison"
+	StatusParalysis = "paralysis"
+	StatusSleep     = "sleep"
+	StatusFreeze    = "freeze"
+	StatusConfusion = "confusion"
+)
+
+const (
+	BattleModeSingle = "single"
+	BattleModeTeam   = "team"
+)
+
+const (
+	BattleStatusPending    = "pending"
+	BattleStatusInProgress = "in_progress"
+	BattleStatusCompleted  = "completed"
+	BattleStatusAbandoned  = "abandoned"
+)
+
 type Battle struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	Player1ID   uint       `gorm:"not null;index" json:"player1_id"`
-	Player2ID   uint       `gorm:"not null;index" json:"player2_id"`
-	Monster1ID  uint       `gorm:"not null" json:"monster1_id"`
-	Monster2ID  uint       `gorm:"not null" json:"monster2_id"`
-	WinnerID    uint       `json:"winner_id"`
-	Status      string     `gorm:"default:'pending'" json:"status"`
-	BattleLog   string     `gorm:"type:text" json:"battle_log"`
-	PointsWon   int        `json:"points_won"`
-	PointsLost  int        `json:"points_lost"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CompletedAt *time.Time `json:"completed_at"`
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	Player1ID     uint         `gorm:"not null;index" json:"player1_id"`
+	Player2ID     uint         `gorm:"not null;index" json:"player2_id"`
+	Monster1ID    uint         `gorm:"not null" json:"monster1_id"` // lead monster in team battles
+	Monster2ID    uint         `gorm:"not null" json:"monster2_id"`
+	Mode          string       `gorm:"default:'single'" json:"mode"`
+	Interactive   bool         `gorm:"default:false" json:"interactive"`
+	AIDifficulty  string       `gorm:"size:16" json:"ai_difficulty,omitempty"` // set on PvE battles, where player 2 is the AI
+	Opponent      string       `gorm:"size:64" json:"opponent,omitempty"`      // "wild" or the trainer's ID in PvE battles
+	WinnerID      uint         `json:"winner_id"`
+	Status        string       `gorm:"default:'pending'" json:"status"`
+	BattleLog     string       `gorm:"type:text" json:"battle_log"`
+	Events        BattleEvents `gorm:"type:jsonb" json:"-"`
+	Turns         TurnLog      `gorm:"type:jsonb" json:"-"` // actions taken in an interactive battle
+	PointsWon     int          `json:"points_won"`          // rating points the winner gains
+	PointsLost    int          `json:"points_lost"`         // rating points the loser gives up
+	Seed          int64        `json:"seed"`
+	EngineVersion int          `json:"engine_version"`
+	// Both players' ratings before the battle and the formula that turned
+	// them into points. Battles from before rated points have no model.
+	RatingModel      string  `gorm:"size:16" json:"rating_model,omitempty"`
+	Player1Rating    float64 `json:"player1_rating,omitempty"`
+	Player1Deviation float64 `json:"player1_deviation,omitempty"`
+	Player2Rating    float64 `json:"player2_rating,omitempty"`
+	Player2Deviation float64 `json:"player2_deviation,omitempty"`
+	// Monster snapshots are only set on battles recorded before parties were
+	// introduced; newer battles keep a snapshot on each participant
+	Monster1Snapshot *Combatant          `gorm:"type:jsonb" json:"monster1_snapshot,omitempty"`
+	Monster2Snapshot *Combatant          `gorm:"type:jsonb" json:"monster2_snapshot,omitempty"`
+	Participants     []BattleParticipant `gorm:"foreignKey:BattleID;constraint:OnDelete:CASCADE" json:"participants,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+	CompletedAt      *time.Time          `json:"completed_at"`
+}
+
+// BattleParticipant is one monster of a player's party, in the order it was
+// sent out, along with how it fared
+type BattleParticipant struct {
+	ID              uint       `gorm:"primaryKey" json:"-"`
+	BattleID        uint       `gorm:"not null;index" json:"-"`
+	Side            int        `gorm:"not null" json:"side"`
+	Slot            int        `gorm:"not null" json:"slot"`
+	PlayerID        uint       `gorm:"not null" json:"player_id"`
+	PlayerMonsterID uint       `gorm:"not null" json:"player_monster_id"`
+	Snapshot        *Combatant `gorm:"type:jsonb" json:"snapshot,omitempty"`
+	RemainingHP     int        `json:"remaining_hp"`
+	DamageDealt     int        `json:"damage_dealt"`
+	DamageTaken     int        `json:"damage_taken"`
+	Knockouts       int        `json:"knockouts"`
+	Fainted         bool       `json:"fainted"`
+}
+
+// BattleReplay is the result of re-simulating a stored battle
+// PlayerRating is a player's standing in ranking-service. Deviation is how
+// uncertain the rating is; only Glicko-style models use it.
+type PlayerRating struct {
+	Rating    float64 `json:"rating"`
+	Deviation float64 `json:"deviation"`
+}
+
+type BattleReplay struct {
+	BattleID         uint   `json:"battle_id"`
+	Seed             int64  `json:"seed"`
+	StoredWinnerID   uint   `json:"stored_winner_id"`
+	ReplayedWinnerID uint   `json:"replayed_winner_id"`
+	WinnerMatches    bool   `json:"winner_matches"`
+	LogMatches       bool   `json:"log_matches"`
+	PointsMatch      bool   `json:"points_match"`
+	Verified         bool   `json:"verified"`
+	BattleLog        string `json:"battle_log"`
 }
 
 type PlayerMonster struct {
+	ID        uint                `json:"id"`
+	PlayerID  uint                `json:"player_id"`
+	MonsterID int                 `json:"monster_id"`
+	Nickname  string              `json:"nickname"`
+	Type1     string              `json:"type1"`
+	Type2     string              `json:"type2"`
+	HP        int                 `json:"hp"`
+	Attack    int                 `json:"attack"`
+	Defense   int                 `json:"defense"`
+	Speed     int                 `json:"speed"`
+	Level     int                 `json:"level"`
+	Moves     []PlayerMonsterMove `json:"moves"`
+}
+
+type PlayerMonsterMove struct {
+	MoveID uint `json:"move_id"`
+	Slot   int  `json:"slot"`
+}
+
+// Move is a move from monster-service's catalog
+type Move struct {
 	ID       uint   `json:"id"`
-	PlayerID uint   `json:"player_id"`
-	Nickname string `json:"nickname"`
-	HP       int    `json:"hp"`
-	Attack   int    `json:"attack"`
-	Defense  int    `json:"defense"`
-	Speed    int    `json:"speed"`
-	Level    int    `json:"level"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Power    int    `json:"power"`
+	Accuracy int    `json:"accuracy"`
+	PP       int    `json:"pp"`
+	Priority int    `json:"priority"`
+	// Effect is the status the move can inflict, with EffectChance percent odds
+	Effect       string `json:"effect"`
+	EffectChance int    `json:"effect_chance"`
+}
+
+// IsStatus reports whether the move only inflicts its effect and deals no damage
+func (m Move) IsStatus() bool {
+	return m.Category == MoveCategoryStatus
+}
+
+// Species is a monster from monster-service's catalog
+type Species struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Type1       string `json:"type1"`
+	Type2       string `json:"type2"`
+	BaseHP      int    `json:"base_hp"`
+	BaseAttack  int    `json:"base_attack"`
+	BaseDefense int    `json:"base_defense"`
+	BaseSpeed   int    `json:"base_speed"`
+}
+
+// LearnsetEntry is a move a species can learn at or above Level
+type LearnsetEntry struct {
+	MonsterID int  `json:"monster_id"`
+	MoveID    uint `json:"move_id"`
+	Level     int  `json:"level"`
+	Move      Move `json:"move"`
 }
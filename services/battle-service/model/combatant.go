@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Combatant is a monster entering battle along with its resolved move set.
+// It is stored on the battle as a snapshot so the fight can be replayed even
+// after the monster levels up or its moves change.
+type Combatant struct {
+	Monster PlayerMonster `json:"monster"`
+	Moves   []Move        `json:"moves"`
+}
+
+// Value implements driver.Valuer so gorm can store the snapshot as JSONB
+func (c Combatant) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *Combatant) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = Combatant{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("cannot scan %T into Combatant", value)
+}
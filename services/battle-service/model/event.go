@@ -0,0 +1,68 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	EventBattleStart = "battle_start"
+	EventSwitchIn    = "switch_in"
+	EventSwitchOut   = "switch_out"
+	EventRoundStart  = "round_start"
+	EventMove        = "move"
+	EventMiss        = "miss"
+	EventRecoil      = "recoil"
+	EventFaint       = "faint"
+	// Status events carry the condition involved in Status
+	EventStatusApplied = "status_applied"
+	EventStatusDamage  = "status_damage"
+	EventStatusCured   = "status_cured"
+	EventCantMove      = "cant_move"
+	EventMoveFailed    = "move_failed"
+	EventBattleEnd     = "battle_end"
+)
+
+// BattleEvent is one entry of the structured battle log. Actor and Target are
+// the side (1 or 2) involved; RemainingHP is the HP left on the monster the
+// event affected: the target of a move, or the actor for everything else.
+type BattleEvent struct {
+	Round         int      `json:"round"`
+	Type          string   `json:"type"`
+	Actor         int      `json:"actor,omitempty"`
+	ActorName     string   `json:"actor_name,omitempty"`
+	Target        int      `json:"target,omitempty"`
+	TargetName    string   `json:"target_name,omitempty"`
+	Move          string   `json:"move,omitempty"`
+	MoveType      string   `json:"move_type,omitempty"`
+	Damage        int      `json:"damage,omitempty"`
+	Critical      bool     `json:"critical,omitempty"`
+	Effectiveness *float64 `json:"effectiveness,omitempty"`
+	RemainingHP   int      `json:"remaining_hp"`
+	Status        string   `json:"status,omitempty"`
+}
+
+type BattleEvents []BattleEvent
+
+// Value implements driver.Valuer so gorm can store the events as JSONB
+func (e BattleEvents) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// Scan implements sql.Scanner
+func (e *BattleEvents) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("cannot scan %T into BattleEvents", value)
+}
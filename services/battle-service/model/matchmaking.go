@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+const (
+	MatchStatusQueued  = "queued"
+	MatchStatusMatched = "matched"
+	MatchStatusFailed  = "failed"
+)
+
+// MatchTicket is a player waiting in the matchmaking queue with the party
+// they want to battle with
+type MatchTicket struct {
+	PlayerID    uint      `json:"player_id"`
+	Party       []uint    `json:"party"`
+	Interactive bool      `json:"interactive"`
+	CombatPower int64     `json:"combat_power"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+}
+
+// MatchStatus is where a player stands in matchmaking. Window is how far
+// from their combat power an opponent may currently be.
+type MatchStatus struct {
+	PlayerID    uint      `json:"player_id"`
+	Status      string    `json:"status"`
+	CombatPower int64     `json:"combat_power"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+	Window      int64     `json:"window,omitempty"`
+	QueueSize   int64     `json:"queue_size,omitempty"`
+	BattleID    uint      `json:"battle_id,omitempty"`
+	OpponentID  uint      `json:"opponent_id,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
@@ -0,0 +1,46 @@
+package model
+
+// SimulationReport summarizes many simulated runs of the same matchup. Run i
+// uses seed Seed+i, so a report can be reproduced by sending its seed back.
+type SimulationReport struct {
+	Iterations    int                `json:"iterations"`
+	Seed          int64              `json:"seed"`
+	Sides         [2]SimulationSide  `json:"sides"`
+	AverageRounds float64            `json:"average_rounds"`
+	RoundsMin     int                `json:"rounds_min"`
+	RoundsMax     int                `json:"rounds_max"`
+	Monsters      []SimulatedMonster `json:"monsters"`
+	EngineVersion int                `json:"engine_version"`
+}
+
+// SimulationSide is how often a side won and the damage it dealt per battle
+type SimulationSide struct {
+	Team           []string           `json:"team"`
+	Wins           int                `json:"wins"`
+	WinProbability float64            `json:"win_probability"`
+	DamageDealt    DamageDistribution `json:"damage_dealt"`
+}
+
+// DamageDistribution describes the total damage a side dealt per battle.
+// Histogram counts battles in buckets of BucketWidth starting at Min.
+type DamageDistribution struct {
+	Min         int     `json:"min"`
+	Max         int     `json:"max"`
+	Mean        float64 `json:"mean"`
+	P10         int     `json:"p10"`
+	Median      int     `json:"median"`
+	P90         int     `json:"p90"`
+	BucketWidth int     `json:"bucket_width"`
+	Histogram   []int   `json:"histogram"`
+}
+
+// SimulatedMonster is how one party member fared on average
+type SimulatedMonster struct {
+	Side            int     `json:"side"`
+	Slot            int     `json:"slot"`
+	Monster         string  `json:"monster"`
+	AverageDamage   float64 `json:"average_damage_dealt"`
+	AverageTaken    float64 `json:"average_damage_taken"`
+	AverageKOs      float64 `json:"average_knockouts"`
+	FaintedFraction float64 `json:"fainted_rate"`
+}
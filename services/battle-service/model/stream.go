@@ -0,0 +1,19 @@
+package model
+
+const (
+	StreamEvents = "events" // new events of a battle in progress
+	StreamEnd    = "end"    // the battle is over; nothing follows
+	StreamPing   = "ping"   // keeps idle connections open
+)
+
+// StreamMessage is pushed to everyone watching a battle. Offset is the index
+// of the first event in the battle's full event list, so a watcher that has
+// already seen some of them can skip ahead.
+type StreamMessage struct {
+	Type     string       `json:"type"`
+	BattleID uint         `json:"battle_id"`
+	Offset   int          `json:"offset"`
+	Events   BattleEvents `json:"events"`
+	Status   string       `json:"status,omitempty"`
+	WinnerID uint         `json:"winner_id,omitempty"`
+}
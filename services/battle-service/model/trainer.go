@@ -0,0 +1,16 @@
+package model
+
+// Trainer is an AI opponent from the configured roster
+type Trainer struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Difficulty string           `json:"difficulty"`
+	Party      []TrainerMonster `json:"party"`
+}
+
+// TrainerMonster is one monster of a trainer's party, by species name
+type TrainerMonster struct {
+	Species  string `json:"species"`
+	Level    int    `json:"level"`
+	Nickname string `json:"nickname,omitempty"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionMove   = "move"
+	ActionSwitch = "switch"
+)
+
+// TurnAction is what a player does in a round of an interactive battle. Slot
+// is the move slot for a move, or the party slot to send out for a switch.
+type TurnAction struct {
+	Type string `json:"type"`
+	Slot int    `json:"slot"`
+}
+
+// TurnRecord is the actions both sides took in one round. TimedOut lists the
+// sides whose action was picked by the engine because their player ran out
+// of time.
+type TurnRecord struct {
+	Round    int           `json:"round"`
+	Actions  [2]TurnAction `json:"actions"`
+	TimedOut []int         `json:"timed_out,omitempty"`
+}
+
+type TurnLog []TurnRecord
+
+// Value implements driver.Valuer so gorm can store the turns as JSONB
+func (t TurnLog) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan implements sql.Scanner
+func (t *TurnLog) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	}
+	return fmt.Errorf("cannot scan %T into TurnLog", value)
+}
+
+// TurnStatus reports where an interactive battle stands after an action
+type TurnStatus struct {
+	BattleID uint         `json:"battle_id"`
+	Status   string       `json:"status"`
+	Round    int          `json:"round"` // the round waiting for actions
+	Waiting  []uint       `json:"waiting_for"`
+	Deadline *time.Time   `json:"deadline,omitempty"`
+	Events   BattleEvents `json:"events"` // events of any round resolved by this action
+	WinnerID uint         `json:"winner_id,omitempty"`
+}
+
+// LiveFighter is the visible state of one party member in a live battle
+type LiveFighter struct {
+	Slot            int      `json:"slot"`
+	Monster         string   `json:"monster"`
+	HP              int      `json:"hp"`
+	MaxHP           int      `json:"max_hp"`
+	Status          string   `json:"status,omitempty"`
+	Confused        bool     `json:"confused,omitempty"`
+	Moves           []string `json:"moves"`
+	MovePP          []int    `json:"move_pp"`
+	PlayerMonsterID uint     `json:"player_monster_id"`
+}
+
+// LiveSide is one player's party in a live battle
+type LiveSide struct {
+	PlayerID uint          `json:"player_id"`
+	Active   int           `json:"active"`
+	Party    []LiveFighter `json:"party"`
+}
+
+// LiveBattleView is the current state of an interactive battle
+type LiveBattleView struct {
+	BattleID   uint        `json:"battle_id"`
+	Status     string      `json:"status"`
+	Round      int         `json:"round"`
+	Sides      [2]LiveSide `json:"sides"`
+	Waiting    []uint      `json:"waiting_for"`
+	Deadline   *time.Time  `json:"deadline,omitempty"`
+	Spectators int64       `json:"spectators"`
+}
@@ -2,16 +2,17 @@ package repository
 
 import (
 	"maushold/battle-service/model"
+	"maushold/outbox"
 
 	"gorm.io/gorm"
 )
 
 type BattleRepository interface {
-	Create(battle *model.Battle) error
+	Create(battle *model.Battle, events ...outbox.Pending) error
 	FindByID(id uint) (*model.Battle, error)
 	FindByPlayerID(playerID uint) ([]model.Battle, error)
 	FindRecent(limit int) ([]model.Battle, error)
-	Update(battle *model.Battle) error
+	Update(battle *model.Battle, events ...outbox.Pending) error
 }
 
 type battleRepository struct {
@@ -22,19 +23,29 @@ func NewBattleRepository(db *gorm.DB) BattleRepository {
 	return &battleRepository{db: db}
 }
 
-func (r *battleRepository) Create(battle *model.Battle) error {
-	return r.db.Create(battle).Error
+// Create saves a new battle and its participants, writing any events to the
+// outbox in the same transaction
+func (r *battleRepository) Create(battle *model.Battle, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(battle).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
 }
 
 func (r *battleRepository) FindByID(id uint) (*model.Battle, error) {
 	var battle model.Battle
-	err := r.db.First(&battle, id).Error
+	err := r.db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("side, slot")
+	}).First(&battle, id).Error
 	return &battle, err
 }
 
 func (r *battleRepository) FindByPlayerID(playerID uint) ([]model.Battle, error) {
 	var battles []model.Battle
-	err := r.db.Where("player1_id = ? OR player2_id = ?", playerID, playerID).
+	err := r.db.Omit("events").
+		Where("player1_id = ? OR player2_id = ?", playerID, playerID).
 		Order("created_at DESC").
 		Limit(20).
 		Find(&battles).Error
@@ -43,10 +54,17 @@ func (r *battleRepository) FindByPlayerID(playerID uint) ([]model.Battle, error)
 
 func (r *battleRepository) FindRecent(limit int) ([]model.Battle, error) {
 	var battles []model.Battle
-	err := r.db.Order("created_at DESC").Limit(limit).Find(&battles).Error
+	err := r.db.Omit("events").Order("created_at DESC").Limit(limit).Find(&battles).Error
 	return battles, err
 }
 
-func (r *battleRepository) Update(battle *model.Battle) error {
-	return r.db.Save(battle).Error
+// Update saves a battle and its participants, writing any events to the
+// outbox in the same transaction
+func (r *battleRepository) Update(battle *model.Battle, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(battle).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
 }
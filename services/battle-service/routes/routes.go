@@ -3,18 +3,35 @@ package routes
 import (
 	"net/http"
 
+	"maushold/auth"
 	"maushold/battle-service/handler"
 
 	"github.com/gorilla/mux"
 	"github.com/unifuu/lapras"
 )
 
-func SetupBattleRoutes(router *mux.Router, handler *handler.BattleHandler) {
+func SetupBattleRoutes(router *mux.Router, handler *handler.BattleHandler, keys *auth.Keys) {
 	router.Use(lapras.Cors)
+	// Simulations save nothing, so anyone may run one
+	router.Use(keys.Middleware("POST /battles/simulate"))
 
 	router.HandleFunc("/battles", handler.CreateBattle).Methods(http.MethodPost)
+	router.HandleFunc("/battles/simulate", handler.SimulateBattle).Methods(http.MethodPost)
+	router.HandleFunc("/battles/pve", handler.CreatePvEBattle).Methods(http.MethodPost)
+	router.HandleFunc("/battles/trainers", handler.GetTrainers).Methods(http.MethodGet)
 	router.HandleFunc("/battles/{id}", handler.GetBattle).Methods(http.MethodGet)
+	router.HandleFunc("/battles/{id}/replay", handler.ReplayBattle).Methods(http.MethodGet)
+	router.HandleFunc("/battles/{id}/events", handler.GetBattleEvents).Methods(http.MethodGet)
+	router.HandleFunc("/battles/{id}/turns", handler.SubmitTurn).Methods(http.MethodPost)
+	router.HandleFunc("/battles/{id}/state", handler.GetLiveBattle).Methods(http.MethodGet)
+	router.HandleFunc("/battles/{id}/stream", handler.StreamBattle).Methods(http.MethodGet)
 	router.HandleFunc("/battles/player/{playerId}", handler.GetPlayerBattles).Methods(http.MethodGet)
 	router.HandleFunc("/battles", handler.GetAllBattles).Methods(http.MethodGet)
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
 }
+
+func SetupMatchmakingRoutes(router *mux.Router, handler *handler.MatchmakingHandler) {
+	router.HandleFunc("/matchmaking/queue", handler.JoinQueue).Methods(http.MethodPost)
+	router.HandleFunc("/matchmaking/queue/{playerId}", handler.GetQueueStatus).Methods(http.MethodGet)
+	router.HandleFunc("/matchmaking/queue/{playerId}", handler.LeaveQueue).Methods(http.MethodDelete)
+}
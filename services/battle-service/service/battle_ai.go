@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"maushold/battle-service/model"
+)
+
+const (
+	AIRandom  = "random"
+	AIGreedy  = "greedy"
+	AIMinimax = "minimax"
+
+	minimaxDepth  = 3    // rounds the minimax AI looks ahead
+	statusPenalty = 0.15 // how much worse a persistent status makes a position, in HP fractions
+	aiSeedSalt    = 0x5eed
+)
+
+var ErrInvalidDifficulty = errors.New("invalid AI difficulty")
+
+// Strategy picks the action for one side of a battle. Strategies only look
+// at the state and their own random source, never the battle's, so adding
+// an AI does not change how the battle itself rolls.
+type Strategy interface {
+	ChooseAction(st *BattleState, side int, rng RandomSource) model.TurnAction
+}
+
+// NewStrategy returns the AI for a difficulty
+func NewStrategy(difficulty string) (Strategy, error) {
+	switch difficulty {
+	case AIRandom:
+		return randomStrategy{}, nil
+	case AIGreedy, "":
+		return greedyStrategy{}, nil
+	case AIMinimax:
+		return minimaxStrategy{depth: minimaxDepth}, nil
+	}
+	return nil, fmt.Errorf("%w: difficulty must be %q, %q or %q", ErrInvalidDifficulty, AIRandom, AIGreedy, AIMinimax)
+}
+
+// aiSeed derives the seed of the AI's random source for a round
+func aiSeed(seed int64, round int) int64 {
+	return turnSeed(seed^aiSeedSalt, round)
+}
+
+// sides returns the active fighters from side's point of view
+func sides(st *BattleState, side int) (*fighter, *fighter) {
+	p1, p2 := st.parties()
+	if side == 2 {
+		return p2.current(), p1.current()
+	}
+	return p1.current(), p2.current()
+}
+
+// usableMoves lists the move slots that still have PP
+func usableMoves(f *fighter) []int {
+	var slots []int
+	for i := range f.Moves {
+		if f.MovePP[i] > 0 {
+			slots = append(slots, i)
+		}
+	}
+	return slots
+}
+
+// randomStrategy uses any move with PP left
+type randomStrategy struct{}
+
+func (randomStrategy) ChooseAction(st *BattleState, side int, rng RandomSource) model.TurnAction {
+	self, _ := sides(st, side)
+	slots := usableMoves(self)
+	if len(slots) == 0 {
+		return model.TurnAction{Type: model.ActionMove}
+	}
+	return model.TurnAction{Type: model.ActionMove, Slot: slots[rng.Intn(len(slots))]}
+}
+
+// greedyStrategy uses the move with the best expected damage this round,
+// the same choice the engine makes for automatic battles
+type greedyStrategy struct{}
+
+func (greedyStrategy) ChooseAction(st *BattleState, side int, _ RandomSource) model.TurnAction {
+	self, opponent := sides(st, side)
+	return model.TurnAction{Type: model.ActionMove, Slot: maxInt(chooseMove(self, opponent), 0)}
+}
+
+// minimaxStrategy looks a few rounds ahead between the two active monsters,
+// assuming the opponent answers every move with its best reply. Damage is
+// taken at its expected value, so the search itself is deterministic.
+type minimaxStrategy struct {
+	depth int
+}
+
+// aiNode is the state of the two active monsters during the search
+type aiNode struct {
+	hp     [2]int
+	status [2]string
+}
+
+func (m minimaxStrategy) ChooseAction(st *BattleState, side int, _ RandomSource) model.TurnAction {
+	self, opponent := sides(st, side)
+	slots := usableMoves(self)
+	if len(slots) == 0 {
+		return model.TurnAction{Type: model.ActionMove}
+	}
+
+	fighters := [2]*fighter{self, opponent}
+	root := aiNode{hp: [2]int{self.HP, opponent.HP}, status: [2]string{self.Status, opponent.Status}}
+
+	best := slots[0]
+	bestScore := -1e9
+	for _, slot := range slots {
+		if score := m.worstReply(fighters, root, slot, m.depth); score > bestScore {
+			best = slot
+			bestScore = score
+		}
+	}
+	return model.TurnAction{Type: model.ActionMove, Slot: best}
+}
+
+// worstReply scores our move against each opponent reply and keeps the one
+// that is worst for us
+func (m minimaxStrategy) worstReply(fighters [2]*fighter, node aiNode, slot, depth int) float64 {
+	replies := usableMoves(fighters[1])
+	if len(replies) == 0 {
+		replies = []int{-1}
+	}
+
+	worst := 1e9
+	for _, reply := range replies {
+		next := playAINode(fighters, node, [2]int{slot, reply})
+		score := m.search(fighters, next, depth-1)
+		if score < worst {
+			worst = score
+		}
+	}
+	return worst
+}
+
+// search returns the value of a position with depth rounds left to play
+func (m minimaxStrategy) search(fighters [2]*fighter, node aiNode, depth int) float64 {
+	if depth == 0 || node.hp[0] <= 0 || node.hp[1] <= 0 {
+		return evaluate(fighters, node)
+	}
+
+	slots := usableMoves(fighters[0])
+	if len(slots) == 0 {
+		slots = []int{-1}
+	}
+
+	best := -1e9
+	for _, slot := range slots {
+		if score := m.worstReply(fighters, node, slot, depth); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// playAINode applies one round of expected damage in turn order
+func playAINode(fighters [2]*fighter, node aiNode, slots [2]int) aiNode {
+	order := [2]int{0, 1}
+	if !goesFirst(fighters[0], fighters[1], slots[0], slots[1]) {
+		order = [2]int{1, 0}
+	}
+
+	for _, i := range order {
+		if node.hp[0] <= 0 || node.hp[1] <= 0 {
+			break
+		}
+		attacker, defender := fighters[i], fighters[1-i]
+		move := struggle
+		if slots[i] >= 0 {
+			move = attacker.Moves[slots[i]]
+		}
+
+		if move.IsStatus() {
+			if node.status[1-i] == "" && move.Effect != model.StatusConfusion && canReceiveType(defender, move.Effect) {
+				node.status[1-i] = move.Effect
+			}
+			continue
+		}
+		node.hp[1-i] -= int(expectedDamage(attacker, defender, move))
+	}
+
+	for i, status := range node.status {
+		switch status {
+		case model.StatusBurn:
+			node.hp[i] -= fighters[i].Monster.HP / burnDamageDiv
+		case model.StatusPoison:
+			node.hp[i] -= fighters[i].Monster.HP / poisonDamageDiv
+		}
+	}
+	return node
+}
+
+// evaluate scores a position for the side doing the search: the difference
+// in remaining HP fractions, less a penalty for carrying a status
+func evaluate(fighters [2]*fighter, node aiNode) float64 {
+	var score float64
+	for i, sign := range [2]float64{1, -1} {
+		hp := float64(maxInt(node.hp[i], 0)) / float64(maxInt(fighters[i].Monster.HP, 1))
+		if node.status[i] != "" {
+			hp -= statusPenalty
+		}
+		score += sign * hp
+	}
+	return score
+}
@@ -1,87 +1,686 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 
 	"maushold/battle-service/model"
 )
 
-type BattleEngine struct{}
+// EngineVersion is bumped whenever a change to the engine alters how a seed
+// plays out, so replays of older battles are not reported as tampered
+const EngineVersion = 4
 
-func NewBattleEngine() *BattleEngine {
-	return &BattleEngine{}
+const (
+	MaxRounds      = 20 // per monster in the larger party
+	MaxPartySize   = 6
+	turnSeedStride = 7919 // spreads the per-round seeds of interactive battles
+	stabBonus      = 1.5
+	critChance     = 16 // 1 in 16 hits is critical
+	critBonus      = 1.5
+	struggleRecoil = 4 // Struggle hurts the user for 1/4 of the damage dealt
+)
+
+var (
+	ErrInvalidAction = errors.New("invalid action")
+	ErrBattleOver    = errors.New("battle is already over")
+	ErrBattleNotOver = errors.New("battle has not finished")
+)
+
+// struggle is used when a monster has no moves or has run out of PP
+var struggle = model.Move{Name: "Struggle", Power: 50, Accuracy: 100}
+
+// RandomSource is the only source of randomness the engine uses, so a battle
+// simulated twice from the same seed plays out identically
+type RandomSource interface {
+	Intn(n int) int
 }
 
-func (e *BattleEngine) SimulateBattle(p1, p2 *model.PlayerMonster) (int, string) {
-	log := ""
-	hp1 := p1.HP
-	hp2 := p2.HP
+// SourceFactory creates a RandomSource for a battle seed
+type SourceFactory func(seed int64) RandomSource
 
-	log += fmt.Sprintf("⚔️ Battle Start!\n%s (HP: %d) vs %s (HP: %d)\n\n",
-		p1.Nickname, hp1, p2.Nickname, hp2)
+// NewMathRandSource is the default SourceFactory backed by math/rand
+func NewMathRandSource(seed int64) RandomSource {
+	return rand.New(rand.NewSource(seed))
+}
+
+// BattleResult is the outcome of a simulated battle
+type BattleResult struct {
+	Winner   int
+	Rounds   int
+	Events   model.BattleEvents
+	Log      string
+	Outcomes []MonsterOutcome
+}
 
-	round := 1
-	for hp1 > 0 && hp2 > 0 && round <= 20 {
-		log += fmt.Sprintf("=== Round %d ===\n", round)
+// MonsterOutcome is how one party member fared, in side then party order
+type MonsterOutcome struct {
+	Side        int
+	Slot        int
+	RemainingHP int
+	DamageDealt int
+	DamageTaken int
+	Knockouts   int
+	Fainted     bool
+}
+
+// FighterState is the part of a party member that changes during a battle
+type FighterState struct {
+	HP             int    `json:"hp"`
+	MovePP         []int  `json:"move_pp"`
+	Status         string `json:"status,omitempty"`          // persistent status, if any
+	StatusTurns    int    `json:"status_turns,omitempty"`    // turns of sleep left
+	ConfusionTurns int    `json:"confusion_turns,omitempty"` // turns of confusion left, 0 when not confused
+	DamageDealt    int    `json:"damage_dealt"`
+	DamageTaken    int    `json:"damage_taken"`
+	Knockouts      int    `json:"knockouts"`
+}
 
-		if p1.Speed >= p2.Speed {
-			damage := calculateDamage(p1.Attack, p2.Defense)
-			hp2 -= damage
-			log += fmt.Sprintf("%s attacks for %d damage! %s HP: %d\n",
-				p1.Nickname, damage, p2.Nickname, maxInt(hp2, 0))
+// BattleState is a battle in progress. Interactive battles keep it between
+// rounds; it holds everything needed to resolve the next one.
+type BattleState struct {
+	Round    int                   `json:"round"` // rounds fought so far
+	Teams    [2][]*model.Combatant `json:"teams"`
+	Fighters [2][]*FighterState    `json:"fighters"`
+	Active   [2]int                `json:"active"`
+	Winner   int                   `json:"winner,omitempty"` // set once the battle is over
+}
 
-			if hp2 > 0 {
-				damage = calculateDamage(p2.Attack, p1.Defense)
-				hp1 -= damage
-				log += fmt.Sprintf("%s attacks for %d damage! %s HP: %d\n",
-					p2.Nickname, damage, p1.Nickname, maxInt(hp1, 0))
+func newBattleState(team1, team2 []*model.Combatant) *BattleState {
+	st := &BattleState{Teams: [2][]*model.Combatant{team1, team2}}
+	for side, team := range st.Teams {
+		for _, c := range team {
+			pp := make([]int, len(c.Moves))
+			for i, m := range c.Moves {
+				pp[i] = m.PP
 			}
+			st.Fighters[side] = append(st.Fighters[side], &FighterState{HP: c.Monster.HP, MovePP: pp})
+		}
+	}
+	return st
+}
+
+// Over reports whether the battle has been decided
+func (st *BattleState) Over() bool {
+	return st.Winner != 0
+}
+
+// maxRounds is the round limit, which grows with the larger party
+func (st *BattleState) maxRounds() int {
+	return MaxRounds * maxInt(len(st.Teams[0]), len(st.Teams[1]))
+}
+
+// parties wraps the state for the engine; changes made to the fighters
+// write straight through to the state
+func (st *BattleState) parties() (*party, *party) {
+	var ps [2]*party
+	for i := range ps {
+		ps[i] = &party{active: st.Active[i]}
+		for slot, c := range st.Teams[i] {
+			ps[i].members = append(ps[i].members, &fighter{Combatant: c, FighterState: st.Fighters[i][slot], side: i + 1, slot: slot})
+		}
+	}
+	return ps[0], ps[1]
+}
+
+// fighter is a party member as seen by the engine
+type fighter struct {
+	*model.Combatant
+	*FighterState
+	side int
+	slot int
+}
+
+// party is one side's team; members fight in order and the next one still
+// standing is sent out automatically when the active monster faints
+type party struct {
+	members []*fighter
+	active  int
+}
+
+func (p *party) current() *fighter {
+	return p.members[p.active]
+}
+
+// remaining counts the members that have not fainted
+func (p *party) remaining() int {
+	n := 0
+	for _, f := range p.members {
+		if f.HP > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// totalHP sums the HP left across the party, used to break ties at the round
+// limit. Fainted members count as 0 however hard they were hit.
+func (p *party) totalHP() int {
+	total := 0
+	for _, f := range p.members {
+		total += maxInt(f.HP, 0)
+	}
+	return total
+}
+
+// replaceFainted sends out the next member still standing, reporting false
+// when the whole party has fainted
+func (p *party) replaceFainted() bool {
+	if p.current().HP > 0 {
+		return true
+	}
+	for i, f := range p.members {
+		if f.HP > 0 {
+			p.active = i
+			return true
+		}
+	}
+	return false
+}
+
+// simulation holds the state of a single battle run
+type simulation struct {
+	rng    RandomSource
+	round  int
+	events model.BattleEvents
+}
+
+type BattleEngine struct {
+	newSource SourceFactory
+}
+
+func NewBattleEngine() *BattleEngine {
+	return NewBattleEngineWithSource(NewMathRandSource)
+}
+
+func NewBattleEngineWithSource(newSource SourceFactory) *BattleEngine {
+	return &BattleEngine{newSource: newSource}
+}
+
+// NewSource returns the random source the engine would use for seed
+func (e *BattleEngine) NewSource(seed int64) RandomSource {
+	return e.newSource(seed)
+}
+
+// SimulateBattle runs a one-on-one battle
+func (e *BattleEngine) SimulateBattle(c1, c2 *model.Combatant, seed int64) *BattleResult {
+	return e.SimulateTeamBattle([]*model.Combatant{c1}, []*model.Combatant{c2}, seed)
+}
+
+// SimulateTeamBattle runs a battle between two ordered parties with the engine
+// choosing every action. Fainted monsters are replaced at the end of the round
+// by the next one in line, and the battle ends when a side has nobody left or
+// the round limit is reached.
+func (e *BattleEngine) SimulateTeamBattle(team1, team2 []*model.Combatant, seed int64) *BattleResult {
+	sim := &simulation{rng: e.newSource(seed)}
+	st := newBattleState(team1, team2)
+	sim.start(st)
+	for !st.Over() {
+		sim.playRound(st, nil, nil)
+	}
+	return battleResult(st, sim.events)
+}
+
+// SimulateAIBattle runs a battle where side 2 is played by ai and the engine
+// chooses side 1's moves as in SimulateTeamBattle
+func (e *BattleEngine) SimulateAIBattle(team1, team2 []*model.Combatant, seed int64, ai Strategy) *BattleResult {
+	sim := &simulation{rng: e.newSource(seed)}
+	st := newBattleState(team1, team2)
+	sim.start(st)
+	for !st.Over() {
+		action := e.AIAction(st, seed, ai)
+		sim.playRound(st, nil, &action)
+	}
+	return battleResult(st, sim.events)
+}
+
+// AIAction is the action ai takes for side 2 in the next round
+func (e *BattleEngine) AIAction(st *BattleState, seed int64, ai Strategy) model.TurnAction {
+	return ai.ChooseAction(st, 2, e.newSource(aiSeed(seed, st.Round+1)))
+}
+
+// StartBattle sets up an interactive battle, returning its state and the
+// events of both leads being sent out
+func (e *BattleEngine) StartBattle(team1, team2 []*model.Combatant) (*BattleState, model.BattleEvents) {
+	sim := &simulation{}
+	st := newBattleState(team1, team2)
+	sim.start(st)
+	return st, sim.events
+}
+
+// ResolveTurn plays the next round of an interactive battle with the actions
+// both players chose. Each round draws from its own source derived from the
+// battle seed so the state does not have to carry the random source.
+func (e *BattleEngine) ResolveTurn(st *BattleState, seed int64, action1, action2 model.TurnAction) (model.BattleEvents, error) {
+	if st.Over() {
+		return nil, ErrBattleOver
+	}
+	if err := ValidateAction(st, 1, action1); err != nil {
+		return nil, err
+	}
+	if err := ValidateAction(st, 2, action2); err != nil {
+		return nil, err
+	}
+
+	sim := &simulation{rng: e.newSource(turnSeed(seed, st.Round+1)), round: st.Round}
+	sim.playRound(st, &action1, &action2)
+	return sim.events, nil
+}
+
+// ReplayTurns re-fights an interactive battle from its recorded turns
+func (e *BattleEngine) ReplayTurns(team1, team2 []*model.Combatant, seed int64, turns model.TurnLog) (*BattleResult, error) {
+	st, events := e.StartBattle(team1, team2)
+	for _, turn := range turns {
+		roundEvents, err := e.ResolveTurn(st, seed, turn.Actions[0], turn.Actions[1])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, roundEvents...)
+	}
+	if !st.Over() {
+		return nil, ErrBattleNotOver
+	}
+	return battleResult(st, events), nil
+}
+
+// DefaultAction is the action the engine takes for a side whose player did
+// not act in time
+func (e *BattleEngine) DefaultAction(st *BattleState, side int) model.TurnAction {
+	p1, p2 := st.parties()
+	self, opponent := p1, p2
+	if side == 2 {
+		self, opponent = p2, p1
+	}
+	return model.TurnAction{Type: model.ActionMove, Slot: maxInt(chooseMove(self.current(), opponent.current()), 0)}
+}
+
+// ValidateAction checks that side can take action in the current state
+func ValidateAction(st *BattleState, side int, action model.TurnAction) error {
+	if side != 1 && side != 2 {
+		return fmt.Errorf("%w: unknown side %d", ErrInvalidAction, side)
+	}
+	p1, p2 := st.parties()
+	p := p1
+	if side == 2 {
+		p = p2
+	}
+	f := p.current()
+
+	switch action.Type {
+	case model.ActionMove:
+		if len(f.Moves) == 0 || !hasPP(f) {
+			return nil // nothing left to choose from, the monster will Struggle
+		}
+		if action.Slot < 0 || action.Slot >= len(f.Moves) {
+			return fmt.Errorf("%w: %s has no move in slot %d", ErrInvalidAction, f.Monster.Nickname, action.Slot)
+		}
+		if f.MovePP[action.Slot] <= 0 {
+			return fmt.Errorf("%w: %s has no PP left", ErrInvalidAction, f.Moves[action.Slot].Name)
+		}
+	case model.ActionSwitch:
+		if action.Slot < 0 || action.Slot >= len(p.members) {
+			return fmt.Errorf("%w: no party member in slot %d", ErrInvalidAction, action.Slot)
+		}
+		if action.Slot == p.active {
+			return fmt.Errorf("%w: %s is already in battle", ErrInvalidAction, f.Monster.Nickname)
+		}
+		if p.members[action.Slot].HP <= 0 {
+			return fmt.Errorf("%w: %s has fainted", ErrInvalidAction, p.members[action.Slot].Monster.Nickname)
+		}
+	default:
+		return fmt.Errorf("%w: action must be %q or %q", ErrInvalidAction, model.ActionMove, model.ActionSwitch)
+	}
+	return nil
+}
+
+func hasPP(f *fighter) bool {
+	for _, pp := range f.MovePP {
+		if pp > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// turnSeed derives the seed for one round of an interactive battle
+func turnSeed(seed int64, round int) int64 {
+	return seed + int64(round)*turnSeedStride
+}
+
+func battleResult(st *BattleState, events model.BattleEvents) *BattleResult {
+	result := &BattleResult{
+		Winner: st.Winner,
+		Rounds: st.Round,
+		Events: events,
+		Log:    RenderBattleLog(events),
+	}
+	for side, fighters := range st.Fighters {
+		for slot, f := range fighters {
+			result.Outcomes = append(result.Outcomes, MonsterOutcome{
+				Side:        side + 1,
+				Slot:        slot,
+				RemainingHP: maxInt(f.HP, 0),
+				DamageDealt: f.DamageDealt,
+				DamageTaken: f.DamageTaken,
+				Knockouts:   f.Knockouts,
+				Fainted:     f.HP <= 0,
+			})
+		}
+	}
+	return result
+}
+
+func (sim *simulation) start(st *BattleState) {
+	p1, p2 := st.parties()
+	sim.emit(model.BattleEvent{Type: model.EventBattleStart})
+	sim.switchIn(p1.current())
+	sim.switchIn(p2.current())
+}
+
+// playRound fights one round. A nil action lets the engine choose a move for
+// that side. Switches happen before any move is used, player 1 first.
+func (sim *simulation) playRound(st *BattleState, action1, action2 *model.TurnAction) {
+	p1, p2 := st.parties()
+	sim.round++
+	sim.emit(model.BattleEvent{Type: model.EventRoundStart})
+
+	switched1 := sim.switchAction(p1, action1)
+	switched2 := sim.switchAction(p2, action2)
+
+	f1, f2 := p1.current(), p2.current()
+	move1 := moveAction(f1, f2, action1)
+	move2 := moveAction(f2, f1, action2)
+
+	switch {
+	case !switched1 && !switched2:
+		if goesFirst(f1, f2, move1, move2) {
+			sim.takeTurn(f1, f2, move1)
+			sim.respond(f2, f1, move2)
 		} else {
-			damage := calculateDamage(p2.Attack, p1.Defense)
-			hp1 -= damage
-			log += fmt.Sprintf("%s attacks for %d damage! %s HP: %d\n",
-				p2.Nickname, damage, p1.Nickname, maxInt(hp1, 0))
-
-			if hp1 > 0 {
-				damage = calculateDamage(p1.Attack, p2.Defense)
-				hp2 -= damage
-				log += fmt.Sprintf("%s attacks for %d damage! %s HP: %d\n",
-					p1.Nickname, damage, p2.Nickname, maxInt(hp2, 0))
-			}
+			sim.takeTurn(f2, f1, move2)
+			sim.respond(f1, f2, move1)
+		}
+	case !switched1:
+		sim.takeTurn(f1, f2, move1)
+	case !switched2:
+		sim.takeTurn(f2, f1, move2)
+	}
+
+	sim.endOfRound(f1, f2)
+
+	alive1, alive2 := p1.replaceFainted(), p2.replaceFainted()
+	if alive1 && alive2 {
+		if p1.current() != f1 {
+			sim.switchIn(p1.current())
 		}
+		if p2.current() != f2 {
+			sim.switchIn(p2.current())
+		}
+	}
+
+	st.Round = sim.round
+	st.Active = [2]int{p1.active, p2.active}
+
+	if !alive1 || !alive2 || st.Round >= st.maxRounds() {
+		last := sim.decide(p1, p2).current()
+		st.Winner = last.side
+		sim.emit(model.BattleEvent{Type: model.EventBattleEnd, Actor: last.side, ActorName: last.Monster.Nickname, RemainingHP: maxInt(last.HP, 0)})
+	}
+}
 
-		log += "\n"
-		round++
+// decide picks the winner of a finished battle: the side with more monsters
+// standing, then the one with more HP left. A dead heat, such as both sides
+// fainting at once, is settled by a coin flip from the round's random source.
+func (sim *simulation) decide(p1, p2 *party) *party {
+	r1, r2 := p1.remaining(), p2.remaining()
+	if r1 == r2 {
+		r1, r2 = p1.totalHP(), p2.totalHP()
 	}
+	if r1 == r2 {
+		r1, r2 = sim.rng.Intn(2), 0
+	}
+	if r1 > r2 {
+		return p1
+	}
+	return p2
+}
 
-	if hp1 > hp2 {
-		log += fmt.Sprintf("🏆 %s wins!\n", p1.Nickname)
-		return 1, log
+// switchAction sends out the party member a switch action asks for
+func (sim *simulation) switchAction(p *party, action *model.TurnAction) bool {
+	if action == nil || action.Type != model.ActionSwitch {
+		return false
 	}
-	log += fmt.Sprintf("🏆 %s wins!\n", p2.Nickname)
-	return 2, log
+	out := p.current()
+	out.ConfusionTurns = 0 // confusion wears off when the monster leaves the field
+	sim.emit(model.BattleEvent{Type: model.EventSwitchOut, Actor: out.side, ActorName: out.Monster.Nickname, RemainingHP: maxInt(out.HP, 0)})
+	p.active = action.Slot
+	sim.switchIn(p.current())
+	return true
 }
 
-func calculateDamage(attack, defense int) int {
-	baseDamage := attack - (defense / 2)
-	if baseDamage < 1 {
-		baseDamage = 1
+// moveAction is the move slot to use, or -1 for Struggle
+func moveAction(attacker, defender *fighter, action *model.TurnAction) int {
+	if action == nil {
+		return chooseMove(attacker, defender)
 	}
+	if action.Type != model.ActionMove || !hasPP(attacker) {
+		return -1
+	}
+	return action.Slot
+}
+
+func (sim *simulation) emit(event model.BattleEvent) {
+	event.Round = sim.round
+	sim.events = append(sim.events, event)
+}
 
-	variance := rand.Intn(10) - 5
-	damage := baseDamage + variance
+func (sim *simulation) switchIn(f *fighter) {
+	sim.emit(model.BattleEvent{Type: model.EventSwitchIn, Actor: f.side, ActorName: f.Monster.Nickname, RemainingHP: f.HP})
+}
 
-	if damage < 1 {
-		damage = 1
+// hurt takes damage off f and records it, counting only the HP f actually had
+func hurt(f *fighter, damage int) {
+	f.DamageTaken += minInt(damage, maxInt(f.HP, 0))
+	f.HP -= damage
+}
+
+// faintCheck records a faint event if the fighter has just been knocked out
+func (sim *simulation) faintCheck(f *fighter) {
+	if f.HP <= 0 {
+		sim.emit(model.BattleEvent{Type: model.EventFaint, Actor: f.side, ActorName: f.Monster.Nickname})
+	}
+}
+
+// chooseMove picks the move slot with the best expected damage, or -1 for Struggle
+func chooseMove(attacker, defender *fighter) int {
+	best := -1
+	bestScore := -1.0
+	for i, move := range attacker.Moves {
+		if attacker.MovePP[i] <= 0 {
+			continue
+		}
+		if score := expectedDamage(attacker, defender, move); score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// takeTurn lets attacker use its move unless a status stops it
+func (sim *simulation) takeTurn(attacker, defender *fighter, slot int) {
+	if sim.canAct(attacker) {
+		sim.useMove(attacker, defender, slot)
+	}
+}
+
+// respond lets the slower monster take its turn. If it is still standing but
+// its target is not, it loses the turn and only its sleep and confusion wear on.
+func (sim *simulation) respond(attacker, defender *fighter, slot int) {
+	switch {
+	case attacker.HP <= 0:
+	case defender.HP > 0:
+		sim.takeTurn(attacker, defender, slot)
+	default:
+		sim.passTurn(attacker)
 	}
+}
 
+// useMove resolves one move from attacker on defender
+func (sim *simulation) useMove(attacker, defender *fighter, slot int) {
+	move := struggle
+	if slot >= 0 {
+		move = attacker.Moves[slot]
+		attacker.MovePP[slot]--
+	}
+
+	event := model.BattleEvent{
+		Actor:      attacker.side,
+		ActorName:  attacker.Monster.Nickname,
+		Target:     defender.side,
+		TargetName: defender.Monster.Nickname,
+		Move:       move.Name,
+		MoveType:   move.Type,
+	}
+
+	if sim.rng.Intn(100) >= move.Accuracy {
+		event.Type = model.EventMiss
+		event.RemainingHP = maxInt(defender.HP, 0)
+		sim.emit(event)
+		return
+	}
+
+	if move.IsStatus() {
+		event.Type = model.EventMove
+		event.RemainingHP = maxInt(defender.HP, 0)
+		sim.emit(event)
+		if !sim.applyStatus(defender, move.Effect) {
+			sim.emit(model.BattleEvent{Type: model.EventMoveFailed, Actor: attacker.side, ActorName: attacker.Monster.Nickname, Move: move.Name})
+		}
+		return
+	}
+
+	multiplier := TypeEffectiveness(move.Type, defender.Monster.Type1, defender.Monster.Type2)
+	damage, critical := sim.calculateDamage(attacker, defender, move, multiplier)
+	attacker.DamageDealt += minInt(damage, maxInt(defender.HP, 0))
+	hurt(defender, damage)
+	if defender.HP <= 0 {
+		attacker.Knockouts++
+	}
+
+	event.Type = model.EventMove
+	event.Damage = damage
+	event.Critical = critical
+	event.Effectiveness = &multiplier
+	event.RemainingHP = maxInt(defender.HP, 0)
+	sim.emit(event)
+
+	if slot < 0 {
+		recoil := maxInt(damage/struggleRecoil, 1)
+		hurt(attacker, recoil)
+		sim.emit(model.BattleEvent{
+			Type:        model.EventRecoil,
+			Actor:       attacker.side,
+			ActorName:   attacker.Monster.Nickname,
+			Damage:      recoil,
+			RemainingHP: maxInt(attacker.HP, 0),
+		})
+	}
+
+	if defender.HP > 0 && damage > 0 && move.Effect != "" && sim.rng.Intn(100) < move.EffectChance {
+		sim.applyStatus(defender, move.Effect)
+	}
+
+	sim.faintCheck(defender)
+	sim.faintCheck(attacker)
+}
+
+// goesFirst orders a round by move priority, then speed; player 1 wins ties
+func goesFirst(f1, f2 *fighter, slot1, slot2 int) bool {
+	p1, p2 := movePriority(f1, slot1), movePriority(f2, slot2)
+	if p1 != p2 {
+		return p1 > p2
+	}
+	return effectiveSpeed(f1) >= effectiveSpeed(f2)
+}
+
+func movePriority(f *fighter, slot int) int {
+	if slot < 0 {
+		return struggle.Priority
+	}
+	return f.Moves[slot].Priority
+}
+
+// expectedDamage scores a move for chooseMove. A status move is worth a quarter
+// of the target's max HP while the target can still receive its effect.
+func expectedDamage(attacker, defender *fighter, move model.Move) float64 {
+	if move.IsStatus() {
+		if !canReceive(defender, move.Effect) {
+			return 0
+		}
+		return float64(defender.Monster.HP) / 4 * float64(move.Accuracy) / 100
+	}
+	multiplier := TypeEffectiveness(move.Type, defender.Monster.Type1, defender.Monster.Type2)
+	return float64(baseDamage(attacker, defender, move)) *
+		stab(&attacker.Monster, move) * multiplier * float64(move.Accuracy) / 100
+}
+
+// baseDamage scales the attack/defense difference by move power; a 50 power move
+// hits as hard as the generic attack did before moves existed. Monsters carry a
+// single attack/defense pair, so physical and special moves read the same stats.
+// A burn halves the damage of physical moves.
+func baseDamage(attacker, defender *fighter, move model.Move) int {
+	base := attacker.Monster.Attack - (defender.Monster.Defense / 2)
+	if base < 1 {
+		base = 1
+	}
+	damage := base * move.Power / 50
+	if attacker.Status == model.StatusBurn && move.Category != model.MoveCategorySpecial {
+		damage /= burnAttackDiv
+	}
 	return damage
 }
 
+// stab is the same-type attack bonus for moves matching one of the user's types
+func stab(attacker *model.PlayerMonster, move model.Move) float64 {
+	moveType := normalizeType(move.Type)
+	if moveType != "" && (moveType == normalizeType(attacker.Type1) || moveType == normalizeType(attacker.Type2)) {
+		return stabBonus
+	}
+	return 1
+}
+
+func (sim *simulation) calculateDamage(attacker, defender *fighter, move model.Move, multiplier float64) (int, bool) {
+	if multiplier == EffectivenessImmune || move.Power == 0 {
+		return 0, false
+	}
+
+	variance := sim.rng.Intn(10) - 5
+	damage := float64(baseDamage(attacker, defender, move)+variance) * stab(&attacker.Monster, move) * multiplier
+
+	critical := sim.rng.Intn(critChance) == 0
+	if critical {
+		damage *= critBonus
+	}
+
+	if damage < 1 {
+		return 1, critical
+	}
+
+	return int(damage), critical
+}
+
 func maxInt(a, b int) int {
 	if a > b {
 		return a
 	}
 	return b
 }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
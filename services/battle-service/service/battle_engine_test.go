@@ -0,0 +1,303 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"maushold/battle-service/model"
+)
+
+var (
+	tackle   = model.Move{Name: "Tackle", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100, PP: 35}
+	ember    = model.Move{Name: "Ember", Type: "Fire", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 25, Effect: model.StatusBurn, EffectChance: 10}
+	vineWhip = model.Move{Name: "Vine Whip", Type: "Grass", Category: model.MoveCategoryPhysical, Power: 45, Accuracy: 100, PP: 25}
+	poison   = model.Move{Name: "Poison Powder", Type: "Poison", Category: model.MoveCategoryStatus, Accuracy: 75, PP: 35, Effect: model.StatusPoison}
+	waterGun = model.Move{Name: "Water Gun", Type: "Water", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 25}
+	quickHit = model.Move{Name: "Quick Attack", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100, PP: 30, Priority: 1}
+)
+
+func combatant(name, type1 string, hp, attack, defense, speed int, moves ...model.Move) *model.Combatant {
+	return &model.Combatant{
+		Monster: model.PlayerMonster{Nickname: name, Type1: type1, HP: hp, Attack: attack, Defense: defense, Speed: speed, Level: 10},
+		Moves:   moves,
+	}
+}
+
+func charmander() *model.Combatant {
+	return combatant("Charmander", "Fire", 100, 52, 43, 65, ember, tackle)
+}
+
+func bulbasaur() *model.Combatant {
+	return combatant("Bulbasaur", "Grass", 120, 49, 49, 45, vineWhip, poison, tackle)
+}
+
+func squirtle() *model.Combatant {
+	return combatant("Squirtle", "Water", 110, 48, 65, 43, waterGun, tackle)
+}
+
+func pidgey() *model.Combatant {
+	return combatant("Pidgey", "Normal", 95, 45, 40, 56, quickHit, tackle)
+}
+
+func TestSimulateBattleExactOutcome(t *testing.T) {
+	result := NewBattleEngine().SimulateBattle(pidgey(), bulbasaur(), 1)
+
+	if result.Winner != 2 || result.Rounds != 3 {
+		t.Fatalf("winner %d after %d rounds, want 2 after 3", result.Winner, result.Rounds)
+	}
+	want := `⚔️ Battle Start!
+Pidgey enters the battle! (HP: 95)
+Bulbasaur enters the battle! (HP: 120)
+
+=== Round 1 ===
+Pidgey used Quick Attack for 27 damage! Bulbasaur HP: 93
+Bulbasaur used Vine Whip for 33 damage! Pidgey HP: 62
+
+=== Round 2 ===
+Pidgey used Quick Attack for 16 damage! Bulbasaur HP: 77
+Bulbasaur used Vine Whip for 37 damage! Pidgey HP: 25
+
+=== Round 3 ===
+Pidgey used Quick Attack for 30 damage! Bulbasaur HP: 47
+Bulbasaur used Vine Whip for 33 damage! Pidgey HP: 0
+Pidgey fainted!
+
+🏆 Bulbasaur wins!
+`
+	if result.Log != want {
+		t.Errorf("log =\n%s\nwant\n%s", result.Log, want)
+	}
+}
+
+func TestSimulateTeamBattleExactOutcome(t *testing.T) {
+	result := NewBattleEngine().SimulateTeamBattle(
+		[]*model.Combatant{charmander(), squirtle()},
+		[]*model.Combatant{bulbasaur(), pidgey()},
+		7,
+	)
+
+	if result.Winner != 1 || result.Rounds != 7 {
+		t.Fatalf("winner %d after %d rounds, want 1 after 7", result.Winner, result.Rounds)
+	}
+	wantOutcomes := []MonsterOutcome{
+		{Side: 1, Slot: 0, RemainingHP: 0, DamageDealt: 159, DamageTaken: 100, Knockouts: 1, Fainted: true},
+		{Side: 1, Slot: 1, RemainingHP: 89, DamageDealt: 56, DamageTaken: 21, Knockouts: 1},
+		{Side: 2, Slot: 0, RemainingHP: 0, DamageDealt: 54, DamageTaken: 120, Fainted: true},
+		{Side: 2, Slot: 1, RemainingHP: 0, DamageDealt: 67, DamageTaken: 95, Knockouts: 1, Fainted: true},
+	}
+	if !reflect.DeepEqual(result.Outcomes, wantOutcomes) {
+		t.Errorf("outcomes = %+v, want %+v", result.Outcomes, wantOutcomes)
+	}
+	want := `⚔️ Battle Start!
+Charmander enters the battle! (HP: 100)
+Bulbasaur enters the battle! (HP: 120)
+
+=== Round 1 ===
+Charmander used Ember for 51 damage! Bulbasaur HP: 69
+It's super effective! (x2)
+Bulbasaur used Tackle for 37 damage! Charmander HP: 63
+A critical hit!
+
+=== Round 2 ===
+Charmander used Ember for 57 damage! Bulbasaur HP: 12
+It's super effective! (x2)
+Bulbasaur used Tackle for 17 damage! Charmander HP: 46
+
+=== Round 3 ===
+Charmander used Ember for 76 damage! Bulbasaur HP: 0
+A critical hit!
+It's super effective! (x2)
+Bulbasaur fainted!
+Pidgey enters the battle! (HP: 95)
+
+=== Round 4 ===
+Pidgey used Quick Attack for 34 damage! Charmander HP: 12
+Charmander used Ember for 39 damage! Pidgey HP: 56
+
+=== Round 5 ===
+Pidgey used Quick Attack for 22 damage! Charmander HP: 0
+Charmander fainted!
+Squirtle enters the battle! (HP: 110)
+
+=== Round 6 ===
+Pidgey used Quick Attack for 12 damage! Squirtle HP: 98
+Squirtle used Water Gun for 30 damage! Pidgey HP: 26
+
+=== Round 7 ===
+Pidgey used Quick Attack for 9 damage! Squirtle HP: 89
+Squirtle used Water Gun for 33 damage! Pidgey HP: 0
+Pidgey fainted!
+
+🏆 Squirtle wins!
+`
+	if result.Log != want {
+		t.Errorf("log =\n%s\nwant\n%s", result.Log, want)
+	}
+}
+
+func TestSimulateTeamBattleIsDeterministic(t *testing.T) {
+	engine := NewBattleEngine()
+	play := func(seed int64) *BattleResult {
+		return engine.SimulateTeamBattle(
+			[]*model.Combatant{charmander(), squirtle()},
+			[]*model.Combatant{bulbasaur(), pidgey()},
+			seed,
+		)
+	}
+
+	for _, seed := range []int64{1, 7, 1234567890} {
+		if first, second := play(seed), play(seed); !reflect.DeepEqual(first, second) {
+			t.Errorf("seed %d played out differently twice", seed)
+		}
+	}
+}
+
+func TestReplayTurnsMatchesLiveBattle(t *testing.T) {
+	const seed = 99
+	engine := NewBattleEngine()
+	team1 := func() []*model.Combatant { return []*model.Combatant{squirtle(), charmander()} }
+	team2 := func() []*model.Combatant { return []*model.Combatant{pidgey(), bulbasaur()} }
+	attack := model.TurnAction{Type: model.ActionMove, Slot: 0}
+	swap := model.TurnAction{Type: model.ActionSwitch, Slot: 1}
+
+	st, events := engine.StartBattle(team1(), team2())
+	turns := model.TurnLog{
+		{Round: 1, Actions: [2]model.TurnAction{attack, attack}},
+		{Round: 2, Actions: [2]model.TurnAction{swap, swap}},
+	}
+	for round := 3; round <= 6; round++ {
+		turns = append(turns, model.TurnRecord{Round: round, Actions: [2]model.TurnAction{attack, attack}})
+	}
+	for _, turn := range turns {
+		roundEvents, err := engine.ResolveTurn(st, seed, turn.Actions[0], turn.Actions[1])
+		if err != nil {
+			t.Fatalf("round %d: %v", turn.Round, err)
+		}
+		events = append(events, roundEvents...)
+	}
+	if st.Winner != 1 || st.Round != 6 {
+		t.Fatalf("winner %d after %d rounds, want 1 after 6", st.Winner, st.Round)
+	}
+
+	replay, err := engine.ReplayTurns(team1(), team2(), seed, turns)
+	if err != nil {
+		t.Fatalf("ReplayTurns: %v", err)
+	}
+	if replay.Winner != st.Winner {
+		t.Errorf("replayed winner %d, want %d", replay.Winner, st.Winner)
+	}
+	if !reflect.DeepEqual(replay.Events, events) {
+		t.Errorf("replayed events differ from the live battle:\n%s\nwant\n%s", replay.Log, RenderBattleLog(events))
+	}
+
+	if _, err := engine.ReplayTurns(team1(), team2(), seed, turns[:3]); !errors.Is(err, ErrBattleNotOver) {
+		t.Errorf("replaying an unfinished battle: err = %v, want %v", err, ErrBattleNotOver)
+	}
+}
+
+func TestBattlePoints(t *testing.T) {
+	s := &battleService{battleEngine: NewBattleEngine()}
+	even := model.Battle{
+		Player1ID: 1, Player2ID: 2,
+		RatingModel:   RatingElo,
+		Player1Rating: DefaultRating, Player2Rating: DefaultRating,
+	}
+	underdog := even
+	underdog.Player1Rating = 1300
+
+	tests := []struct {
+		name     string
+		battle   model.Battle
+		winnerID uint
+		won      int
+		lost     int
+	}{
+		{"no model is elo", model.Battle{Player1ID: 1, Player2ID: 2, Player1Rating: DefaultRating, Player2Rating: DefaultRating}, 1, 32, 32},
+		{"even elo", even, 1, 32, 32},
+		{"elo upset", underdog, 1, 49, 49},
+		{"elo favourite", underdog, 2, 15, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			won, lost := s.battlePoints(&tt.battle, tt.winnerID)
+			if won != tt.won || lost != tt.lost {
+				t.Errorf("points = %d/%d, want %d/%d", won, lost, tt.won, tt.lost)
+			}
+		})
+	}
+}
+
+func TestEndOfRoundRunsWhenOpponentFaints(t *testing.T) {
+	engine := NewBattleEngine()
+	st, _ := engine.StartBattle([]*model.Combatant{charmander()}, []*model.Combatant{bulbasaur(), pidgey()})
+	st.Fighters[0][0].Status = model.StatusPoison
+	st.Fighters[1][0].HP = 1
+
+	attack := model.TurnAction{Type: model.ActionMove, Slot: 0}
+	events, err := engine.ResolveTurn(st, 1, attack, attack)
+	if err != nil {
+		t.Fatalf("ResolveTurn: %v", err)
+	}
+
+	var poisoned bool
+	for _, e := range events {
+		if e.Type == model.EventStatusDamage && e.Actor == 1 && e.Status == model.StatusPoison {
+			poisoned = true
+		}
+	}
+	if !poisoned {
+		t.Errorf("Charmander took no poison damage in the round it knocked out Bulbasaur:\n%s", RenderBattleLog(events))
+	}
+	if want := 100 - 100/poisonDamageDiv; st.Fighters[0][0].HP != want {
+		t.Errorf("Charmander HP = %d, want %d", st.Fighters[0][0].HP, want)
+	}
+}
+
+// fixedSource draws the same value every time, capped to the range asked for
+type fixedSource int
+
+func (s fixedSource) Intn(n int) int {
+	return minInt(int(s), n-1)
+}
+
+func testParty(hps ...int) *party {
+	p := &party{}
+	for slot, hp := range hps {
+		p.members = append(p.members, &fighter{
+			Combatant:    pidgey(),
+			FighterState: &FighterState{HP: hp},
+			slot:         slot,
+		})
+	}
+	return p
+}
+
+func TestDecideWinner(t *testing.T) {
+	tests := []struct {
+		name     string
+		hp1, hp2 []int
+		coin     fixedSource
+		want     int
+	}{
+		{"more monsters standing", []int{0, 5}, []int{0, -10}, 0, 1},
+		{"more HP left", []int{0, 20}, []int{30, 0}, 0, 2},
+		{"overkill does not count", []int{-50, 30}, []int{0, 20}, 0, 1},
+		{"dead heat goes to side 2 on tails", []int{0, 20}, []int{20, -5}, 0, 2},
+		{"dead heat goes to side 1 on heads", []int{0, 20}, []int{20, -5}, 1, 1},
+		{"both wiped out", []int{-3}, []int{-8}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p1, p2 := testParty(tt.hp1...), testParty(tt.hp2...)
+			sim := &simulation{rng: tt.coin}
+			got := 2
+			if sim.decide(p1, p2) == p1 {
+				got = 1
+			}
+			if got != tt.want {
+				t.Errorf("winner = side %d, want side %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"maushold/battle-service/model"
+)
+
+// RenderBattleLog turns structured battle events into the human-readable log
+func RenderBattleLog(events []model.BattleEvent) string {
+	var b strings.Builder
+
+	for i, e := range events {
+		switch e.Type {
+		case model.EventBattleStart:
+			b.WriteString("⚔️ Battle Start!\n")
+		case model.EventSwitchIn:
+			fmt.Fprintf(&b, "%s enters the battle! (HP: %d)\n", e.ActorName, e.RemainingHP)
+		case model.EventSwitchOut:
+			fmt.Fprintf(&b, "%s, come back!\n", e.ActorName)
+		case model.EventRoundStart:
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "=== Round %d ===\n", e.Round)
+		case model.EventMiss:
+			fmt.Fprintf(&b, "%s used %s, but it missed!\n", e.ActorName, e.Move)
+		case model.EventMove:
+			if e.Effectiveness == nil {
+				// status moves deal no damage and carry no effectiveness
+				fmt.Fprintf(&b, "%s used %s!\n", e.ActorName, e.Move)
+				break
+			}
+			fmt.Fprintf(&b, "%s used %s for %d damage! %s HP: %d\n",
+				e.ActorName, e.Move, e.Damage, e.TargetName, e.RemainingHP)
+			if e.Critical {
+				b.WriteString("A critical hit!\n")
+			}
+			if e.Effectiveness != nil {
+				if text := EffectivenessText(*e.Effectiveness); text != "" {
+					fmt.Fprintf(&b, "%s (x%g)\n", text, *e.Effectiveness)
+				}
+			}
+		case model.EventRecoil:
+			fmt.Fprintf(&b, "%s is hit by recoil for %d damage! %s HP: %d\n",
+				e.ActorName, e.Damage, e.ActorName, e.RemainingHP)
+		case model.EventMoveFailed:
+			b.WriteString("But it failed!\n")
+		case model.EventStatusApplied:
+			fmt.Fprintf(&b, "%s %s\n", e.ActorName, statusAppliedText[e.Status])
+		case model.EventStatusDamage:
+			fmt.Fprintf(&b, "%s %s for %d damage! %s HP: %d\n",
+				e.ActorName, statusDamageText[e.Status], e.Damage, e.ActorName, e.RemainingHP)
+		case model.EventStatusCured:
+			fmt.Fprintf(&b, "%s %s\n", e.ActorName, statusCuredText[e.Status])
+		case model.EventCantMove:
+			fmt.Fprintf(&b, "%s %s\n", e.ActorName, cantMoveText[e.Status])
+		case model.EventFaint:
+			fmt.Fprintf(&b, "%s fainted!\n", e.ActorName)
+		case model.EventBattleEnd:
+			fmt.Fprintf(&b, "\n🏆 %s wins!\n", e.ActorName)
+		}
+	}
+
+	return b.String()
+}
+
+var statusAppliedText = map[string]string{
+	model.StatusBurn:      "was burned!",
+	model.StatusPoison:    "was poisoned!",
+	model.StatusParalysis: "is paralyzed! It may be unable to move!",
+	model.StatusSleep:     "fell asleep!",
+	model.StatusFreeze:    "was frozen solid!",
+	model.StatusConfusion: "became confused!",
+}
+
+var statusDamageText = map[string]string{
+	model.StatusBurn:      "is hurt by its burn",
+	model.StatusPoison:    "is hurt by poison",
+	model.StatusConfusion: "hurt itself in its confusion",
+}
+
+var statusCuredText = map[string]string{
+	model.StatusSleep:     "woke up!",
+	model.StatusFreeze:    "thawed out!",
+	model.StatusConfusion: "snapped out of its confusion!",
+}
+
+var cantMoveText = map[string]string{
+	model.StatusSleep:     "is fast asleep.",
+	model.StatusFreeze:    "is frozen solid!",
+	model.StatusParalysis: "is paralyzed! It can't move!",
+}
@@ -1,95 +1,459 @@
 package service
 
 import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
 	"errors"
-	"math/rand"
+	"fmt"
+	"log"
 	"time"
 
 	"maushold/battle-service/model"
 	"maushold/battle-service/repository"
+	"maushold/events"
+	"maushold/outbox"
 
 	"github.com/go-redis/redis/v8"
 )
 
 type BattleService interface {
 	CreateBattle(player1ID, player2ID, monster1ID, monster2ID uint) (*model.Battle, error)
+	CreateTeamBattle(player1ID, player2ID uint, party1, party2 []uint) (*model.Battle, error)
 	GetBattle(id uint) (*model.Battle, error)
 	GetPlayerBattles(playerID uint) ([]model.Battle, error)
 	GetRecentBattles() ([]model.Battle, error)
+	ReplayBattle(id uint) (*model.BattleReplay, error)
+	GetBattleEvents(id uint) (model.BattleEvents, error)
+	CreateInteractiveBattle(player1ID, player2ID uint, party1, party2 []uint) (*model.Battle, error)
+	CreateMatchedBattle(player1ID, player2ID uint, party1, party2 []uint, interactive bool) (*model.Battle, error)
+	SubmitTurn(battleID, playerID uint, action model.TurnAction) (*model.TurnStatus, error)
+	GetLiveBattle(battleID uint) (*model.LiveBattleView, error)
+	ResolveExpiredTurns()
+	StartTurnTimer()
+	WatchBattle(ctx context.Context, battleID uint, send func(model.StreamMessage) error) error
+	CreatePvEBattle(opts PvEOptions) (*model.Battle, error)
+	GetTrainers() []model.Trainer
+	SimulateMatchup(opts SimulateOptions) (*model.SimulationReport, error)
 }
 
+var (
+	ErrReplayUnavailable = errors.New("battle has no replay data")
+	ErrInvalidParty      = errors.New("invalid party")
+	ErrSamePlayer        = errors.New("a player cannot battle themselves")
+	ErrNotParticipant    = errors.New("player is not in this battle")
+	ErrAlreadyActed      = errors.New("action already submitted for this round")
+	ErrBattleBusy        = errors.New("battle is being updated, try again")
+)
+
 type battleService struct {
-	repo         repository.BattleRepository
-	playerClient *PlayerClient
-	battleEngine *BattleEngine
-	redis        *redis.Client
+	repo          repository.BattleRepository
+	playerClient  *PlayerClient
+	monsterClient *MonsterClient
+	rankingClient *RankingClient
+	ratingModel   RatingModel
+	battleEngine  *BattleEngine
+	redis         *redis.Client
+	liveStore     *LiveBattleStore
+	stream        *BattleStream
+	turnTimeout   time.Duration
+	trainers      []model.Trainer
 }
 
 func NewBattleService(
 	repo repository.BattleRepository,
 	playerClient *PlayerClient,
+	monsterClient *MonsterClient,
+	rankingClient *RankingClient,
+	ratingModel RatingModel,
 	battleEngine *BattleEngine,
 	redisClient *redis.Client,
+	turnTimeout time.Duration,
+	trainers []model.Trainer,
 ) BattleService {
 	return &battleService{
-		repo:         repo,
-		playerClient: playerClient,
-		battleEngine: battleEngine,
-		redis:        redisClient,
+		repo:          repo,
+		playerClient:  playerClient,
+		monsterClient: monsterClient,
+		rankingClient: rankingClient,
+		ratingModel:   ratingModel,
+		battleEngine:  battleEngine,
+		redis:         redisClient,
+		liveStore:     NewLiveBattleStore(redisClient),
+		stream:        NewBattleStream(redisClient),
+		turnTimeout:   turnTimeout,
+		trainers:      trainers,
 	}
 }
 
 func (s *battleService) CreateBattle(player1ID, player2ID, monster1ID, monster2ID uint) (*model.Battle, error) {
-	monster1, err := s.playerClient.GetPlayerMonster(player1ID, monster1ID)
+	return s.CreateTeamBattle(player1ID, player2ID, []uint{monster1ID}, []uint{monster2ID})
+}
+
+// CreateTeamBattle runs a battle between two ordered parties of up to
+// MaxPartySize monsters each
+func (s *battleService) CreateTeamBattle(player1ID, player2ID uint, party1, party2 []uint) (*model.Battle, error) {
+	battle, err := s.newBattle(player1ID, player2ID, party1, party2)
 	if err != nil {
-		return nil, errors.New("monster 1 not found")
+		return nil, err
 	}
+	return s.runBattle(battle)
+}
 
-	monster2, err := s.playerClient.GetPlayerMonster(player2ID, monster2ID)
+// CreateMatchedBattle starts the battle between two players matchmaking
+// paired, writing battle.matched in the same transaction as the battle
+func (s *battleService) CreateMatchedBattle(player1ID, player2ID uint, party1, party2 []uint, interactive bool) (*model.Battle, error) {
+	battle, err := s.newBattle(player1ID, player2ID, party1, party2)
 	if err != nil {
-		return nil, errors.New("monster 2 not found")
+		return nil, err
 	}
 
-	battle := &model.Battle{
-		Player1ID:  player1ID,
-		Player2ID:  player2ID,
-		Monster1ID: monster1ID,
-		Monster2ID: monster2ID,
-		Status:     "in_progress",
+	matched := func() events.Event {
+		return events.BattleMatched{
+			BattleID:    battle.ID,
+			Player1ID:   battle.Player1ID,
+			Player2ID:   battle.Player2ID,
+			Interactive: interactive,
+		}
+	}
+	if interactive {
+		return s.startLiveBattle(battle, matched)
+	}
+	return s.runBattle(battle, matched)
+}
+
+// runBattle saves a new battle together with any events and simulates it
+// to the end
+func (s *battleService) runBattle(battle *model.Battle, pending ...outbox.Pending) (*model.Battle, error) {
+	battle.Status = model.BattleStatusInProgress
+
+	if err := s.repo.Create(battle, pending...); err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.Create(battle); err != nil {
+	team1, team2, _ := battleTeams(battle)
+	result := s.battleEngine.SimulateTeamBattle(team1, team2, battle.Seed)
+	if err := s.completeBattle(battle, result); err != nil {
+		return nil, err
+	}
+
+	return battle, nil
+}
+
+// newBattle validates and loads both parties into a battle that has not
+// been saved yet
+func (s *battleService) newBattle(player1ID, player2ID uint, party1, party2 []uint) (*model.Battle, error) {
+	if player1ID == player2ID {
+		return nil, ErrSamePlayer
+	}
+	if err := validateParty(party1); err != nil {
+		return nil, fmt.Errorf("%w: player 1: %v", ErrInvalidParty, err)
+	}
+	if err := validateParty(party2); err != nil {
+		return nil, fmt.Errorf("%w: player 2: %v", ErrInvalidParty, err)
+	}
+
+	participants1, err := s.loadParty(1, player1ID, party1)
+	if err != nil {
+		return nil, err
+	}
+	participants2, err := s.loadParty(2, player2ID, party2)
+	if err != nil {
 		return nil, err
 	}
 
-	winner, battleLog := s.battleEngine.SimulateBattle(monster1, monster2)
+	mode := model.BattleModeSingle
+	if len(party1) > 1 || len(party2) > 1 {
+		mode = model.BattleModeTeam
+	}
+
+	battle := &model.Battle{
+		Player1ID:     player1ID,
+		Player2ID:     player2ID,
+		Monster1ID:    party1[0],
+		Monster2ID:    party2[0],
+		Mode:          mode,
+		Seed:          newSeed(),
+		EngineVersion: EngineVersion,
+		Participants:  append(participants1, participants2...),
+	}
+	s.recordRatings(battle)
+
+	return battle, nil
+}
+
+// newSeed draws a battle seed from crypto/rand. A seed taken from the clock
+// could be worked out from the battle's creation time, letting a player in a
+// live battle predict every roll.
+func newSeed() int64 {
+	var b [8]byte
+	crand.Read(b[:]) // never fails, it crashes the program instead
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// recordRatings stores both players' ratings on a new battle so the points
+// are based on where they stood when it started. If ranking-service can't be
+// reached the players are treated as equals rather than refusing the battle.
+func (s *battleService) recordRatings(battle *model.Battle) {
+	var ratings [2]model.PlayerRating
+	for i, playerID := range []uint{battle.Player1ID, battle.Player2ID} {
+		rating, err := s.rankingClient.GetRating(playerID)
+		if err != nil {
+			log.Printf("Failed to get rating of player %d, using the default: %v", playerID, err)
+			rating = model.PlayerRating{Rating: DefaultRating, Deviation: DefaultRatingDeviation}
+		}
+		ratings[i] = rating
+	}
+
+	battle.RatingModel = s.ratingModel.Name()
+	battle.Player1Rating, battle.Player1Deviation = ratings[0].Rating, ratings[0].Deviation
+	battle.Player2Rating, battle.Player2Deviation = ratings[1].Rating, ratings[1].Deviation
+}
 
-	if winner == 1 {
-		battle.WinnerID = player1ID
+// completeBattle records the result on the battle and saves it together with
+// its battle.completed event
+func (s *battleService) completeBattle(battle *model.Battle, result *BattleResult) error {
+	if result.Winner == 1 {
+		battle.WinnerID = battle.Player1ID
 	} else {
-		battle.WinnerID = player2ID
+		battle.WinnerID = battle.Player2ID
 	}
 
-	battle.PointsWon = 50 + rand.Intn(50)
-	battle.PointsLost = 20 + rand.Intn(30)
-	battle.BattleLog = battleLog
-	battle.Status = "completed"
+	for i, outcome := range result.Outcomes {
+		p := &battle.Participants[i]
+		p.RemainingHP = outcome.RemainingHP
+		p.DamageDealt = outcome.DamageDealt
+		p.DamageTaken = outcome.DamageTaken
+		p.Knockouts = outcome.Knockouts
+		p.Fainted = outcome.Fainted
+	}
+
+	if battle.AIDifficulty == "" {
+		battle.PointsWon, battle.PointsLost = s.battlePoints(battle, battle.WinnerID)
+	}
+	battle.BattleLog = result.Log
+	battle.Events = result.Events
+	battle.Status = model.BattleStatusCompleted
 	now := time.Now()
 	battle.CompletedAt = &now
 
-	s.repo.Update(battle)
+	// PvE battles are practice and don't affect rankings
+	var pending []outbox.Pending
+	if battle.AIDifficulty == "" {
+		pending = append(pending, func() events.Event {
+			return events.BattleCompleted{
+				BattleID:   battle.ID,
+				WinnerID:   battle.WinnerID,
+				LoserID:    loserID(battle),
+				PointsWon:  battle.PointsWon,
+				PointsLost: battle.PointsLost,
+			}
+		})
+	}
 
-	return battle, nil
+	return s.repo.Update(battle, pending...)
+}
+
+func loserID(battle *model.Battle) uint {
+	if battle.WinnerID == battle.Player1ID {
+		return battle.Player2ID
+	}
+	return battle.Player1ID
+}
+
+func validateParty(ids []uint) error {
+	if len(ids) == 0 {
+		return errors.New("party is empty")
+	}
+	if len(ids) > MaxPartySize {
+		return fmt.Errorf("party has %d monsters, the limit is %d", len(ids), MaxPartySize)
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("monster %d is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// loadParty fetches a player's monsters and their moves as battle participants
+func (s *battleService) loadParty(side int, playerID uint, ids []uint) ([]model.BattleParticipant, error) {
+	participants := make([]model.BattleParticipant, 0, len(ids))
+	for slot, id := range ids {
+		monster, err := s.playerClient.GetPlayerMonster(playerID, id)
+		if err != nil {
+			return nil, fmt.Errorf("monster %d of player %d not found", id, side)
+		}
+
+		moves, err := s.monsterClient.GetMoveSet(monster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load moves for monster %d of player %d", id, side)
+		}
+
+		participants = append(participants, model.BattleParticipant{
+			Side:            side,
+			Slot:            slot,
+			PlayerID:        playerID,
+			PlayerMonsterID: id,
+			Snapshot:        &model.Combatant{Monster: *monster, Moves: moves},
+		})
+	}
+	return participants, nil
+}
+
+// battleTeams rebuilds both parties from a stored battle, falling back to the
+// single monster snapshots of battles recorded before parties existed
+func battleTeams(battle *model.Battle) ([]*model.Combatant, []*model.Combatant, bool) {
+	var team1, team2 []*model.Combatant
+	for _, p := range battle.Participants {
+		if p.Snapshot == nil {
+			return nil, nil, false
+		}
+		if p.Side == 1 {
+			team1 = append(team1, p.Snapshot)
+		} else {
+			team2 = append(team2, p.Snapshot)
+		}
+	}
+
+	if len(team1) == 0 && len(team2) == 0 && battle.Monster1Snapshot != nil && battle.Monster2Snapshot != nil {
+		team1 = []*model.Combatant{battle.Monster1Snapshot}
+		team2 = []*model.Combatant{battle.Monster2Snapshot}
+	}
+
+	return team1, team2, len(team1) > 0 && len(team2) > 0
+}
+
+// ReplayBattle re-simulates a stored battle from its seed and snapshots and
+// reports whether the outcome matches what was recorded
+func (s *battleService) ReplayBattle(id uint) (*model.BattleReplay, error) {
+	battle, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	team1, team2, ok := battleTeams(battle)
+	if battle.Status != model.BattleStatusCompleted || !ok {
+		return nil, ErrReplayUnavailable
+	}
+	if battle.EngineVersion != EngineVersion {
+		return nil, fmt.Errorf("%w: recorded with engine version %d, current is %d",
+			ErrReplayUnavailable, battle.EngineVersion, EngineVersion)
+	}
+
+	var result *BattleResult
+	if battle.Interactive {
+		result, err = s.battleEngine.ReplayTurns(team1, team2, battle.Seed, battle.Turns)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReplayUnavailable, err)
+		}
+	} else if battle.AIDifficulty != "" {
+		ai, err := NewStrategy(battle.AIDifficulty)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrReplayUnavailable, err)
+		}
+		result = s.battleEngine.SimulateAIBattle(team1, team2, battle.Seed, ai)
+	} else {
+		result = s.battleEngine.SimulateTeamBattle(team1, team2, battle.Seed)
+	}
+
+	replay := &model.BattleReplay{
+		BattleID:       battle.ID,
+		Seed:           battle.Seed,
+		StoredWinnerID: battle.WinnerID,
+		BattleLog:      result.Log,
+	}
+
+	if result.Winner == 1 {
+		replay.ReplayedWinnerID = battle.Player1ID
+	} else {
+		replay.ReplayedWinnerID = battle.Player2ID
+	}
+
+	var pointsWon, pointsLost int
+	if battle.AIDifficulty == "" {
+		pointsWon, pointsLost = s.battlePoints(battle, replay.ReplayedWinnerID)
+	}
+	replay.WinnerMatches = replay.ReplayedWinnerID == replay.StoredWinnerID
+	replay.LogMatches = result.Log == battle.BattleLog
+	replay.PointsMatch = pointsWon == battle.PointsWon && pointsLost == battle.PointsLost
+	replay.Verified = replay.WinnerMatches && replay.LogMatches && replay.PointsMatch
+
+	return replay, nil
+}
+
+// battlePoints works out the points exchanged when winnerID wins, from the
+// ratings stored on the battle
+func (s *battleService) battlePoints(battle *model.Battle, winnerID uint) (int, int) {
+	name := battle.RatingModel
+	if name == "" {
+		name = DefaultRatingModel
+	}
+	ratingModel, err := NewRatingModel(name)
+	if err != nil {
+		log.Printf("Battle %d: %v", battle.ID, err)
+		return 0, 0
+	}
+
+	winner := model.PlayerRating{Rating: battle.Player1Rating, Deviation: battle.Player1Deviation}
+	loser := model.PlayerRating{Rating: battle.Player2Rating, Deviation: battle.Player2Deviation}
+	if winnerID != battle.Player1ID {
+		winner, loser = loser, winner
+	}
+	return ratingModel.Exchange(winner, loser)
 }
 
 func (s *battleService) GetBattle(id uint) (*model.Battle, error) {
-	return s.repo.FindByID(id)
+	battle, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	hideLiveSeeds(battle)
+	return battle, nil
+}
+
+// hideLiveSeeds clears the seed of battles still being fought; it would let
+// a player predict every remaining roll
+func hideLiveSeeds(battles ...*model.Battle) {
+	for _, b := range battles {
+		if b.Status == model.BattleStatusPending || b.Status == model.BattleStatusInProgress {
+			b.Seed = 0
+		}
+	}
+}
+
+// GetBattleEvents returns the structured log of a battle, so far for a
+// battle that is still being fought
+func (s *battleService) GetBattleEvents(id uint) (model.BattleEvents, error) {
+	battle, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if battle.Interactive && battle.Status != model.BattleStatusCompleted {
+		if lb, err := s.liveStore.Load(id); err == nil {
+			return lb.Events, nil
+		}
+	}
+	if battle.Events == nil {
+		return model.BattleEvents{}, nil
+	}
+	return battle.Events, nil
 }
 
 func (s *battleService) GetPlayerBattles(playerID uint) ([]model.Battle, error) {
-	return s.repo.FindByPlayerID(playerID)
+	battles, err := s.repo.FindByPlayerID(playerID)
+	for i := range battles {
+		hideLiveSeeds(&battles[i])
+	}
+	return battles, err
 }
 
 func (s *battleService) GetRecentBattles() ([]model.Battle, error) {
-	return s.repo.FindRecent(50)
+	battles, err := s.repo.FindRecent(50)
+	for i := range battles {
+		hideLiveSeeds(&battles[i])
+	}
+	return battles, err
 }
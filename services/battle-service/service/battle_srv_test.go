@@ -0,0 +1,13 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewBattleRejectsSelfBattle(t *testing.T) {
+	s := &battleService{}
+	if _, err := s.newBattle(7, 7, []uint{1}, []uint{2}); !errors.Is(err, ErrSamePlayer) {
+		t.Errorf("err = %v, want %v", err, ErrSamePlayer)
+	}
+}
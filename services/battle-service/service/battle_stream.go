@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"maushold/battle-service/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	BattleStreamPrefix = "battle:stream:"
+	SpectatorsPrefix   = "battle:spectators:"
+	StreamPingInterval = 15 * time.Second
+)
+
+// BattleStream fans battle events out over Redis pub/sub so a watcher can be
+// connected to any battle-service replica, not just the one resolving turns
+type BattleStream struct {
+	redis *redis.Client
+	ctx   context.Context
+}
+
+func NewBattleStream(redisClient *redis.Client) *BattleStream {
+	return &BattleStream{
+		redis: redisClient,
+		ctx:   context.Background(),
+	}
+}
+
+func battleStreamChannel(battleID uint) string {
+	return fmt.Sprintf("%s%d", BattleStreamPrefix, battleID)
+}
+
+func spectatorsKey(battleID uint) string {
+	return fmt.Sprintf("%s%d", SpectatorsPrefix, battleID)
+}
+
+func (s *BattleStream) Publish(msg model.StreamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.redis.Publish(s.ctx, battleStreamChannel(msg.BattleID), data).Err()
+}
+
+// Subscribe listens to a battle's channel; it is confirmed before returning
+// so nothing published afterwards is missed
+func (s *BattleStream) Subscribe(ctx context.Context, battleID uint) (*redis.PubSub, error) {
+	sub := s.redis.Subscribe(ctx, battleStreamChannel(battleID))
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// AddSpectator counts a watcher in; the count expires with the live battle
+func (s *BattleStream) AddSpectator(battleID uint) {
+	pipe := s.redis.TxPipeline()
+	pipe.Incr(s.ctx, spectatorsKey(battleID))
+	pipe.Expire(s.ctx, spectatorsKey(battleID), LiveBattleTTL)
+	pipe.Exec(s.ctx)
+}
+
+func (s *BattleStream) RemoveSpectator(battleID uint) {
+	s.redis.Decr(s.ctx, spectatorsKey(battleID))
+}
+
+// Spectators returns how many watchers a battle has
+func (s *BattleStream) Spectators(battleID uint) int64 {
+	n, err := s.redis.Get(s.ctx, spectatorsKey(battleID)).Int64()
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"maushold/battle-service/model"
+	"maushold/outbox"
+)
+
+// turnTimerInterval is how often the turn timer looks for expired deadlines
+const turnTimerInterval = time.Second
+
+// CreateInteractiveBattle sets up a battle that is fought one round at a time
+// with actions submitted by both players. It starts out pending and the
+// state lives in Redis until the battle is decided.
+func (s *battleService) CreateInteractiveBattle(player1ID, player2ID uint, party1, party2 []uint) (*model.Battle, error) {
+	battle, err := s.newBattle(player1ID, player2ID, party1, party2)
+	if err != nil {
+		return nil, err
+	}
+	return s.startLiveBattle(battle)
+}
+
+// startLiveBattle saves a new interactive battle together with any events
+// and puts its state in Redis
+func (s *battleService) startLiveBattle(battle *model.Battle, pending ...outbox.Pending) (*model.Battle, error) {
+	battle.Status = model.BattleStatusPending
+	battle.Interactive = true
+
+	if err := s.repo.Create(battle, pending...); err != nil {
+		return nil, err
+	}
+
+	team1, team2, _ := battleTeams(battle)
+	state, events := s.battleEngine.StartBattle(team1, team2)
+
+	lb := &LiveBattle{
+		BattleID:  battle.ID,
+		PlayerIDs: [2]uint{battle.Player1ID, battle.Player2ID},
+		AI:        battle.AIDifficulty,
+		Seed:      battle.Seed,
+		State:     state,
+		Events:    events,
+		Deadline:  time.Now().Add(s.turnTimeout),
+	}
+	if err := s.liveStore.Save(lb); err != nil {
+		return nil, err
+	}
+	s.stream.Publish(model.StreamMessage{Type: model.StreamEvents, BattleID: battle.ID, Events: events, Status: battle.Status})
+
+	hideLiveSeeds(battle)
+	return battle, nil
+}
+
+// SubmitTurn records a player's action for the current round and resolves
+// the round once both players have acted
+func (s *battleService) SubmitTurn(battleID, playerID uint, action model.TurnAction) (*model.TurnStatus, error) {
+	lb, err := s.lockLiveBattle(battleID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlockLiveBattle(lb)
+
+	side := lb.side(playerID)
+	if side == 0 {
+		return nil, ErrNotParticipant
+	}
+	if lb.Pending[side-1] != nil {
+		return nil, ErrAlreadyActed
+	}
+	if err := ValidateAction(lb.State, side, action); err != nil {
+		return nil, err
+	}
+
+	lb.Pending[side-1] = &action
+	if lb.AI != "" {
+		ai, err := NewStrategy(lb.AI)
+		if err != nil {
+			return nil, err
+		}
+		aiAction := s.battleEngine.AIAction(lb.State, lb.Seed, ai)
+		lb.Pending[1] = &aiAction
+	}
+	if lb.Pending[0] == nil || lb.Pending[1] == nil {
+		if err := s.liveStore.Save(lb); err != nil {
+			return nil, err
+		}
+		return s.turnStatus(lb, model.BattleStatusInProgress, nil), nil
+	}
+
+	return s.resolveRound(lb, nil)
+}
+
+// GetLiveBattle returns the current state of an interactive battle
+func (s *battleService) GetLiveBattle(battleID uint) (*model.LiveBattleView, error) {
+	lb, err := s.liveStore.Load(battleID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := model.BattleStatusInProgress
+	if lb.State.Round == 0 {
+		status = model.BattleStatusPending
+	}
+
+	view := &model.LiveBattleView{
+		BattleID:   lb.BattleID,
+		Status:     status,
+		Round:      lb.State.Round + 1,
+		Waiting:    lb.waiting(),
+		Deadline:   &lb.Deadline,
+		Spectators: s.stream.Spectators(battleID),
+	}
+
+	battle, err := s.repo.FindByID(battleID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range view.Sides {
+		side := &view.Sides[i]
+		side.PlayerID = lb.PlayerIDs[i]
+		side.Active = lb.State.Active[i]
+		for slot, c := range lb.State.Teams[i] {
+			f := lb.State.Fighters[i][slot]
+			names := make([]string, len(c.Moves))
+			for m, move := range c.Moves {
+				names[m] = move.Name
+			}
+			side.Party = append(side.Party, model.LiveFighter{
+				Slot:            slot,
+				PlayerMonsterID: participantMonsterID(battle, i+1, slot),
+				Monster:         c.Monster.Nickname,
+				HP:              maxInt(f.HP, 0),
+				MaxHP:           c.Monster.HP,
+				Status:          f.Status,
+				Confused:        f.ConfusionTurns > 0,
+				Moves:           names,
+				MovePP:          f.MovePP,
+			})
+		}
+	}
+
+	return view, nil
+}
+
+func participantMonsterID(battle *model.Battle, side, slot int) uint {
+	for _, p := range battle.Participants {
+		if p.Side == side && p.Slot == slot {
+			return p.PlayerMonsterID
+		}
+	}
+	return 0
+}
+
+// ResolveExpiredTurns plays out every round whose deadline has passed. The
+// engine picks a move for any player who did not act; if neither player
+// acted the battle is abandoned.
+func (s *battleService) ResolveExpiredTurns() {
+	now := time.Now()
+	ids, err := s.liveStore.Expired(now)
+	if err != nil {
+		log.Printf("Failed to look up expired turns: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		lb, err := s.lockLiveBattle(id)
+		if err == ErrBattleNotLive {
+			s.liveStore.Delete(id) // the state expired, drop its deadline
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if lb.Deadline.After(now) {
+			// resolved by a player while we were waiting for the lock
+			s.unlockLiveBattle(lb)
+			continue
+		}
+
+		if lb.Pending[0] == nil && lb.Pending[1] == nil {
+			s.abandonBattle(lb)
+			s.unlockLiveBattle(lb)
+			continue
+		}
+
+		var timedOut []int
+		for i, action := range lb.Pending {
+			if action == nil {
+				defaultAction := s.battleEngine.DefaultAction(lb.State, i+1)
+				lb.Pending[i] = &defaultAction
+				timedOut = append(timedOut, i+1)
+			}
+		}
+
+		if _, err := s.resolveRound(lb, timedOut); err != nil {
+			log.Printf("Failed to resolve timed out turn of battle %d: %v", id, err)
+		}
+		s.unlockLiveBattle(lb)
+	}
+}
+
+// StartTurnTimer resolves expired turns in the background
+func (s *battleService) StartTurnTimer() {
+	ticker := time.NewTicker(turnTimerInterval)
+	go func() {
+		for range ticker.C {
+			s.ResolveExpiredTurns()
+		}
+	}()
+
+	log.Printf("Turn timer started (timeout %s)", s.turnTimeout)
+}
+
+// lockLiveBattle takes the battle's lock and loads it; the caller unlocks
+// it with unlockLiveBattle
+func (s *battleService) lockLiveBattle(battleID uint) (*LiveBattle, error) {
+	token, err := s.liveStore.Lock(battleID)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, ErrBattleBusy
+	}
+
+	lb, err := s.liveStore.Load(battleID)
+	if err != nil {
+		s.liveStore.Unlock(battleID, token)
+		return nil, err
+	}
+	lb.lockToken = token
+	return lb, nil
+}
+
+func (s *battleService) unlockLiveBattle(lb *LiveBattle) {
+	s.liveStore.Unlock(lb.BattleID, lb.lockToken)
+}
+
+// resolveRound plays the round with both pending actions and either saves
+// the state for the next round or completes the battle
+func (s *battleService) resolveRound(lb *LiveBattle, timedOut []int) (*model.TurnStatus, error) {
+	actions := [2]model.TurnAction{*lb.Pending[0], *lb.Pending[1]}
+	events, err := s.battleEngine.ResolveTurn(lb.State, lb.Seed, actions[0], actions[1])
+	if err != nil {
+		return nil, err
+	}
+
+	lb.Turns = append(lb.Turns, model.TurnRecord{Round: lb.State.Round, Actions: actions, TimedOut: timedOut})
+	offset := len(lb.Events)
+	lb.Events = append(lb.Events, events...)
+	lb.Pending = [2]*model.TurnAction{}
+
+	battle, err := s.repo.FindByID(lb.BattleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if lb.State.Over() {
+		battle.Turns = lb.Turns
+		// On failure the round is left pending, so it is resolved again
+		if err := s.completeBattle(battle, battleResult(lb.State, lb.Events)); err != nil {
+			return nil, err
+		}
+		s.liveStore.Delete(lb.BattleID)
+		s.stream.Publish(model.StreamMessage{
+			Type:     model.StreamEnd,
+			BattleID: battle.ID,
+			Offset:   offset,
+			Events:   events,
+			Status:   battle.Status,
+			WinnerID: battle.WinnerID,
+		})
+
+		status := s.turnStatus(lb, model.BattleStatusCompleted, events)
+		status.WinnerID = battle.WinnerID
+		return status, nil
+	}
+
+	lb.Deadline = time.Now().Add(s.turnTimeout)
+	if err := s.liveStore.Save(lb); err != nil {
+		return nil, err
+	}
+
+	if battle.Status == model.BattleStatusPending {
+		battle.Status = model.BattleStatusInProgress
+		s.repo.Update(battle)
+	}
+	s.stream.Publish(model.StreamMessage{Type: model.StreamEvents, BattleID: battle.ID, Offset: offset, Events: events, Status: battle.Status})
+
+	return s.turnStatus(lb, model.BattleStatusInProgress, events), nil
+}
+
+// abandonBattle ends a battle neither player is playing; no points change hands
+func (s *battleService) abandonBattle(lb *LiveBattle) {
+	battle, err := s.repo.FindByID(lb.BattleID)
+	if err == nil {
+		battle.Status = model.BattleStatusAbandoned
+		battle.Events = lb.Events
+		battle.Turns = lb.Turns
+		battle.BattleLog = RenderBattleLog(lb.Events)
+		now := time.Now()
+		battle.CompletedAt = &now
+		s.repo.Update(battle)
+	}
+	s.liveStore.Delete(lb.BattleID)
+	s.stream.Publish(model.StreamMessage{
+		Type:     model.StreamEnd,
+		BattleID: lb.BattleID,
+		Offset:   len(lb.Events),
+		Events:   model.BattleEvents{},
+		Status:   model.BattleStatusAbandoned,
+	})
+	log.Printf("Battle %d abandoned: neither player acted in round %d", lb.BattleID, lb.State.Round+1)
+}
+
+func (s *battleService) turnStatus(lb *LiveBattle, status string, events model.BattleEvents) *model.TurnStatus {
+	if events == nil {
+		events = model.BattleEvents{}
+	}
+	turnStatus := &model.TurnStatus{
+		BattleID: lb.BattleID,
+		Status:   status,
+		Round:    lb.State.Round + 1,
+		Waiting:  lb.waiting(),
+		Events:   events,
+	}
+	if status != model.BattleStatusCompleted {
+		turnStatus.Deadline = &lb.Deadline
+	} else {
+		turnStatus.Round = lb.State.Round
+		turnStatus.Waiting = []uint{}
+	}
+	return turnStatus
+}
+
+// WatchBattle sends a battle's events to a watcher as they happen: first
+// everything so far, then each round as it is resolved on any replica. A
+// finished battle is sent whole. It returns when the battle ends, ctx is
+// cancelled or send fails.
+func (s *battleService) WatchBattle(ctx context.Context, battleID uint, send func(model.StreamMessage) error) error {
+	battle, err := s.repo.FindByID(battleID)
+	if err != nil {
+		return err
+	}
+	if battle.Status != model.BattleStatusPending && battle.Status != model.BattleStatusInProgress {
+		return send(finishedStreamMessage(battle))
+	}
+
+	// Subscribe before reading the backlog so no round falls in between
+	sub, err := s.stream.Subscribe(ctx, battleID)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	lb, err := s.liveStore.Load(battleID)
+	if err == ErrBattleNotLive {
+		// finished since we looked it up
+		if battle, err = s.repo.FindByID(battleID); err != nil {
+			return err
+		}
+		return send(finishedStreamMessage(battle))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := send(model.StreamMessage{Type: model.StreamEvents, BattleID: battleID, Events: lb.Events, Status: battle.Status}); err != nil {
+		return err
+	}
+	sent := len(lb.Events)
+
+	s.stream.AddSpectator(battleID)
+	defer s.stream.RemoveSpectator(battleID)
+
+	ping := time.NewTicker(StreamPingInterval)
+	defer ping.Stop()
+	messages := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			if err := send(model.StreamMessage{Type: model.StreamPing, BattleID: battleID}); err != nil {
+				return err
+			}
+		case m, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var msg model.StreamMessage
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				continue
+			}
+
+			// Drop events the watcher already has from the backlog
+			if skip := sent - msg.Offset; skip > 0 {
+				if skip > len(msg.Events) {
+					skip = len(msg.Events)
+				}
+				msg.Events = msg.Events[skip:]
+				msg.Offset = sent
+			}
+			sent = msg.Offset + len(msg.Events)
+
+			if err := send(msg); err != nil {
+				return err
+			}
+			if msg.Type == model.StreamEnd {
+				return nil
+			}
+		}
+	}
+}
+
+func finishedStreamMessage(battle *model.Battle) model.StreamMessage {
+	events := battle.Events
+	if events == nil {
+		events = model.BattleEvents{}
+	}
+	return model.StreamMessage{
+		Type:     model.StreamEnd,
+		BattleID: battle.ID,
+		Events:   events,
+		Status:   battle.Status,
+		WinnerID: battle.WinnerID,
+	}
+}
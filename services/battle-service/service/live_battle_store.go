@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"maushold/battle-service/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	LiveBattlePrefix = "battle:live:"
+	LiveDeadlinesKey = "battle:live:deadlines"
+	LiveBattleTTL    = 24 * time.Hour
+	LiveLockTimeout  = 10 * time.Second
+)
+
+var ErrBattleNotLive = errors.New("battle is not in progress")
+
+// LiveBattle is an interactive battle between rounds. Pending holds the
+// action each side has submitted for the next round, nil until they act.
+type LiveBattle struct {
+	BattleID  uint                 `json:"battle_id"`
+	PlayerIDs [2]uint              `json:"player_ids"`
+	AI        string               `json:"ai,omitempty"` // difficulty of the AI playing side 2 in PvE
+	Seed      int64                `json:"seed"`
+	State     *BattleState         `json:"state"`
+	Events    model.BattleEvents   `json:"events"`
+	Turns     model.TurnLog        `json:"turns"`
+	Pending   [2]*model.TurnAction `json:"pending"`
+	Deadline  time.Time            `json:"deadline"`
+
+	lockToken string // of the lock held on the battle while it is changed
+}
+
+// side returns 1 or 2 for a player in the battle, 0 for anyone else
+func (lb *LiveBattle) side(playerID uint) int {
+	if playerID == 0 {
+		return 0 // the AI's side has no player
+	}
+	for i, id := range lb.PlayerIDs {
+		if id == playerID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// waiting lists the players who have not acted in the current round
+func (lb *LiveBattle) waiting() []uint {
+	waiting := []uint{}
+	for i, action := range lb.Pending {
+		if action == nil && lb.PlayerIDs[i] != 0 {
+			waiting = append(waiting, lb.PlayerIDs[i])
+		}
+	}
+	return waiting
+}
+
+// LiveBattleStore keeps interactive battles in Redis so any replica can
+// resolve their turns. A sorted set of turn deadlines lets the turn timer
+// find battles whose players ran out of time.
+type LiveBattleStore struct {
+	redis *redis.Client
+	ctx   context.Context
+}
+
+func NewLiveBattleStore(redisClient *redis.Client) *LiveBattleStore {
+	return &LiveBattleStore{
+		redis: redisClient,
+		ctx:   context.Background(),
+	}
+}
+
+func liveBattleKey(battleID uint) string {
+	return fmt.Sprintf("%s%d", LiveBattlePrefix, battleID)
+}
+
+func liveLockKey(battleID uint) string {
+	return fmt.Sprintf("%s%d:lock", LiveBattlePrefix, battleID)
+}
+
+// Save stores the battle and schedules its turn deadline
+func (s *LiveBattleStore) Save(lb *LiveBattle) error {
+	data, err := json.Marshal(lb)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(s.ctx, liveBattleKey(lb.BattleID), data, LiveBattleTTL)
+	pipe.ZAdd(s.ctx, LiveDeadlinesKey, &redis.Z{
+		Score:  float64(lb.Deadline.Unix()),
+		Member: strconv.FormatUint(uint64(lb.BattleID), 10),
+	})
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
+
+func (s *LiveBattleStore) Load(battleID uint) (*LiveBattle, error) {
+	data, err := s.redis.Get(s.ctx, liveBattleKey(battleID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrBattleNotLive
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lb LiveBattle
+	if err := json.Unmarshal(data, &lb); err != nil {
+		return nil, err
+	}
+	return &lb, nil
+}
+
+// Delete removes a battle that has finished
+func (s *LiveBattleStore) Delete(battleID uint) error {
+	pipe := s.redis.TxPipeline()
+	pipe.Del(s.ctx, liveBattleKey(battleID))
+	pipe.ZRem(s.ctx, LiveDeadlinesKey, strconv.FormatUint(uint64(battleID), 10))
+	_, err := pipe.Exec(s.ctx)
+	return err
+}
+
+// Expired returns the battles whose turn deadline has passed
+func (s *LiveBattleStore) Expired(now time.Time) ([]uint, error) {
+	members, err := s.redis.ZRangeByScore(s.ctx, LiveDeadlinesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// Lock takes a per-battle lock so two requests never resolve the same
+// round. It returns the token to unlock it with, or "" if the battle is
+// already locked.
+func (s *LiveBattleStore) Lock(battleID uint) (string, error) {
+	return acquireLock(s.ctx, s.redis, liveLockKey(battleID), LiveLockTimeout)
+}
+
+// Unlock releases the battle lock if it is still held with token
+func (s *LiveBattleStore) Unlock(battleID uint, token string) error {
+	return releaseLock(s.ctx, s.redis, liveLockKey(battleID), token)
+}
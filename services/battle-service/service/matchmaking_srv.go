@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"maushold/battle-service/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	MatchQueueKey     = "matchmaking:queue"
+	MatchTicketPrefix = "matchmaking:ticket:"
+	MatchResultPrefix = "matchmaking:result:"
+	MatchLockKey      = "matchmaking:lock"
+	MatchQueueTTL     = 10 * time.Minute // how long a player waits before dropping out
+	MatchResultTTL    = 10 * time.Minute
+	MatchLockTimeout  = 30 * time.Second
+	MatchInterval     = 2 * time.Second
+
+	// The combat power window starts at a 10-point gap and widens by 2 points
+	// a second waited, up to 500 points
+	MatchWindowBase   = 10 * combatPowerPerPoint
+	MatchWindowGrowth = 2 * combatPowerPerPoint // per second waited
+	MatchWindowMax    = 500 * combatPowerPerPoint
+
+	combatPowerPerPoint = 100 // ranking-service's combat power is points * 100
+)
+
+var (
+	ErrAlreadyQueued = errors.New("player is already in the matchmaking queue")
+	ErrNotQueued     = errors.New("player is not in matchmaking")
+)
+
+type MatchmakingService interface {
+	Enqueue(playerID uint, party []uint, interactive bool) (*model.MatchStatus, error)
+	Leave(playerID uint) error
+	GetStatus(playerID uint) (*model.MatchStatus, error)
+	MatchPlayers()
+	StartMatcher()
+}
+
+// matchmakingService keeps the queue in Redis so every battle-service
+// replica can take players; a lock makes sure only one of them pairs
+// players at a time.
+type matchmakingService struct {
+	redis         *redis.Client
+	ctx           context.Context
+	battleService BattleService
+	playerClient  *PlayerClient
+	rankingClient *RankingClient
+}
+
+func NewMatchmakingService(
+	redisClient *redis.Client,
+	battleService BattleService,
+	playerClient *PlayerClient,
+	rankingClient *RankingClient,
+) MatchmakingService {
+	return &matchmakingService{
+		redis:         redisClient,
+		ctx:           context.Background(),
+		battleService: battleService,
+		playerClient:  playerClient,
+		rankingClient: rankingClient,
+	}
+}
+
+func matchTicketKey(playerID uint) string {
+	return fmt.Sprintf("%s%d", MatchTicketPrefix, playerID)
+}
+
+func matchResultKey(playerID uint) string {
+	return fmt.Sprintf("%s%d", MatchResultPrefix, playerID)
+}
+
+func matchMember(playerID uint) string {
+	return strconv.FormatUint(uint64(playerID), 10)
+}
+
+// matchWindow is the largest combat power difference accepted after waiting
+func matchWindow(waited time.Duration) int64 {
+	window := int64(MatchWindowBase + MatchWindowGrowth*waited.Seconds())
+	if window > MatchWindowMax {
+		return MatchWindowMax
+	}
+	return window
+}
+
+// Enqueue puts a player in the queue with the party they will battle with
+func (s *matchmakingService) Enqueue(playerID uint, party []uint, interactive bool) (*model.MatchStatus, error) {
+	if err := validateParty(party); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParty, err)
+	}
+	for _, id := range party {
+		if _, err := s.playerClient.GetPlayerMonster(playerID, id); err != nil {
+			return nil, fmt.Errorf("%w: monster %d not found", ErrInvalidParty, id)
+		}
+	}
+
+	combatPower, err := s.rankingClient.GetCombatPower(playerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up combat power: %w", err)
+	}
+
+	ticket := model.MatchTicket{
+		PlayerID:    playerID,
+		Party:       party,
+		Interactive: interactive,
+		CombatPower: combatPower,
+		EnqueuedAt:  time.Now(),
+	}
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	queued, err := s.redis.SetNX(s.ctx, matchTicketKey(playerID), data, MatchQueueTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !queued {
+		return nil, ErrAlreadyQueued
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Del(s.ctx, matchResultKey(playerID))
+	pipe.ZAdd(s.ctx, MatchQueueKey, &redis.Z{Score: float64(combatPower), Member: matchMember(playerID)})
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Player %d joined matchmaking (combat power %d)", playerID, combatPower)
+	return s.GetStatus(playerID)
+}
+
+// Leave takes a player out of the queue
+func (s *matchmakingService) Leave(playerID uint) error {
+	pipe := s.redis.TxPipeline()
+	removed := pipe.Del(s.ctx, matchTicketKey(playerID))
+	pipe.ZRem(s.ctx, MatchQueueKey, matchMember(playerID))
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		return err
+	}
+	if removed.Val() == 0 {
+		return ErrNotQueued
+	}
+	return nil
+}
+
+// GetStatus reports a queued player's window, or the match they were given
+func (s *matchmakingService) GetStatus(playerID uint) (*model.MatchStatus, error) {
+	ticket, err := s.loadTicket(playerID)
+	if err != nil {
+		return nil, err
+	}
+	if ticket != nil {
+		size, _ := s.redis.ZCard(s.ctx, MatchQueueKey).Result()
+		return &model.MatchStatus{
+			PlayerID:    playerID,
+			Status:      model.MatchStatusQueued,
+			CombatPower: ticket.CombatPower,
+			EnqueuedAt:  ticket.EnqueuedAt,
+			Window:      matchWindow(time.Since(ticket.EnqueuedAt)),
+			QueueSize:   size,
+		}, nil
+	}
+
+	data, err := s.redis.Get(s.ctx, matchResultKey(playerID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotQueued
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var status model.MatchStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (s *matchmakingService) loadTicket(playerID uint) (*model.MatchTicket, error) {
+	data, err := s.redis.Get(s.ctx, matchTicketKey(playerID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket model.MatchTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// MatchPlayers pairs up the queue and creates a battle for every pair. The
+// battles are created once the pairs are out of the queue and the lock is
+// released, so a slow database or monster-service doesn't hold up matching
+// on other replicas.
+func (s *matchmakingService) MatchPlayers() {
+	for _, pair := range s.pairPlayers() {
+		s.startMatch(pair[0], pair[1])
+	}
+}
+
+// pairPlayers takes pairs of players out of the queue under MatchLockKey.
+// The longest waiting players are matched first, each with the closest
+// combat power inside their window; players only meet others who asked for
+// the same kind of battle.
+func (s *matchmakingService) pairPlayers() [][2]*model.MatchTicket {
+	token, err := acquireLock(s.ctx, s.redis, MatchLockKey, MatchLockTimeout)
+	if err != nil || token == "" {
+		return nil
+	}
+	defer releaseLock(s.ctx, s.redis, MatchLockKey, token)
+
+	tickets, err := s.queuedTickets()
+	if err != nil {
+		log.Printf("Failed to load matchmaking queue: %v", err)
+		return nil
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].EnqueuedAt.Before(tickets[j].EnqueuedAt)
+	})
+
+	now := time.Now()
+	var pairs [][2]*model.MatchTicket
+	matched := make(map[uint]bool, len(tickets))
+	for i, ticket := range tickets {
+		if matched[ticket.PlayerID] {
+			continue
+		}
+
+		window := matchWindow(now.Sub(ticket.EnqueuedAt))
+		var opponent *model.MatchTicket
+		var closest int64
+		for j := i + 1; j < len(tickets); j++ {
+			candidate := tickets[j]
+			if matched[candidate.PlayerID] || candidate.PlayerID == ticket.PlayerID || candidate.Interactive != ticket.Interactive {
+				continue
+			}
+			diff := ticket.CombatPower - candidate.CombatPower
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= window && (opponent == nil || diff < closest) {
+				opponent, closest = candidate, diff
+			}
+		}
+		if opponent == nil {
+			continue
+		}
+
+		matched[ticket.PlayerID] = true
+		matched[opponent.PlayerID] = true
+		if s.dequeue(ticket, opponent) {
+			pairs = append(pairs, [2]*model.MatchTicket{ticket, opponent})
+		}
+	}
+	return pairs
+}
+
+// queuedTickets loads every ticket in the queue, dropping players whose
+// ticket expired
+func (s *matchmakingService) queuedTickets() ([]*model.MatchTicket, error) {
+	members, err := s.redis.ZRange(s.ctx, MatchQueueKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := make([]*model.MatchTicket, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 32)
+		if err != nil {
+			s.redis.ZRem(s.ctx, MatchQueueKey, m)
+			continue
+		}
+		ticket, err := s.loadTicket(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if ticket == nil {
+			s.redis.ZRem(s.ctx, MatchQueueKey, m)
+			continue
+		}
+		tickets = append(tickets, ticket)
+	}
+	return tickets, nil
+}
+
+// dequeue takes both players out of the queue, reporting whether they were
+// both still in it
+func (s *matchmakingService) dequeue(t1, t2 *model.MatchTicket) bool {
+	pipe := s.redis.TxPipeline()
+	removed1 := pipe.Del(s.ctx, matchTicketKey(t1.PlayerID))
+	removed2 := pipe.Del(s.ctx, matchTicketKey(t2.PlayerID))
+	pipe.ZRem(s.ctx, MatchQueueKey, matchMember(t1.PlayerID), matchMember(t2.PlayerID))
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		log.Printf("Failed to take players %d and %d out of the queue: %v", t1.PlayerID, t2.PlayerID, err)
+		return false
+	}
+
+	// A player who left while we were matching gives their opponent back
+	// their place in the queue
+	if removed1.Val() == 0 || removed2.Val() == 0 {
+		if removed1.Val() != 0 {
+			s.requeue(t1)
+		}
+		if removed2.Val() != 0 {
+			s.requeue(t2)
+		}
+		return false
+	}
+	return true
+}
+
+// startMatch creates the battle of two players taken out of the queue and
+// tells them about it. battle.matched is written with the battle.
+func (s *matchmakingService) startMatch(t1, t2 *model.MatchTicket) {
+	battle, err := s.battleService.CreateMatchedBattle(t1.PlayerID, t2.PlayerID, t1.Party, t2.Party, t1.Interactive)
+	if err != nil {
+		log.Printf("Failed to create matched battle for players %d and %d: %v", t1.PlayerID, t2.PlayerID, err)
+		s.saveResult(t1, t2.PlayerID, 0, err)
+		s.saveResult(t2, t1.PlayerID, 0, err)
+		return
+	}
+
+	s.saveResult(t1, t2.PlayerID, battle.ID, nil)
+	s.saveResult(t2, t1.PlayerID, battle.ID, nil)
+
+	log.Printf("Matched players %d and %d into battle %d", t1.PlayerID, t2.PlayerID, battle.ID)
+}
+
+func (s *matchmakingService) requeue(ticket *model.MatchTicket) {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(s.ctx, matchTicketKey(ticket.PlayerID), data, MatchQueueTTL)
+	pipe.ZAdd(s.ctx, MatchQueueKey, &redis.Z{Score: float64(ticket.CombatPower), Member: matchMember(ticket.PlayerID)})
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		log.Printf("Failed to requeue player %d: %v", ticket.PlayerID, err)
+	}
+}
+
+// saveResult records the match so the player can pick it up from GetStatus
+func (s *matchmakingService) saveResult(ticket *model.MatchTicket, opponentID, battleID uint, matchErr error) {
+	status := model.MatchStatus{
+		PlayerID:    ticket.PlayerID,
+		Status:      model.MatchStatusMatched,
+		CombatPower: ticket.CombatPower,
+		EnqueuedAt:  ticket.EnqueuedAt,
+		BattleID:    battleID,
+		OpponentID:  opponentID,
+	}
+	if matchErr != nil {
+		status.Status = model.MatchStatusFailed
+		status.Error = matchErr.Error()
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	s.redis.Set(s.ctx, matchResultKey(ticket.PlayerID), data, MatchResultTTL)
+}
+
+// StartMatcher pairs queued players in the background
+func (s *matchmakingService) StartMatcher() {
+	ticker := time.NewTicker(MatchInterval)
+	go func() {
+		for range ticker.C {
+			s.MatchPlayers()
+		}
+	}()
+
+	log.Println("Matchmaker started")
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchWindowIsInCombatPower(t *testing.T) {
+	tests := []struct {
+		waited time.Duration
+		want   int64
+	}{
+		{0, 1000},                 // 10 points
+		{5 * time.Second, 2000},   // 20 points
+		{time.Minute, 13000},      // 130 points
+		{10 * time.Minute, 50000}, // capped at 500 points
+	}
+	for _, tt := range tests {
+		if got := matchWindow(tt.waited); got != tt.want {
+			t.Errorf("matchWindow(%s) = %d, want %d", tt.waited, got, tt.want)
+		}
+	}
+}
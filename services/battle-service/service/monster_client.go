@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"maushold/battle-service/model"
+)
+
+type MonsterClient struct {
+	baseURL string
+}
+
+func NewMonsterClient(baseURL string) *MonsterClient {
+	return &MonsterClient{baseURL: baseURL}
+}
+
+func (c *MonsterClient) GetMove(moveID uint) (*model.Move, error) {
+	url := fmt.Sprintf("%s/moves/%d", c.baseURL, moveID)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("move %d not found", moveID)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var move model.Move
+	if err := json.Unmarshal(body, &move); err != nil {
+		return nil, err
+	}
+
+	return &move, nil
+}
+
+// GetMoveSet resolves a monster's move slots into full move definitions
+func (c *MonsterClient) GetMoveSet(monster *model.PlayerMonster) ([]model.Move, error) {
+	moves := make([]model.Move, 0, len(monster.Moves))
+	for _, slot := range monster.Moves {
+		move, err := c.GetMove(slot.MoveID)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, *move)
+	}
+	return moves, nil
+}
+
+// GetRandomMonster picks a random species, used for wild PvE opponents
+func (c *MonsterClient) GetRandomMonster() (*model.Species, error) {
+	var species model.Species
+	if err := c.getJSON(fmt.Sprintf("%s/monster/random", c.baseURL), &species); err != nil {
+		return nil, err
+	}
+	return &species, nil
+}
+
+// GetAllMonsters returns the whole species catalog
+func (c *MonsterClient) GetAllMonsters() ([]model.Species, error) {
+	var species []model.Species
+	if err := c.getJSON(fmt.Sprintf("%s/monster", c.baseURL), &species); err != nil {
+		return nil, err
+	}
+	return species, nil
+}
+
+// GetLearnset returns the moves a species can learn, by level
+func (c *MonsterClient) GetLearnset(monsterID int) ([]model.LearnsetEntry, error) {
+	var learnset []model.LearnsetEntry
+	if err := c.getJSON(fmt.Sprintf("%s/monster/%d/moves", c.baseURL, monsterID), &learnset); err != nil {
+		return nil, err
+	}
+	return learnset, nil
+}
+
+func (c *MonsterClient) getJSON(url string, v interface{}) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("monster-service returned %d for %s", resp.StatusCode, url)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	return json.Unmarshal(body, v)
+}
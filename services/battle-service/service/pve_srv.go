@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"maushold/battle-service/model"
+)
+
+// OpponentWild battles a random species from monster-service's catalog
+const OpponentWild = "wild"
+
+// maxAIMoves matches the number of move slots a player's monster has
+const maxAIMoves = 4
+
+var ErrUnknownTrainer = errors.New("unknown trainer")
+
+// PvEOptions describes a battle against the AI. Opponent is OpponentWild or
+// a trainer ID from the roster; Difficulty defaults to the trainer's own, or
+// greedy for wild monsters.
+type PvEOptions struct {
+	PlayerID    uint
+	Party       []uint
+	Opponent    string
+	Difficulty  string
+	Interactive bool
+}
+
+// GetTrainers returns the configured trainer roster
+func (s *battleService) GetTrainers() []model.Trainer {
+	return s.trainers
+}
+
+// CreatePvEBattle starts a battle where player 2 is played by the AI. No
+// ranking points change hands, so it does not publish battle.completed.
+func (s *battleService) CreatePvEBattle(opts PvEOptions) (*model.Battle, error) {
+	if err := validateParty(opts.Party); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParty, err)
+	}
+
+	difficulty := opts.Difficulty
+	var trainer *model.Trainer
+	if opts.Opponent != OpponentWild {
+		for i := range s.trainers {
+			if s.trainers[i].ID == opts.Opponent {
+				trainer = &s.trainers[i]
+			}
+		}
+		if trainer == nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTrainer, opts.Opponent)
+		}
+		if difficulty == "" {
+			difficulty = trainer.Difficulty
+		}
+	}
+	if difficulty == "" {
+		difficulty = AIGreedy
+	}
+	ai, err := NewStrategy(difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := s.loadParty(1, opts.PlayerID, opts.Party)
+	if err != nil {
+		return nil, err
+	}
+
+	var team []*model.Combatant
+	if trainer != nil {
+		team, err = s.trainerTeam(trainer)
+	} else {
+		team, err = s.wildTeam(participants[0].Snapshot.Monster.Level)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for slot, c := range team {
+		participants = append(participants, model.BattleParticipant{Side: 2, Slot: slot, Snapshot: c})
+	}
+
+	mode := model.BattleModeSingle
+	if len(opts.Party) > 1 || len(team) > 1 {
+		mode = model.BattleModeTeam
+	}
+
+	battle := &model.Battle{
+		Player1ID:     opts.PlayerID,
+		Monster1ID:    opts.Party[0],
+		Mode:          mode,
+		AIDifficulty:  difficulty,
+		Opponent:      opts.Opponent,
+		Seed:          newSeed(),
+		EngineVersion: EngineVersion,
+		Participants:  participants,
+	}
+
+	if opts.Interactive {
+		return s.startLiveBattle(battle)
+	}
+
+	battle.Status = model.BattleStatusInProgress
+	if err := s.repo.Create(battle); err != nil {
+		return nil, err
+	}
+
+	team1, team2, _ := battleTeams(battle)
+	if err := s.completeBattle(battle, s.battleEngine.SimulateAIBattle(team1, team2, battle.Seed, ai)); err != nil {
+		return nil, err
+	}
+
+	return battle, nil
+}
+
+// wildTeam is a single random species at the level of the player's lead
+func (s *battleService) wildTeam(level int) ([]*model.Combatant, error) {
+	species, err := s.monsterClient.GetRandomMonster()
+	if err != nil {
+		return nil, errors.New("failed to find a wild monster")
+	}
+
+	c, err := s.aiCombatant(species, maxInt(level, 1), "Wild "+species.Name)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.Combatant{c}, nil
+}
+
+// trainerTeam builds a trainer's party from the species catalog
+func (s *battleService) trainerTeam(trainer *model.Trainer) ([]*model.Combatant, error) {
+	catalog, err := s.monsterClient.GetAllMonsters()
+	if err != nil {
+		return nil, errors.New("failed to load the monster catalog")
+	}
+
+	bySpecies := make(map[string]*model.Species, len(catalog))
+	for i := range catalog {
+		bySpecies[strings.ToLower(catalog[i].Name)] = &catalog[i]
+	}
+
+	team := make([]*model.Combatant, 0, len(trainer.Party))
+	for _, member := range trainer.Party {
+		species, ok := bySpecies[strings.ToLower(member.Species)]
+		if !ok {
+			return nil, fmt.Errorf("trainer %s has unknown species %q", trainer.ID, member.Species)
+		}
+
+		nickname := member.Nickname
+		if nickname == "" {
+			nickname = fmt.Sprintf("%s's %s", trainer.Name, species.Name)
+		}
+
+		c, err := s.aiCombatant(species, maxInt(member.Level, 1), nickname)
+		if err != nil {
+			return nil, err
+		}
+		team = append(team, c)
+	}
+	return team, nil
+}
+
+// aiCombatant gives a species its base stats and the last moves it has
+// learned by level, the same default moves a player's new monster gets
+func (s *battleService) aiCombatant(species *model.Species, level int, nickname string) (*model.Combatant, error) {
+	learnset, err := s.monsterClient.GetLearnset(species.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load moves for %s", species.Name)
+	}
+
+	var moves []model.Move
+	for _, entry := range learnset {
+		if entry.Level <= level {
+			moves = append(moves, entry.Move)
+		}
+	}
+	if len(moves) > maxAIMoves {
+		moves = moves[len(moves)-maxAIMoves:]
+	}
+
+	return &model.Combatant{
+		Monster: model.PlayerMonster{
+			MonsterID: species.ID,
+			Nickname:  nickname,
+			Type1:     species.Type1,
+			Type2:     species.Type2,
+			HP:        species.BaseHP,
+			Attack:    species.BaseAttack,
+			Defense:   species.BaseDefense,
+			Speed:     species.BaseSpeed,
+			Level:     level,
+		},
+		Moves: moves,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"maushold/battle-service/model"
+)
+
+type RankingClient struct {
+	baseURL string
+}
+
+func NewRankingClient(baseURL string) *RankingClient {
+	return &RankingClient{baseURL: baseURL}
+}
+
+// GetCombatPower returns a player's combat power from ranking-service.
+// Players who have not been ranked yet have none.
+func (c *RankingClient) GetCombatPower(playerID uint) (int64, error) {
+	url := fmt.Sprintf("%s/rankings/player/%d", c.baseURL, playerID)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("ranking-service returned %d", resp.StatusCode)
+	}
+
+	var ranking struct {
+		CombatPower int64 `json:"combat_power"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ranking); err != nil {
+		return 0, err
+	}
+	return ranking.CombatPower, nil
+}
+
+// GetRating returns a player's Glicko-2 rating and deviation from
+// ranking-service. Players who have not been ranked yet get the defaults.
+func (c *RankingClient) GetRating(playerID uint) (model.PlayerRating, error) {
+	url := fmt.Sprintf("%s/rankings/player/%d", c.baseURL, playerID)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return model.PlayerRating{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return model.PlayerRating{Rating: DefaultRating, Deviation: DefaultRatingDeviation}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return model.PlayerRating{}, fmt.Errorf("ranking-service returned %d", resp.StatusCode)
+	}
+
+	var ranking struct {
+		Rating    float64 `json:"rating"`
+		Deviation float64 `json:"rating_deviation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ranking); err != nil {
+		return model.PlayerRating{}, err
+	}
+	return model.PlayerRating{Rating: ranking.Rating, Deviation: ranking.Deviation}, nil
+}
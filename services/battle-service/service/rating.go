@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"maushold/battle-service/model"
+)
+
+const (
+	RatingElo    = "elo"
+	RatingGlicko = "glicko"
+
+	// DefaultRatingModel scores battles saved without a rating model
+	DefaultRatingModel = RatingElo
+
+	// DefaultRating and DefaultRatingDeviation are those of a player with
+	// no history
+	DefaultRating          = 1500.0
+	DefaultRatingDeviation = 350.0
+
+	eloK           = 64 // most points an Elo battle can move
+	glickoMaxDelta = 128
+	minPoints      = 1 // a win always pays and a loss always costs something
+)
+
+var ErrInvalidRatingModel = errors.New("invalid rating model")
+
+// RatingModel turns the ratings of the two players into the points that
+// change hands when one beats the other
+type RatingModel interface {
+	Name() string
+	Exchange(winner, loser model.PlayerRating) (won, lost int)
+}
+
+// NewRatingModel returns the rating model with the given name
+func NewRatingModel(name string) (RatingModel, error) {
+	switch name {
+	case RatingElo:
+		return eloModel{k: eloK}, nil
+	case RatingGlicko:
+		return glickoModel{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q, must be %q or %q", ErrInvalidRatingModel, name, RatingElo, RatingGlicko)
+}
+
+// eloModel moves K times how unexpected the result was. Both players move
+// by the same amount, so upsets pay the most and beating a much weaker
+// player pays almost nothing.
+type eloModel struct {
+	k float64
+}
+
+func (m eloModel) Name() string { return RatingElo }
+
+func (m eloModel) Exchange(winner, loser model.PlayerRating) (int, int) {
+	expected := 1 / (1 + math.Pow(10, (loser.Rating-winner.Rating)/400))
+	points := roundPoints(m.k * (1 - expected))
+	return points, points
+}
+
+// glickoModel applies a single-game Glicko update to each player. A player
+// whose rating is uncertain moves further, and beating an opponent whose
+// rating is uncertain counts for less.
+type glickoModel struct{}
+
+func (glickoModel) Name() string { return RatingGlicko }
+
+func (glickoModel) Exchange(winner, loser model.PlayerRating) (int, int) {
+	won := glickoDelta(winner, loser, 1)
+	lost := -glickoDelta(loser, winner, 0)
+	return roundPoints(math.Min(won, glickoMaxDelta)), roundPoints(math.Min(lost, glickoMaxDelta))
+}
+
+// glickoDelta is how far a player's rating moves after scoring score
+// against opponent
+func glickoDelta(player, opponent model.PlayerRating, score float64) float64 {
+	const q = math.Ln10 / 400
+
+	g := 1 / math.Sqrt(1+3*q*q*sq(deviation(opponent))/(math.Pi*math.Pi))
+	expected := 1 / (1 + math.Pow(10, -g*(player.Rating-opponent.Rating)/400))
+	dSquared := 1 / (q * q * g * g * expected * (1 - expected))
+
+	return q / (1/sq(deviation(player)) + 1/dSquared) * g * (score - expected)
+}
+
+func deviation(r model.PlayerRating) float64 {
+	if r.Deviation <= 0 {
+		return DefaultRatingDeviation
+	}
+	return r.Deviation
+}
+
+func sq(x float64) float64 { return x * x }
+
+func roundPoints(points float64) int {
+	return maxInt(int(math.Round(points)), minPoints)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// releaseScript deletes a lock only if it still holds the token it was taken
+// with, in one step, so a lock that expired and was taken by someone else
+// is left alone
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// acquireLock takes a Redis lock for ttl. It returns the token to release
+// it with, or "" if someone else holds it. Every acquire gets its own
+// token, so two holders on the same replica can't release each other's
+// lock.
+func acquireLock(ctx context.Context, rdb *redis.Client, key string, ttl time.Duration) (string, error) {
+	var b [16]byte
+	rand.Read(b[:]) // never fails, it crashes the program instead
+	token := hex.EncodeToString(b[:])
+
+	locked, err := rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !locked {
+		return "", err
+	}
+	return token, nil
+}
+
+// releaseLock releases a lock taken with acquireLock if it is still held
+// with token
+func releaseLock(ctx context.Context, rdb *redis.Client, key, token string) error {
+	return releaseScript.Run(ctx, rdb, []string{key}, token).Err()
+}
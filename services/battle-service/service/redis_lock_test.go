@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	first, err := acquireLock(ctx, rdb, "lock", LiveLockTimeout)
+	if err != nil || first == "" {
+		t.Fatalf("first acquire = %q, %v; want a token", first, err)
+	}
+	if second, err := acquireLock(ctx, rdb, "lock", LiveLockTimeout); err != nil || second != "" {
+		t.Fatalf("second acquire = %q, %v; want the lock to be busy", second, err)
+	}
+
+	// The lock expires and someone else takes it before the first holder
+	// gets round to releasing it
+	mr.FastForward(LiveLockTimeout)
+	other, err := acquireLock(ctx, rdb, "lock", LiveLockTimeout)
+	if err != nil || other == "" || other == first {
+		t.Fatalf("acquire after expiry = %q, %v; want a new token", other, err)
+	}
+	if err := releaseLock(ctx, rdb, "lock", first); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get("lock"); got != other {
+		t.Fatalf("a stale release freed the lock of the new holder")
+	}
+
+	if err := releaseLock(ctx, rdb, "lock", other); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("lock") {
+		t.Errorf("lock still held after its holder released it")
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"maushold/battle-service/model"
+)
+
+const (
+	DefaultSimulations     = 1000
+	MaxSimulations         = 10000
+	DefaultSimulationLevel = 50
+	damageBuckets          = 10
+)
+
+var ErrInvalidSimulation = errors.New("invalid simulation")
+
+// MatchupSide is one side of a simulated matchup: either a player's party,
+// or species at a level so monsters nobody owns yet can be compared
+type MatchupSide struct {
+	PlayerID uint
+	Party    []uint
+	Species  []int
+	Level    int
+}
+
+// SimulateOptions describes a matchup to simulate. A nil Seed picks one.
+type SimulateOptions struct {
+	Sides      [2]MatchupSide
+	Iterations int
+	Seed       *int64
+}
+
+// SimulateMatchup plays the same matchup many times with consecutive seeds
+// and reports the odds. Nothing is saved or published.
+func (s *battleService) SimulateMatchup(opts SimulateOptions) (*model.SimulationReport, error) {
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = DefaultSimulations
+	}
+	if iterations < 1 || iterations > MaxSimulations {
+		return nil, fmt.Errorf("%w: iterations must be between 1 and %d", ErrInvalidSimulation, MaxSimulations)
+	}
+
+	seed := newSeed()
+	if opts.Seed != nil {
+		seed = *opts.Seed
+	}
+
+	var teams [2][]*model.Combatant
+	for i, side := range opts.Sides {
+		team, err := s.matchupTeam(i+1, side)
+		if err != nil {
+			return nil, err
+		}
+		teams[i] = team
+	}
+
+	report := &model.SimulationReport{
+		Iterations:    iterations,
+		Seed:          seed,
+		EngineVersion: EngineVersion,
+	}
+	for i, team := range teams {
+		for _, c := range team {
+			report.Sides[i].Team = append(report.Sides[i].Team, c.Monster.Nickname)
+		}
+	}
+
+	var damage [2][]int
+	var totalRounds int
+	var monsters []model.SimulatedMonster
+	for i := 0; i < iterations; i++ {
+		result := s.battleEngine.SimulateTeamBattle(teams[0], teams[1], seed+int64(i))
+
+		report.Sides[result.Winner-1].Wins++
+		totalRounds += result.Rounds
+		if i == 0 || result.Rounds < report.RoundsMin {
+			report.RoundsMin = result.Rounds
+		}
+		if result.Rounds > report.RoundsMax {
+			report.RoundsMax = result.Rounds
+		}
+
+		if monsters == nil {
+			monsters = make([]model.SimulatedMonster, len(result.Outcomes))
+		}
+		var dealt [2]int
+		for j, outcome := range result.Outcomes {
+			dealt[outcome.Side-1] += outcome.DamageDealt
+
+			m := &monsters[j]
+			m.AverageDamage += float64(outcome.DamageDealt)
+			m.AverageTaken += float64(outcome.DamageTaken)
+			m.AverageKOs += float64(outcome.Knockouts)
+			if outcome.Fainted {
+				m.FaintedFraction++
+			}
+		}
+		damage[0] = append(damage[0], dealt[0])
+		damage[1] = append(damage[1], dealt[1])
+	}
+
+	n := float64(iterations)
+	report.AverageRounds = float64(totalRounds) / n
+	for i := range report.Sides {
+		report.Sides[i].WinProbability = float64(report.Sides[i].Wins) / n
+		report.Sides[i].DamageDealt = damageDistribution(damage[i])
+	}
+
+	// Outcomes are in side then party order, the same order as the teams
+	j := 0
+	for side, team := range teams {
+		for slot, c := range team {
+			m := &monsters[j]
+			m.Side = side + 1
+			m.Slot = slot
+			m.Monster = c.Monster.Nickname
+			m.AverageDamage /= n
+			m.AverageTaken /= n
+			m.AverageKOs /= n
+			m.FaintedFraction /= n
+			j++
+		}
+	}
+	report.Monsters = monsters
+
+	return report, nil
+}
+
+// matchupTeam loads one side of a matchup
+func (s *battleService) matchupTeam(side int, ms MatchupSide) ([]*model.Combatant, error) {
+	if len(ms.Species) > 0 {
+		if len(ms.Party) > 0 {
+			return nil, fmt.Errorf("%w: side %d lists both a party and species", ErrInvalidSimulation, side)
+		}
+		return s.speciesTeam(side, ms.Species, ms.Level)
+	}
+
+	if err := validateParty(ms.Party); err != nil {
+		return nil, fmt.Errorf("%w: side %d: %v", ErrInvalidSimulation, side, err)
+	}
+	participants, err := s.loadParty(side, ms.PlayerID, ms.Party)
+	if err != nil {
+		return nil, err
+	}
+
+	team := make([]*model.Combatant, len(participants))
+	for i, p := range participants {
+		team[i] = p.Snapshot
+	}
+	return team, nil
+}
+
+// speciesTeam builds a party straight from the species catalog, the same way
+// the AI's wild monsters are built
+func (s *battleService) speciesTeam(side int, ids []int, level int) ([]*model.Combatant, error) {
+	if len(ids) > MaxPartySize {
+		return nil, fmt.Errorf("%w: side %d has %d monsters, the limit is %d", ErrInvalidSimulation, side, len(ids), MaxPartySize)
+	}
+	if level == 0 {
+		level = DefaultSimulationLevel
+	}
+	if level < 1 || level > 100 {
+		return nil, fmt.Errorf("%w: level must be between 1 and 100", ErrInvalidSimulation)
+	}
+
+	catalog, err := s.monsterClient.GetAllMonsters()
+	if err != nil {
+		return nil, errors.New("failed to load the monster catalog")
+	}
+	byID := make(map[int]*model.Species, len(catalog))
+	for i := range catalog {
+		byID[catalog[i].ID] = &catalog[i]
+	}
+
+	team := make([]*model.Combatant, 0, len(ids))
+	for _, id := range ids {
+		species, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: species %d not found", ErrInvalidSimulation, id)
+		}
+		c, err := s.aiCombatant(species, level, species.Name)
+		if err != nil {
+			return nil, err
+		}
+		team = append(team, c)
+	}
+	return team, nil
+}
+
+// damageDistribution summarizes the damage a side dealt in each battle
+func damageDistribution(values []int) model.DamageDistribution {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	min, max := sorted[0], sorted[len(sorted)-1]
+	percentile := func(p float64) int {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+
+	var total int
+	histogram := make([]int, damageBuckets)
+	width := (max-min)/damageBuckets + 1
+	for _, v := range sorted {
+		total += v
+		histogram[(v-min)/width]++
+	}
+
+	return model.DamageDistribution{
+		Min:         min,
+		Max:         max,
+		Mean:        float64(total) / float64(len(sorted)),
+		P10:         percentile(0.1),
+		Median:      percentile(0.5),
+		P90:         percentile(0.9),
+		BucketWidth: width,
+		Histogram:   histogram,
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import "maushold/battle-service/model"
+
+const (
+	paralysisSkipChance = 4  // 1 in 4 turns a paralyzed monster cannot move
+	paralysisSpeedDiv   = 2  // paralysis halves speed
+	burnAttackDiv       = 2  // burn halves the damage of physical moves
+	burnDamageDiv       = 16 // burn deals 1/16 of max HP at the end of each round
+	poisonDamageDiv     = 8  // poison deals 1/8 of max HP at the end of each round
+	freezeThawChance    = 5  // 1 in 5 turns a frozen monster thaws out
+	confusionHitChance  = 3  // 1 in 3 turns a confused monster hurts itself
+	minSleepTurns       = 1
+	maxSleepTurns       = 3
+	minConfusionTurns   = 2
+	maxConfusionTurns   = 5
+)
+
+// confusionHit is the typeless attack a confused monster uses on itself
+var confusionHit = model.Move{Name: "Confusion", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100}
+
+// statusImmunities lists the types that cannot receive a persistent status
+var statusImmunities = map[string][]string{
+	model.StatusBurn:      {"Fire"},
+	model.StatusPoison:    {"Poison", "Steel"},
+	model.StatusParalysis: {"Electric"},
+	model.StatusFreeze:    {"Ice"},
+}
+
+// canReceive reports whether status can be inflicted on f right now
+func canReceive(f *fighter, status string) bool {
+	if status == model.StatusConfusion {
+		return f.ConfusionTurns == 0
+	}
+	return f.Status == "" && canReceiveType(f, status)
+}
+
+// canReceiveType checks only the type immunities of a persistent status
+func canReceiveType(f *fighter, status string) bool {
+	for _, t := range statusImmunities[status] {
+		if t == normalizeType(f.Monster.Type1) || t == normalizeType(f.Monster.Type2) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyStatus inflicts status on target if it can receive it
+func (sim *simulation) applyStatus(target *fighter, status string) bool {
+	if !canReceive(target, status) {
+		return false
+	}
+
+	switch status {
+	case model.StatusConfusion:
+		target.ConfusionTurns = minConfusionTurns + sim.rng.Intn(maxConfusionTurns-minConfusionTurns+1)
+	case model.StatusSleep:
+		target.Status = status
+		target.StatusTurns = minSleepTurns + sim.rng.Intn(maxSleepTurns-minSleepTurns+1)
+	default:
+		target.Status = status
+	}
+
+	sim.emit(model.BattleEvent{
+		Type:        model.EventStatusApplied,
+		Actor:       target.side,
+		ActorName:   target.Monster.Nickname,
+		RemainingHP: maxInt(target.HP, 0),
+		Status:      status,
+	})
+	return true
+}
+
+func (sim *simulation) cure(f *fighter, status string) {
+	if status == model.StatusConfusion {
+		f.ConfusionTurns = 0
+	} else {
+		f.Status = ""
+		f.StatusTurns = 0
+	}
+	sim.emit(model.BattleEvent{
+		Type:        model.EventStatusCured,
+		Actor:       f.side,
+		ActorName:   f.Monster.Nickname,
+		RemainingHP: maxInt(f.HP, 0),
+		Status:      status,
+	})
+}
+
+func (sim *simulation) cantMove(f *fighter, status string) bool {
+	sim.emit(model.BattleEvent{
+		Type:        model.EventCantMove,
+		Actor:       f.side,
+		ActorName:   f.Monster.Nickname,
+		RemainingHP: maxInt(f.HP, 0),
+		Status:      status,
+	})
+	return false
+}
+
+// canAct resolves the statuses that act before a monster moves: sleep and
+// freeze first, then confusion, then paralysis. It returns false if the
+// monster loses its turn.
+func (sim *simulation) canAct(f *fighter) bool {
+	switch f.Status {
+	case model.StatusSleep:
+		f.StatusTurns--
+		if f.StatusTurns > 0 {
+			return sim.cantMove(f, model.StatusSleep)
+		}
+		sim.cure(f, model.StatusSleep)
+	case model.StatusFreeze:
+		if sim.rng.Intn(freezeThawChance) != 0 {
+			return sim.cantMove(f, model.StatusFreeze)
+		}
+		sim.cure(f, model.StatusFreeze)
+	}
+
+	if f.ConfusionTurns > 0 {
+		f.ConfusionTurns--
+		if f.ConfusionTurns == 0 {
+			sim.cure(f, model.StatusConfusion)
+		} else if sim.rng.Intn(confusionHitChance) == 0 {
+			damage := maxInt(baseDamage(f, f, confusionHit), 1)
+			hurt(f, damage)
+			sim.emit(model.BattleEvent{
+				Type:        model.EventStatusDamage,
+				Actor:       f.side,
+				ActorName:   f.Monster.Nickname,
+				Damage:      damage,
+				RemainingHP: maxInt(f.HP, 0),
+				Status:      model.StatusConfusion,
+			})
+			sim.faintCheck(f)
+			return false
+		}
+	}
+
+	if f.Status == model.StatusParalysis && sim.rng.Intn(paralysisSkipChance) == 0 {
+		return sim.cantMove(f, model.StatusParalysis)
+	}
+
+	return true
+}
+
+// passTurn counts down the sleep and confusion of a monster that had no
+// target left to act on this round. Freeze only thaws on a turn it tries to move.
+func (sim *simulation) passTurn(f *fighter) {
+	if f.Status == model.StatusSleep {
+		f.StatusTurns--
+		if f.StatusTurns <= 0 {
+			sim.cure(f, model.StatusSleep)
+		}
+	}
+	if f.ConfusionTurns > 0 {
+		f.ConfusionTurns--
+		if f.ConfusionTurns == 0 {
+			sim.cure(f, model.StatusConfusion)
+		}
+	}
+}
+
+// endOfRound applies burn and poison damage to the monsters still standing,
+// faster monster first
+func (sim *simulation) endOfRound(f1, f2 *fighter) {
+	order := []*fighter{f1, f2}
+	if effectiveSpeed(f2) > effectiveSpeed(f1) {
+		order = []*fighter{f2, f1}
+	}
+
+	for _, f := range order {
+		if f.HP <= 0 {
+			continue
+		}
+
+		var damage int
+		switch f.Status {
+		case model.StatusBurn:
+			damage = maxInt(f.Monster.HP/burnDamageDiv, 1)
+		case model.StatusPoison:
+			damage = maxInt(f.Monster.HP/poisonDamageDiv, 1)
+		default:
+			continue
+		}
+
+		hurt(f, damage)
+		sim.emit(model.BattleEvent{
+			Type:        model.EventStatusDamage,
+			Actor:       f.side,
+			ActorName:   f.Monster.Nickname,
+			Damage:      damage,
+			RemainingHP: maxInt(f.HP, 0),
+			Status:      f.Status,
+		})
+		sim.faintCheck(f)
+	}
+}
+
+// effectiveSpeed is the monster's speed after paralysis
+func effectiveSpeed(f *fighter) int {
+	if f.Status == model.StatusParalysis {
+		return f.Monster.Speed / paralysisSpeedDiv
+	}
+	return f.Monster.Speed
+}
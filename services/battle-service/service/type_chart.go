@@ -0,0 +1,116 @@
+package service
+
+import "strings"
+
+const (
+	EffectivenessImmune           = 0.0
+	EffectivenessNotVeryEffective = 0.5
+	EffectivenessNeutral          = 1.0
+	EffectivenessSuperEffective   = 2.0
+)
+
+// typeChart maps attacking type -> defending type -> multiplier.
+// Only non-neutral matchups are listed; anything missing is 1x.
+var typeChart = map[string]map[string]float64{
+	"Normal": {
+		"Rock": 0.5, "Ghost": 0, "Steel": 0.5,
+	},
+	"Fire": {
+		"Fire": 0.5, "Water": 0.5, "Grass": 2, "Ice": 2, "Bug": 2,
+		"Rock": 0.5, "Dragon": 0.5, "Steel": 2,
+	},
+	"Water": {
+		"Fire": 2, "Water": 0.5, "Grass": 0.5, "Ground": 2, "Rock": 2, "Dragon": 0.5,
+	},
+	"Electric": {
+		"Water": 2, "Electric": 0.5, "Grass": 0.5, "Ground": 0, "Flying": 2, "Dragon": 0.5,
+	},
+	"Grass": {
+		"Fire": 0.5, "Water": 2, "Grass": 0.5, "Poison": 0.5, "Ground": 2,
+		"Flying": 0.5, "Bug": 0.5, "Rock": 2, "Dragon": 0.5, "Steel": 0.5,
+	},
+	"Ice": {
+		"Fire": 0.5, "Water": 0.5, "Grass": 2, "Ice": 0.5, "Ground": 2,
+		"Flying": 2, "Dragon": 2, "Steel": 0.5,
+	},
+	"Fighting": {
+		"Normal": 2, "Ice": 2, "Poison": 0.5, "Flying": 0.5, "Psychic": 0.5,
+		"Bug": 0.5, "Rock": 2, "Ghost": 0, "Dark": 2, "Steel": 2, "Fairy": 0.5,
+	},
+	"Poison": {
+		"Grass": 2, "Poison": 0.5, "Ground": 0.5, "Rock": 0.5, "Ghost": 0.5,
+		"Steel": 0, "Fairy": 2,
+	},
+	"Ground": {
+		"Fire": 2, "Electric": 2, "Grass": 0.5, "Poison": 2, "Flying": 0,
+		"Bug": 0.5, "Rock": 2, "Steel": 2,
+	},
+	"Flying": {
+		"Electric": 0.5, "Grass": 2, "Fighting": 2, "Bug": 2, "Rock": 0.5, "Steel": 0.5,
+	},
+	"Psychic": {
+		"Fighting": 2, "Poison": 2, "Psychic": 0.5, "Dark": 0, "Steel": 0.5,
+	},
+	"Bug": {
+		"Fire": 0.5, "Grass": 2, "Fighting": 0.5, "Poison": 0.5, "Flying": 0.5,
+		"Psychic": 2, "Ghost": 0.5, "Dark": 2, "Steel": 0.5, "Fairy": 0.5,
+	},
+	"Rock": {
+		"Fire": 2, "Ice": 2, "Fighting": 0.5, "Ground": 0.5, "Flying": 2, "Bug": 2, "Steel": 0.5,
+	},
+	"Ghost": {
+		"Normal": 0, "Psychic": 2, "Ghost": 2, "Dark": 0.5,
+	},
+	"Dragon": {
+		"Dragon": 2, "Steel": 0.5, "Fairy": 0,
+	},
+	"Dark": {
+		"Fighting": 0.5, "Psychic": 2, "Ghost": 2, "Dark": 0.5, "Fairy": 0.5,
+	},
+	"Steel": {
+		"Fire": 0.5, "Water": 0.5, "Electric": 0.5, "Ice": 2, "Rock": 2, "Steel": 0.5, "Fairy": 2,
+	},
+	"Fairy": {
+		"Fire": 0.5, "Fighting": 2, "Poison": 0.5, "Dragon": 2, "Dark": 2, "Steel": 0.5,
+	},
+}
+
+// TypeEffectiveness returns the damage multiplier for an attack of attackType
+// against a defender with the given types. Dual types multiply, so the result
+// is one of 0, 0.25, 0.5, 1, 2 or 4. Unknown or empty types are neutral.
+func TypeEffectiveness(attackType string, defenderTypes ...string) float64 {
+	matchups, ok := typeChart[normalizeType(attackType)]
+	if !ok {
+		return EffectivenessNeutral
+	}
+
+	multiplier := EffectivenessNeutral
+	for _, t := range defenderTypes {
+		if m, ok := matchups[normalizeType(t)]; ok {
+			multiplier *= m
+		}
+	}
+	return multiplier
+}
+
+// EffectivenessText returns the battle log message for a multiplier
+func EffectivenessText(multiplier float64) string {
+	switch {
+	case multiplier == EffectivenessImmune:
+		return "It had no effect..."
+	case multiplier > EffectivenessNeutral:
+		return "It's super effective!"
+	case multiplier < EffectivenessNeutral:
+		return "It's not very effective..."
+	}
+	return ""
+}
+
+// normalizeType turns "fire" or "FIRE" into "Fire" to match the species catalog
+func normalizeType(t string) string {
+	t = strings.TrimSpace(t)
+	if t == "" {
+		return ""
+	}
+	return strings.ToUpper(t[:1]) + strings.ToLower(t[1:])
+}
@@ -0,0 +1,55 @@
+package events
+
+// Published by battle-service on battle.events
+const (
+	BattleCompletedKey = "battle.completed"
+	BattleMatchedKey   = "battle.matched"
+)
+
+var (
+	_ Event = BattleCompleted{}
+	_ Event = BattleMatched{}
+)
+
+// BattleCompleted is published when a PvP battle ends. PvE battles are
+// practice and aren't announced.
+type BattleCompleted struct {
+	BattleID   uint `json:"battle_id"`
+	WinnerID   uint `json:"winner_id"`
+	LoserID    uint `json:"loser_id"`
+	PointsWon  int  `json:"points_won"`
+	PointsLost int  `json:"points_lost"`
+}
+
+func (BattleCompleted) RoutingKey() string { return BattleCompletedKey }
+func (BattleCompleted) Version() int       { return 1 }
+
+func (e BattleCompleted) Validate() error {
+	switch {
+	case e.BattleID == 0 || e.WinnerID == 0 || e.LoserID == 0:
+		return invalid(BattleCompletedKey, "needs battle_id, winner_id and loser_id")
+	case e.WinnerID == e.LoserID:
+		return invalid(BattleCompletedKey, "has the same winner and loser")
+	case e.PointsWon < 0 || e.PointsLost < 0:
+		return invalid(BattleCompletedKey, "has negative points")
+	}
+	return nil
+}
+
+// BattleMatched is published when matchmaking pairs two players
+type BattleMatched struct {
+	BattleID    uint `json:"battle_id"`
+	Player1ID   uint `json:"player1_id"`
+	Player2ID   uint `json:"player2_id"`
+	Interactive bool `json:"interactive"`
+}
+
+func (BattleMatched) RoutingKey() string { return BattleMatchedKey }
+func (BattleMatched) Version() int       { return 1 }
+
+func (e BattleMatched) Validate() error {
+	if e.BattleID == 0 || e.Player1ID == 0 || e.Player2ID == 0 {
+		return invalid(BattleMatchedKey, "needs battle_id, player1_id and player2_id")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package events defines the messages services publish to RabbitMQ. Producers
+// and consumers share these types, so a payload change that one side doesn't
+// follow fails to compile instead of failing on consume.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// VersionHeader carries the schema version of a message's payload. Messages
+// without it predate versioning and are read as version 1.
+const VersionHeader = "x-schema-version"
+
+var (
+	ErrInvalid            = errors.New("invalid event")
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+)
+
+// Event is a payload published on a routing key. Its version goes up when
+// the payload changes in a way older consumers can't read; new optional
+// fields don't need one.
+type Event interface {
+	RoutingKey() string
+	Version() int
+	Validate() error
+}
+
+// Encode validates an event and returns its payload
+func Encode(e Event) ([]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// Decode reads a payload published with the given schema version into e and
+// validates it. Versions newer than e's are rejected, since this consumer
+// can't know what changed in them.
+func Decode(body []byte, version int, e Event) error {
+	if version > e.Version() {
+		return fmt.Errorf("%w: %s v%d, expected up to v%d", ErrUnsupportedVersion, e.RoutingKey(), version, e.Version())
+	}
+	if err := json.Unmarshal(body, e); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, e.RoutingKey(), err)
+	}
+	return e.Validate()
+}
+
+// HeaderVersion returns the schema version in a message's headers
+func HeaderVersion(headers map[string]interface{}) int {
+	switch v := headers[VersionHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 1
+}
+
+func invalid(routingKey, reason string) error {
+	return fmt.Errorf("%w: %s %s", ErrInvalid, routingKey, reason)
+}
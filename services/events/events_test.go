@@ -0,0 +1,183 @@
+package events_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"maushold/events"
+)
+
+// contracts lists every event a producer publishes, built the way the
+// producer builds it, with a fresh value of the type its consumers decode it
+// into and the fields they can't do without
+var contracts = []struct {
+	name     string
+	event    events.Event
+	consume  func() events.Event
+	required []string
+}{
+	{
+		name:     "battle.completed",
+		event:    events.BattleCompleted{BattleID: 1, WinnerID: 2, LoserID: 3, PointsWon: 40, PointsLost: 25},
+		consume:  func() events.Event { return &events.BattleCompleted{} },
+		required: []string{"battle_id", "winner_id", "loser_id"},
+	},
+	{
+		name:     "battle.matched",
+		event:    events.BattleMatched{BattleID: 1, Player1ID: 2, Player2ID: 3, Interactive: true},
+		consume:  func() events.Event { return &events.BattleMatched{} },
+		required: []string{"battle_id", "player1_id", "player2_id"},
+	},
+	{
+		name:     "player.created",
+		event:    events.PlayerCreated{PlayerID: 1, Username: "ash", CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		consume:  func() events.Event { return &events.PlayerCreated{} },
+		required: []string{"player_id", "username"},
+	},
+	{
+		name:     "player.updated",
+		event:    events.PlayerUpdated{PlayerID: 1, Username: "ash", Points: 1200},
+		consume:  func() events.Event { return &events.PlayerUpdated{} },
+		required: []string{"player_id", "username"},
+	},
+	{
+		name:     "player.deleted",
+		event:    events.PlayerDeleted{PlayerID: 1},
+		consume:  func() events.Event { return &events.PlayerDeleted{} },
+		required: []string{"player_id"},
+	},
+	{
+		name:     "player.monster.added",
+		event:    events.PlayerMonsterAdded{PlayerID: 1, PlayerMonsterID: 2, MonsterID: 25, Nickname: "Sparky", Level: 5, MoveIDs: []uint{1, 2}},
+		consume:  func() events.Event { return &events.PlayerMonsterAdded{} },
+		required: []string{"player_id", "player_monster_id", "monster_id"},
+	},
+	{
+		name:     "player.monster.moves_updated",
+		event:    events.PlayerMonsterMovesUpdated{PlayerID: 1, PlayerMonsterID: 2, MoveIDs: []uint{3, 1}},
+		consume:  func() events.Event { return &events.PlayerMonsterMovesUpdated{} },
+		required: []string{"player_id", "player_monster_id", "move_ids"},
+	},
+	{
+		name: "season.ended",
+		event: events.SeasonEnded{
+			SeasonID:     1,
+			Name:         "Season 1",
+			StartsAt:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndsAt:       time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			TotalPlayers: 1,
+			Standings:    []events.SeasonStanding{{Rank: 1, PlayerID: 2, Username: "ash", CombatPower: 150000, TotalPoints: 1500, Wins: 10, WinRate: 100, Rating: 1700, RatingDeviation: 80, ConservativeRating: 1540}},
+		},
+		consume:  func() events.Event { return &events.SeasonEnded{} },
+		required: []string{"season_id", "ends_at"},
+	},
+}
+
+// headers are what the outbox relays publish an event with
+func headers(version int) map[string]interface{} {
+	return map[string]interface{}{events.VersionHeader: int32(version)}
+}
+
+func TestProducerAndConsumerAgree(t *testing.T) {
+	for _, c := range contracts {
+		t.Run(c.name, func(t *testing.T) {
+			if c.event.RoutingKey() != c.name {
+				t.Fatalf("routing key = %q, want %q", c.event.RoutingKey(), c.name)
+			}
+			body, err := events.Encode(c.event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got := c.consume()
+			if err := events.Decode(body, events.HeaderVersion(headers(c.event.Version())), got); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if decoded := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(decoded, c.event) {
+				t.Errorf("consumer read %+v, producer sent %+v", decoded, c.event)
+			}
+		})
+	}
+}
+
+func TestConsumerRejectsNewerVersion(t *testing.T) {
+	for _, c := range contracts {
+		t.Run(c.name, func(t *testing.T) {
+			body, err := events.Encode(c.event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			err = events.Decode(body, events.HeaderVersion(headers(c.event.Version()+1)), c.consume())
+			if !errors.Is(err, events.ErrUnsupportedVersion) {
+				t.Errorf("err = %v, want %v", err, events.ErrUnsupportedVersion)
+			}
+		})
+	}
+}
+
+func TestConsumerReadsUnversionedAsVersion1(t *testing.T) {
+	for _, c := range contracts {
+		if c.event.Version() != 1 {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			body, err := events.Encode(c.event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if err := events.Decode(body, events.HeaderVersion(nil), c.consume()); err != nil {
+				t.Errorf("Decode without a version header: %v", err)
+			}
+		})
+	}
+}
+
+func TestMissingFieldsAreRejected(t *testing.T) {
+	for _, c := range contracts {
+		for _, field := range c.required {
+			t.Run(c.name+"/"+field, func(t *testing.T) {
+				body, err := events.Encode(c.event)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				var payload map[string]json.RawMessage
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatalf("Unmarshal: %v", err)
+				}
+				if _, ok := payload[field]; !ok {
+					t.Fatalf("payload has no %q field", field)
+				}
+				delete(payload, field)
+				body, _ = json.Marshal(payload)
+
+				err = events.Decode(body, c.event.Version(), c.consume())
+				if !errors.Is(err, events.ErrInvalid) {
+					t.Errorf("err = %v, want %v", err, events.ErrInvalid)
+				}
+
+				// The producer can't publish it either
+				stripped := c.consume()
+				if err := json.Unmarshal(body, stripped); err != nil {
+					t.Fatalf("Unmarshal: %v", err)
+				}
+				if _, err := events.Encode(reflect.ValueOf(stripped).Elem().Interface().(events.Event)); !errors.Is(err, events.ErrInvalid) {
+					t.Errorf("Encode: err = %v, want %v", err, events.ErrInvalid)
+				}
+			})
+		}
+	}
+}
+
+func TestMalformedPayloadIsRejected(t *testing.T) {
+	for _, c := range contracts {
+		t.Run(c.name, func(t *testing.T) {
+			err := events.Decode([]byte(`{"battle_id": `), c.event.Version(), c.consume())
+			if !errors.Is(err, events.ErrInvalid) {
+				t.Errorf("err = %v, want %v", err, events.ErrInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+module maushold/events
+
+go 1.25.3
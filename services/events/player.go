@@ -0,0 +1,106 @@
+package events
+
+import "time"
+
+// Published by player-service on player.events
+const (
+	PlayerCreatedKey             = "player.created"
+	PlayerUpdatedKey             = "player.updated"
+	PlayerDeletedKey             = "player.deleted"
+	PlayerMonsterAddedKey        = "player.monster.added"
+	PlayerMonsterMovesUpdatedKey = "player.monster.moves_updated"
+)
+
+var (
+	_ Event = PlayerCreated{}
+	_ Event = PlayerUpdated{}
+	_ Event = PlayerDeleted{}
+	_ Event = PlayerMonsterAdded{}
+	_ Event = PlayerMonsterMovesUpdated{}
+)
+
+type PlayerCreated struct {
+	PlayerID  uint      `json:"player_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PlayerCreated) RoutingKey() string { return PlayerCreatedKey }
+func (PlayerCreated) Version() int       { return 1 }
+
+func (e PlayerCreated) Validate() error {
+	if e.PlayerID == 0 || e.Username == "" {
+		return invalid(PlayerCreatedKey, "needs player_id and username")
+	}
+	return nil
+}
+
+type PlayerUpdated struct {
+	PlayerID uint   `json:"player_id"`
+	Username string `json:"username"`
+	Points   int    `json:"points"`
+}
+
+func (PlayerUpdated) RoutingKey() string { return PlayerUpdatedKey }
+func (PlayerUpdated) Version() int       { return 1 }
+
+func (e PlayerUpdated) Validate() error {
+	if e.PlayerID == 0 || e.Username == "" {
+		return invalid(PlayerUpdatedKey, "needs player_id and username")
+	}
+	return nil
+}
+
+type PlayerDeleted struct {
+	PlayerID uint `json:"player_id"`
+}
+
+func (PlayerDeleted) RoutingKey() string { return PlayerDeletedKey }
+func (PlayerDeleted) Version() int       { return 1 }
+
+func (e PlayerDeleted) Validate() error {
+	if e.PlayerID == 0 {
+		return invalid(PlayerDeletedKey, "needs player_id")
+	}
+	return nil
+}
+
+// PlayerMonsterAdded is published when a player catches a monster.
+// MonsterID is the species in monster-service's catalog.
+type PlayerMonsterAdded struct {
+	PlayerID        uint   `json:"player_id"`
+	PlayerMonsterID uint   `json:"player_monster_id"`
+	MonsterID       int    `json:"monster_id"`
+	Nickname        string `json:"nickname"`
+	Level           int    `json:"level"`
+	MoveIDs         []uint `json:"move_ids"`
+}
+
+func (PlayerMonsterAdded) RoutingKey() string { return PlayerMonsterAddedKey }
+func (PlayerMonsterAdded) Version() int       { return 1 }
+
+func (e PlayerMonsterAdded) Validate() error {
+	if e.PlayerID == 0 || e.PlayerMonsterID == 0 || e.MonsterID == 0 {
+		return invalid(PlayerMonsterAddedKey, "needs player_id, player_monster_id and monster_id")
+	}
+	return nil
+}
+
+type PlayerMonsterMovesUpdated struct {
+	PlayerID        uint   `json:"player_id"`
+	PlayerMonsterID uint   `json:"player_monster_id"`
+	MoveIDs         []uint `json:"move_ids"` // in slot order
+}
+
+func (PlayerMonsterMovesUpdated) RoutingKey() string { return PlayerMonsterMovesUpdatedKey }
+func (PlayerMonsterMovesUpdated) Version() int       { return 1 }
+
+func (e PlayerMonsterMovesUpdated) Validate() error {
+	switch {
+	case e.PlayerID == 0 || e.PlayerMonsterID == 0:
+		return invalid(PlayerMonsterMovesUpdatedKey, "needs player_id and player_monster_id")
+	case len(e.MoveIDs) == 0:
+		return invalid(PlayerMonsterMovesUpdatedKey, "has no moves")
+	}
+	return nil
+}
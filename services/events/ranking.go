@@ -0,0 +1,43 @@
+package events
+
+import "time"
+
+// Published by ranking-service on ranking.events
+const SeasonEndedKey = "season.ended"
+
+var _ Event = SeasonEnded{}
+
+// SeasonEnded is published once a season's standings are archived, for
+// other services to grant rewards from
+type SeasonEnded struct {
+	SeasonID     uint             `json:"season_id"`
+	Name         string           `json:"name"`
+	StartsAt     time.Time        `json:"starts_at"`
+	EndsAt       time.Time        `json:"ends_at"`
+	TotalPlayers int              `json:"total_players"`
+	Standings    []SeasonStanding `json:"standings"` // top players, the rest via GET /rankings/seasons/{id}
+}
+
+type SeasonStanding struct {
+	Rank               int     `json:"rank"`
+	PlayerID           uint    `json:"player_id"`
+	Username           string  `json:"username"`
+	CombatPower        int64   `json:"combat_power"`
+	TotalPoints        int     `json:"total_points"`
+	Wins               int     `json:"wins"`
+	Losses             int     `json:"losses"`
+	WinRate            float64 `json:"win_rate"`
+	Rating             float64 `json:"rating"`
+	RatingDeviation    float64 `json:"rating_deviation"`
+	ConservativeRating float64 `json:"conservative_rating"`
+}
+
+func (SeasonEnded) RoutingKey() string { return SeasonEndedKey }
+func (SeasonEnded) Version() int       { return 1 }
+
+func (e SeasonEnded) Validate() error {
+	if e.SeasonID == 0 || e.EndsAt.IsZero() {
+		return invalid(SeasonEndedKey, "needs season_id and ends_at")
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ type Config struct {
 	RedisHost     string
 	RedisPassword string
 	RabbitMQURL   string
+	JWTSecret     string
 	ServicePort   string
 	ConsulAddr    string
 }
@@ -27,6 +28,7 @@ func LoadConfig() *Config {
 		RedisHost:     getEnv("REDIS_HOST"),
 		RedisPassword: getEnv("REDIS_PASSWORD"),
 		RabbitMQURL:   getEnv("RABBITMQ_URL"),
+		JWTSecret:     getEnv("JWT_SECRET"),
 		ServicePort:   getEnv("SERVICE_PORT"),
 		ConsulAddr:    getEnv("CONSUL_ADDR"),
 	}
@@ -20,7 +20,7 @@ func InitDB(cfg *Config) *gorm.DB {
 	}
 
 	// Auto migrate
-	err = db.AutoMigrate(&model.Monster{})
+	err = db.AutoMigrate(&model.Monster{}, &model.Move{}, &model.LearnsetEntry{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
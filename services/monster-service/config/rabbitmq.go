@@ -1,39 +1,35 @@
 package config
 
 import (
+	"fmt"
 	"log"
-	"time"
+
+	"maushold/rabbitmq"
 
 	"github.com/streadway/amqp"
 )
 
-func InitRabbitMQ(cfg *Config) (*amqp.Connection, *amqp.Channel) {
-	var conn *amqp.Connection
-	var err error
-
-	// Retry connection
-	for i := 0; i < 10; i++ {
-		conn, err = amqp.Dial(cfg.RabbitMQURL)
-		if err == nil {
-			break
-		}
-		log.Printf("Failed to connect to RabbitMQ (attempt %d/10): %v", i+1, err)
-		time.Sleep(3 * time.Second)
-	}
-
+// InitRabbitMQ connects to RabbitMQ and keeps the connection up, declaring
+// the topology again after every reconnect
+func InitRabbitMQ(cfg *Config) *rabbitmq.Connection {
+	conn, err := rabbitmq.Dial(cfg.RabbitMQURL, declareTopology)
 	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ after 10 attempts:", err)
+		log.Fatal("Failed to connect to RabbitMQ:", err)
 	}
+	return conn
+}
 
-	ch, err := conn.Channel()
+func declareTopology(ch *amqp.Channel) error {
+	// Declare OUR exchange (player.events)
+	err := ch.ExchangeDeclare("player.events", "topic", true, false, false, false, nil)
 	if err != nil {
-		log.Fatal("Failed to open channel:", err)
+		return fmt.Errorf("failed to declare player.events exchange: %w", err)
 	}
 
-	// Declare OUR exchange (player.events)
-	err = ch.ExchangeDeclare("player.events", "topic", true, false, false, false, nil)
+	// Declare monster.events, which monster and move changes are published to
+	err = ch.ExchangeDeclare("monster.events", "topic", true, false, false, false, nil)
 	if err != nil {
-		log.Fatal("Failed to declare player.events exchange:", err)
+		return fmt.Errorf("failed to declare monster.events exchange: %w", err)
 	}
 
 	// Declare battle.events exchange (so we can bind to it)
@@ -46,7 +42,7 @@ func InitRabbitMQ(cfg *Config) (*amqp.Connection, *amqp.Channel) {
 	// Declare our queue
 	_, err = ch.QueueDeclare("player.updates", true, false, false, false, nil)
 	if err != nil {
-		log.Fatal("Failed to declare queue:", err)
+		return fmt.Errorf("failed to declare queue: %w", err)
 	}
 
 	// Bind queue to battle events (safe now that exchange exists)
@@ -56,6 +52,5 @@ func InitRabbitMQ(cfg *Config) (*amqp.Connection, *amqp.Channel) {
 		// Don't fail here - battle service might not be up yet
 	}
 
-	log.Println("RabbitMQ connected")
-	return conn, ch
+	return nil
 }
@@ -9,6 +9,8 @@ require (
 	github.com/streadway/amqp v1.1.0
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.31.1
+	maushold/auth v0.0.0
+	maushold/rabbitmq v0.0.0
 )
 
 require (
@@ -17,6 +19,7 @@ require (
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/fatih/color v1.16.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
 	github.com/hashicorp/go-hclog v1.5.0 // indirect
@@ -40,3 +43,8 @@ require (
 	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 )
+
+replace (
+	maushold/auth => ../auth
+	maushold/rabbitmq => ../rabbitmq
+)
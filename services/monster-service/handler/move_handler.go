@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"maushold/monster-service/messaging"
+	"maushold/monster-service/model"
+	"maushold/monster-service/service"
+
+	"github.com/gorilla/mux"
+)
+
+type MoveHandler struct {
+	moveService     service.MoveService
+	messageProducer *messaging.Producer
+}
+
+func NewMoveHandler(moveService service.MoveService, messageProducer *messaging.Producer) *MoveHandler {
+	return &MoveHandler{
+		moveService:     moveService,
+		messageProducer: messageProducer,
+	}
+}
+
+func (h *MoveHandler) CreateMove(w http.ResponseWriter, r *http.Request) {
+	var move model.Move
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.moveService.CreateMove(&move); err != nil {
+		respondMoveError(w, err)
+		return
+	}
+
+	h.messageProducer.PublishMonsterEvent("move.created", move)
+	respondJSON(w, http.StatusCreated, move)
+}
+
+func (h *MoveHandler) GetMove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid move ID")
+		return
+	}
+
+	move, err := h.moveService.GetMove(uint(id))
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Move not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, move)
+}
+
+func (h *MoveHandler) GetAllMoves(w http.ResponseWriter, r *http.Request) {
+	moves, err := h.moveService.GetAllMoves()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, moves)
+}
+
+func (h *MoveHandler) UpdateMove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid move ID")
+		return
+	}
+
+	move, err := h.moveService.GetMove(uint(id))
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Move not found")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(move); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	move.ID = uint(id)
+
+	if err := h.moveService.UpdateMove(move); err != nil {
+		respondMoveError(w, err)
+		return
+	}
+
+	h.messageProducer.PublishMonsterEvent("move.updated", move)
+	respondJSON(w, http.StatusOK, move)
+}
+
+func (h *MoveHandler) DeleteMove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid move ID")
+		return
+	}
+
+	if err := h.moveService.DeleteMove(uint(id)); err != nil {
+		respondError(w, http.StatusNotFound, "Move not found")
+		return
+	}
+
+	h.messageProducer.PublishMonsterEvent("move.deleted", map[string]interface{}{"move_id": id})
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Move deleted successfully"})
+}
+
+func (h *MoveHandler) GetLearnset(w http.ResponseWriter, r *http.Request) {
+	monsterID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid monster ID")
+		return
+	}
+
+	learnset, err := h.moveService.GetLearnset(monsterID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, learnset)
+}
+
+func (h *MoveHandler) AddToLearnset(w http.ResponseWriter, r *http.Request) {
+	monsterID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid monster ID")
+		return
+	}
+
+	var entry model.LearnsetEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	entry.MonsterID = monsterID
+
+	if err := h.moveService.AddToLearnset(&entry); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, entry)
+}
+
+func (h *MoveHandler) RemoveFromLearnset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	monsterID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid monster ID")
+		return
+	}
+
+	moveID, err := strconv.ParseUint(vars["moveId"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid move ID")
+		return
+	}
+
+	if err := h.moveService.RemoveFromLearnset(monsterID, uint(moveID)); err != nil {
+		respondError(w, http.StatusNotFound, "Learnset entry not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Move removed from learnset"})
+}
+
+func respondMoveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrInvalidMove) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondError(w, http.StatusInternalServerError, err.Error())
+}
@@ -20,9 +20,10 @@ func main() {
 
 	db := config.InitDB(cfg)
 	redisClient := config.InitRedis(cfg)
-	rabbitConn, rabbitCh := config.InitRabbitMQ(cfg)
+	rabbitConn := config.InitRabbitMQ(cfg)
 	defer rabbitConn.Close()
-	defer rabbitCh.Close()
+
+	authKeys := config.InitAuth(cfg, redisClient)
 
 	consulClient := config.InitConsul(cfg)
 	err := config.RegisterService(consulClient, "monster-service", cfg.ServicePort)
@@ -35,16 +36,21 @@ func main() {
 	serviceDiscovery := service.NewServiceDiscovery(consulClient)
 
 	monsterRepo := repository.NewMonsterRepository(db)
+	moveRepo := repository.NewMoveRepository(db)
 	monsterService := service.NewMonsterService(monsterRepo, redisClient)
+	moveService := service.NewMoveService(moveRepo, monsterRepo, redisClient)
 
 	// Seed initial data
 	service.SeedMonster(monsterRepo)
+	service.SeedMoves(moveRepo, monsterRepo)
 
-	messageProducer := messaging.NewProducer(rabbitCh)
+	messageProducer := messaging.NewProducer(rabbitConn)
 	monsterHandler := handler.NewMonsterHandler(monsterService, messageProducer, serviceDiscovery)
+	moveHandler := handler.NewMoveHandler(moveService, messageProducer)
 
 	router := mux.NewRouter()
-	routes.SetupMonsterRoutes(router, monsterHandler)
+	routes.SetupMonsterRoutes(router, monsterHandler, authKeys)
+	routes.SetupMoveRoutes(router, moveHandler)
 
 	port := os.Getenv("SERVICE_PORT")
 	if port == "" {
@@ -4,15 +4,17 @@ import (
 	"encoding/json"
 	"log"
 
+	"maushold/rabbitmq"
+
 	"github.com/streadway/amqp"
 )
 
 type Producer struct {
-	channel *amqp.Channel
+	conn *rabbitmq.Connection
 }
 
-func NewProducer(channel *amqp.Channel) *Producer {
-	return &Producer{channel: channel}
+func NewProducer(conn *rabbitmq.Connection) *Producer {
+	return &Producer{conn: conn}
 }
 
 func (p *Producer) PublishMonsterEvent(routingKey string, data interface{}) error {
@@ -21,11 +23,9 @@ func (p *Producer) PublishMonsterEvent(routingKey string, data interface{}) erro
 		return err
 	}
 
-	err = p.channel.Publish(
+	err = p.conn.Publish(
 		"monster.events",
 		routingKey,
-		false,
-		false,
 		amqp.Publishing{
 			ContentType: "application/json",
 			Body:        body,
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+const (
+	MoveCategoryPhysical = "physical"
+	MoveCategorySpecial  = "special"
+	MoveCategoryStatus   = "status"
+)
+
+// Status conditions a move can inflict
+const (
+	StatusBurn      = "burn"
+	StatusPoison    = "poison"
+	StatusParalysis = "paralysis"
+	StatusSleep     = "sleep"
+	StatusFreeze    = "freeze"
+	StatusConfusion = "confusion"
+)
+
+type Move struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"unique;not null" json:"name"`
+	Type         string    `gorm:"not null" json:"type"`
+	Category     string    `gorm:"not null;default:'physical'" json:"category"`
+	Power        int       `gorm:"not null" json:"power"`
+	Accuracy     int       `gorm:"not null;default:100" json:"accuracy"`
+	PP           int       `gorm:"not null;default:10" json:"pp"`
+	Priority     int       `gorm:"default:0" json:"priority"`
+	Effect       string    `json:"effect"`
+	EffectChance int       `gorm:"default:0" json:"effect_chance"`
+	Description  string    `json:"description"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LearnsetEntry records that a species can learn a move once it reaches Level
+type LearnsetEntry struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	MonsterID int  `gorm:"not null;uniqueIndex:idx_learnset_monster_move" json:"monster_id"`
+	MoveID    uint `gorm:"not null;uniqueIndex:idx_learnset_monster_move" json:"move_id"`
+	Level     int  `gorm:"default:1" json:"level"`
+	Move      Move `gorm:"foreignKey:MoveID;constraint:OnDelete:CASCADE" json:"move"`
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"maushold/monster-service/model"
+
+	"gorm.io/gorm"
+)
+
+type MoveRepository interface {
+	Create(move *model.Move) error
+	FindByID(id uint) (*model.Move, error)
+	FindAll() ([]model.Move, error)
+	Update(move *model.Move) error
+	Delete(move *model.Move) error
+	FindLearnset(monsterID int) ([]model.LearnsetEntry, error)
+	AddToLearnset(entry *model.LearnsetEntry) error
+	RemoveFromLearnset(monsterID int, moveID uint) error
+}
+
+type moveRepository struct {
+	db *gorm.DB
+}
+
+func NewMoveRepository(db *gorm.DB) MoveRepository {
+	return &moveRepository{db: db}
+}
+
+func (r *moveRepository) Create(move *model.Move) error {
+	return r.db.Create(move).Error
+}
+
+func (r *moveRepository) FindByID(id uint) (*model.Move, error) {
+	var move model.Move
+	err := r.db.First(&move, id).Error
+	return &move, err
+}
+
+func (r *moveRepository) FindAll() ([]model.Move, error) {
+	var moves []model.Move
+	err := r.db.Order("id ASC").Find(&moves).Error
+	return moves, err
+}
+
+func (r *moveRepository) Update(move *model.Move) error {
+	return r.db.Save(move).Error
+}
+
+func (r *moveRepository) Delete(move *model.Move) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("move_id = ?", move.ID).Delete(&model.LearnsetEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(move).Error
+	})
+}
+
+func (r *moveRepository) FindLearnset(monsterID int) ([]model.LearnsetEntry, error) {
+	var entries []model.LearnsetEntry
+	err := r.db.Preload("Move").
+		Where("monster_id = ?", monsterID).
+		Order("level ASC, move_id ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *moveRepository) AddToLearnset(entry *model.LearnsetEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *moveRepository) RemoveFromLearnset(monsterID int, moveID uint) error {
+	result := r.db.Where("monster_id = ? AND move_id = ?", monsterID, moveID).Delete(&model.LearnsetEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -3,16 +3,32 @@ package routes
 import (
 	"net/http"
 
+	"maushold/auth"
 	"maushold/monster-service/handler"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupMonsterRoutes(router *mux.Router, handler *handler.MonsterHandler) {
+func SetupMonsterRoutes(router *mux.Router, handler *handler.MonsterHandler, keys *auth.Keys) {
+	router.Use(keys.Middleware())
+
 	// API routes
-	router.HandleFunc("/monster", handler.CreateMonster).Methods(http.MethodPost)
+	router.HandleFunc("/monster", auth.RequireRole(auth.RoleAdmin, handler.CreateMonster)).Methods(http.MethodPost)
+	router.HandleFunc("/monster/random", handler.GetRandomMonster).Methods(http.MethodGet)
 	router.HandleFunc("/monster/{id}", handler.GetMonster).Methods(http.MethodGet)
 	router.HandleFunc("/monster", handler.GetAllMonster).Methods(http.MethodGet)
-	router.HandleFunc("/monster/random", handler.GetRandomMonster).Methods(http.MethodGet)
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
 }
+
+func SetupMoveRoutes(router *mux.Router, handler *handler.MoveHandler) {
+	router.HandleFunc("/moves", auth.RequireRole(auth.RoleAdmin, handler.CreateMove)).Methods(http.MethodPost)
+	router.HandleFunc("/moves", handler.GetAllMoves).Methods(http.MethodGet)
+	router.HandleFunc("/moves/{id}", handler.GetMove).Methods(http.MethodGet)
+	router.HandleFunc("/moves/{id}", auth.RequireRole(auth.RoleAdmin, handler.UpdateMove)).Methods(http.MethodPut)
+	router.HandleFunc("/moves/{id}", auth.RequireRole(auth.RoleAdmin, handler.DeleteMove)).Methods(http.MethodDelete)
+
+	// Learnsets
+	router.HandleFunc("/monster/{id}/moves", handler.GetLearnset).Methods(http.MethodGet)
+	router.HandleFunc("/monster/{id}/moves", auth.RequireRole(auth.RoleAdmin, handler.AddToLearnset)).Methods(http.MethodPost)
+	router.HandleFunc("/monster/{id}/moves/{moveId}", auth.RequireRole(auth.RoleAdmin, handler.RemoveFromLearnset)).Methods(http.MethodDelete)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"maushold/monster-service/model"
+	"maushold/monster-service/repository"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const MaxMovePriority = 5
+
+var ErrInvalidMove = errors.New("invalid move")
+
+type MoveService interface {
+	CreateMove(move *model.Move) error
+	GetMove(id uint) (*model.Move, error)
+	GetAllMoves() ([]model.Move, error)
+	UpdateMove(move *model.Move) error
+	DeleteMove(id uint) error
+	GetLearnset(monsterID int) ([]model.LearnsetEntry, error)
+	AddToLearnset(entry *model.LearnsetEntry) error
+	RemoveFromLearnset(monsterID int, moveID uint) error
+}
+
+type moveService struct {
+	repo        repository.MoveRepository
+	monsterRepo repository.MonsterRepository
+	redis       *redis.Client
+	ctx         context.Context
+}
+
+func NewMoveService(repo repository.MoveRepository, monsterRepo repository.MonsterRepository, redisClient *redis.Client) MoveService {
+	return &moveService{
+		repo:        repo,
+		monsterRepo: monsterRepo,
+		redis:       redisClient,
+		ctx:         context.Background(),
+	}
+}
+
+func (s *moveService) CreateMove(move *model.Move) error {
+	if err := validateMove(move); err != nil {
+		return err
+	}
+
+	if err := s.repo.Create(move); err != nil {
+		return err
+	}
+
+	s.redis.Del(s.ctx, "move:all")
+	return nil
+}
+
+func (s *moveService) GetMove(id uint) (*model.Move, error) {
+	cacheKey := fmt.Sprintf("move:%d", id)
+
+	cached, err := s.redis.Get(s.ctx, cacheKey).Result()
+	if err == nil {
+		var move model.Move
+		if json.Unmarshal([]byte(cached), &move) == nil {
+			return &move, nil
+		}
+	}
+
+	move, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(move)
+	s.redis.Set(s.ctx, cacheKey, data, 10*time.Minute)
+
+	return move, nil
+}
+
+func (s *moveService) GetAllMoves() ([]model.Move, error) {
+	cached, err := s.redis.Get(s.ctx, "move:all").Result()
+	if err == nil {
+		var moves []model.Move
+		if json.Unmarshal([]byte(cached), &moves) == nil {
+			return moves, nil
+		}
+	}
+
+	moves, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(moves)
+	s.redis.Set(s.ctx, "move:all", data, 10*time.Minute)
+
+	return moves, nil
+}
+
+func (s *moveService) UpdateMove(move *model.Move) error {
+	if err := validateMove(move); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(move); err != nil {
+		return err
+	}
+
+	s.invalidateMove(move.ID)
+	return nil
+}
+
+func (s *moveService) DeleteMove(id uint) error {
+	move, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(move); err != nil {
+		return err
+	}
+
+	s.invalidateMove(id)
+	return nil
+}
+
+func (s *moveService) GetLearnset(monsterID int) ([]model.LearnsetEntry, error) {
+	cacheKey := fmt.Sprintf("learnset:%d", monsterID)
+
+	cached, err := s.redis.Get(s.ctx, cacheKey).Result()
+	if err == nil {
+		var entries []model.LearnsetEntry
+		if json.Unmarshal([]byte(cached), &entries) == nil {
+			return entries, nil
+		}
+	}
+
+	entries, err := s.repo.FindLearnset(monsterID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(entries)
+	s.redis.Set(s.ctx, cacheKey, data, 10*time.Minute)
+
+	return entries, nil
+}
+
+func (s *moveService) AddToLearnset(entry *model.LearnsetEntry) error {
+	if _, err := s.monsterRepo.FindByID(entry.MonsterID); err != nil {
+		return errors.New("monster not found")
+	}
+
+	move, err := s.repo.FindByID(entry.MoveID)
+	if err != nil {
+		return errors.New("move not found")
+	}
+
+	if entry.Level < 1 {
+		entry.Level = 1
+	}
+
+	if err := s.repo.AddToLearnset(entry); err != nil {
+		return err
+	}
+	entry.Move = *move
+
+	s.redis.Del(s.ctx, fmt.Sprintf("learnset:%d", entry.MonsterID))
+	return nil
+}
+
+func (s *moveService) RemoveFromLearnset(monsterID int, moveID uint) error {
+	if err := s.repo.RemoveFromLearnset(monsterID, moveID); err != nil {
+		return err
+	}
+
+	s.redis.Del(s.ctx, fmt.Sprintf("learnset:%d", monsterID))
+	return nil
+}
+
+// invalidateMove drops every cache entry that may embed the move
+func (s *moveService) invalidateMove(id uint) {
+	s.redis.Del(s.ctx, fmt.Sprintf("move:%d", id), "move:all")
+
+	keys, err := s.redis.Keys(s.ctx, "learnset:*").Result()
+	if err == nil && len(keys) > 0 {
+		s.redis.Del(s.ctx, keys...)
+	}
+}
+
+func validateMove(move *model.Move) error {
+	if move.Name == "" || move.Type == "" {
+		return fmt.Errorf("%w: name and type are required", ErrInvalidMove)
+	}
+
+	switch move.Category {
+	case "":
+		move.Category = model.MoveCategoryPhysical
+	case model.MoveCategoryPhysical, model.MoveCategorySpecial, model.MoveCategoryStatus:
+	default:
+		return fmt.Errorf("%w: category must be %q, %q or %q", ErrInvalidMove,
+			model.MoveCategoryPhysical, model.MoveCategorySpecial, model.MoveCategoryStatus)
+	}
+
+	switch move.Effect {
+	case "":
+		move.EffectChance = 0
+	case model.StatusBurn, model.StatusPoison, model.StatusParalysis,
+		model.StatusSleep, model.StatusFreeze, model.StatusConfusion:
+		if move.EffectChance == 0 {
+			move.EffectChance = 100
+		}
+		if move.EffectChance < 0 || move.EffectChance > 100 {
+			return fmt.Errorf("%w: effect_chance must be between 1 and 100", ErrInvalidMove)
+		}
+	default:
+		return fmt.Errorf("%w: unknown effect %q", ErrInvalidMove, move.Effect)
+	}
+
+	if move.Category == model.MoveCategoryStatus && (move.Power != 0 || move.Effect == "") {
+		return fmt.Errorf("%w: status moves must have no power and an effect", ErrInvalidMove)
+	}
+
+	if move.Accuracy == 0 {
+		move.Accuracy = 100
+	}
+	if move.PP == 0 {
+		move.PP = 10
+	}
+
+	if move.Power < 0 {
+		return fmt.Errorf("%w: power cannot be negative", ErrInvalidMove)
+	}
+	if move.Accuracy <= 0 || move.Accuracy > 100 {
+		return fmt.Errorf("%w: accuracy must be between 1 and 100", ErrInvalidMove)
+	}
+	if move.PP <= 0 {
+		return fmt.Errorf("%w: pp must be positive", ErrInvalidMove)
+	}
+	if move.Priority < -MaxMovePriority || move.Priority > MaxMovePriority {
+		return fmt.Errorf("%w: priority must be between %d and %d", ErrInvalidMove, -MaxMovePriority, MaxMovePriority)
+	}
+
+	return nil
+}
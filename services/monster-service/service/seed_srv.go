@@ -34,3 +34,93 @@ func SeedMonster(repo repository.MonsterRepository) {
 
 	log.Println("Seeded initial Monster data")
 }
+
+type learnsetSeed struct {
+	Move  string
+	Level int
+}
+
+func SeedMoves(moveRepo repository.MoveRepository, monsterRepo repository.MonsterRepository) {
+	all, err := moveRepo.FindAll()
+	if err == nil && len(all) > 0 {
+		log.Println("Move data already seeded")
+		return
+	}
+
+	starterMoves := []model.Move{
+		{Name: "Tackle", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100, PP: 35, Description: "A physical attack in which the user charges and slams into the target."},
+		{Name: "Scratch", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100, PP: 35, Description: "Hard, pointed, sharp claws rake the target to inflict damage."},
+		{Name: "Quick Attack", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 40, Accuracy: 100, PP: 30, Priority: 1, Description: "An extremely fast attack that always strikes first."},
+		{Name: "Headbutt", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 70, Accuracy: 100, PP: 15, Description: "The user sticks out its head and rams straight forward."},
+		{Name: "Body Slam", Type: "Normal", Category: model.MoveCategoryPhysical, Power: 85, Accuracy: 100, PP: 15, Effect: model.StatusParalysis, EffectChance: 30, Description: "The user drops onto the target with its full body weight."},
+		{Name: "Ember", Type: "Fire", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 25, Effect: model.StatusBurn, EffectChance: 10, Description: "The target is attacked with small flames."},
+		{Name: "Flamethrower", Type: "Fire", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 15, Effect: model.StatusBurn, EffectChance: 10, Description: "The target is scorched with an intense blast of fire."},
+		{Name: "Water Gun", Type: "Water", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 25, Description: "The target is blasted with a forceful shot of water."},
+		{Name: "Surf", Type: "Water", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 15, Description: "The user attacks everything around it by swamping its surroundings with a giant wave."},
+		{Name: "Vine Whip", Type: "Grass", Category: model.MoveCategoryPhysical, Power: 45, Accuracy: 100, PP: 25, Description: "The target is struck with slender, whiplike vines."},
+		{Name: "Razor Leaf", Type: "Grass", Category: model.MoveCategoryPhysical, Power: 55, Accuracy: 95, PP: 25, Description: "Sharp-edged leaves are launched to slash at the target."},
+		{Name: "Thunder Shock", Type: "Electric", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 30, Effect: model.StatusParalysis, EffectChance: 10, Description: "A jolt of electricity crashes down on the target."},
+		{Name: "Thunderbolt", Type: "Electric", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 15, Effect: model.StatusParalysis, EffectChance: 10, Description: "A strong electric blast crashes down on the target."},
+		{Name: "Sludge Bomb", Type: "Poison", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 10, Effect: model.StatusPoison, EffectChance: 30, Description: "Unsanitary sludge is hurled at the target."},
+		{Name: "Confusion", Type: "Psychic", Category: model.MoveCategorySpecial, Power: 50, Accuracy: 100, PP: 25, Effect: model.StatusConfusion, EffectChance: 10, Description: "The target is hit by a weak telekinetic force."},
+		{Name: "Psychic", Type: "Psychic", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 10, Description: "The target is hit by a strong telekinetic force."},
+		{Name: "Lick", Type: "Ghost", Category: model.MoveCategoryPhysical, Power: 30, Accuracy: 100, PP: 30, Effect: model.StatusParalysis, EffectChance: 30, Description: "The target is licked with a long tongue."},
+		{Name: "Shadow Ball", Type: "Ghost", Category: model.MoveCategorySpecial, Power: 80, Accuracy: 100, PP: 15, Description: "The user hurls a shadowy blob at the target."},
+		{Name: "Ice Beam", Type: "Ice", Category: model.MoveCategorySpecial, Power: 90, Accuracy: 100, PP: 10, Effect: model.StatusFreeze, EffectChance: 10, Description: "The target is struck with an icy-cold beam of energy."},
+		{Name: "Poison Powder", Type: "Poison", Category: model.MoveCategoryStatus, Accuracy: 75, PP: 35, Effect: model.StatusPoison, EffectChance: 100, Description: "The user scatters a cloud of poisonous dust that poisons the target."},
+		{Name: "Will-O-Wisp", Type: "Fire", Category: model.MoveCategoryStatus, Accuracy: 85, PP: 15, Effect: model.StatusBurn, EffectChance: 100, Description: "The user shoots a sinister flame at the target to inflict a burn."},
+		{Name: "Thunder Wave", Type: "Electric", Category: model.MoveCategoryStatus, Accuracy: 90, PP: 20, Effect: model.StatusParalysis, EffectChance: 100, Description: "The user launches a weak jolt of electricity that paralyzes the target."},
+		{Name: "Sing", Type: "Normal", Category: model.MoveCategoryStatus, Accuracy: 55, PP: 15, Effect: model.StatusSleep, EffectChance: 100, Description: "A soothing lullaby is sung in a calming voice that puts the target into a deep slumber."},
+		{Name: "Hypnosis", Type: "Psychic", Category: model.MoveCategoryStatus, Accuracy: 60, PP: 20, Effect: model.StatusSleep, EffectChance: 100, Description: "The user employs hypnotic suggestion to make the target fall into a deep sleep."},
+		{Name: "Supersonic", Type: "Normal", Category: model.MoveCategoryStatus, Accuracy: 55, PP: 20, Effect: model.StatusConfusion, EffectChance: 100, Description: "The user generates odd sound waves from its body that confuse the target."},
+		{Name: "Wing Attack", Type: "Flying", Category: model.MoveCategoryPhysical, Power: 60, Accuracy: 100, PP: 35, Description: "The target is struck with large, imposing wings."},
+		{Name: "Bite", Type: "Dark", Category: model.MoveCategoryPhysical, Power: 60, Accuracy: 100, PP: 25, Description: "The target is bitten with viciously sharp fangs."},
+		{Name: "Disarming Voice", Type: "Fairy", Category: model.MoveCategorySpecial, Power: 40, Accuracy: 100, PP: 15, Description: "Letting out a charming cry, the user does emotional damage to opponents."},
+		{Name: "Dragon Claw", Type: "Dragon", Category: model.MoveCategoryPhysical, Power: 80, Accuracy: 100, PP: 15, Description: "The user slashes the target with huge sharp claws."},
+		{Name: "Aura Sphere", Type: "Fighting", Category: model.MoveCategorySpecial, Power: 80, Accuracy: 100, PP: 20, Description: "The user lets loose a blast of aura power from deep within its body."},
+		{Name: "Metal Claw", Type: "Steel", Category: model.MoveCategoryPhysical, Power: 50, Accuracy: 95, PP: 35, Description: "The target is raked with steel claws."},
+	}
+
+	moveIDs := make(map[string]uint)
+	for _, m := range starterMoves {
+		if err := moveRepo.Create(&m); err != nil {
+			log.Printf("Failed to seed move %s: %v", m.Name, err)
+			continue
+		}
+		moveIDs[m.Name] = m.ID
+	}
+
+	// Learnsets are keyed by species name so they also cover monsters seeded from SQL
+	learnsets := map[string][]learnsetSeed{
+		"Bulbasaur":  {{"Tackle", 1}, {"Vine Whip", 1}, {"Poison Powder", 5}, {"Razor Leaf", 10}, {"Sludge Bomb", 20}},
+		"Charmander": {{"Scratch", 1}, {"Ember", 1}, {"Metal Claw", 10}, {"Will-O-Wisp", 15}, {"Flamethrower", 20}},
+		"Charizard":  {{"Ember", 1}, {"Wing Attack", 1}, {"Dragon Claw", 10}, {"Flamethrower", 20}},
+		"Squirtle":   {{"Tackle", 1}, {"Water Gun", 1}, {"Bite", 10}, {"Surf", 20}, {"Ice Beam", 25}},
+		"Pikachu":    {{"Quick Attack", 1}, {"Thunder Shock", 1}, {"Thunder Wave", 5}, {"Headbutt", 10}, {"Thunderbolt", 20}},
+		"Jigglypuff": {{"Sing", 1}, {"Tackle", 1}, {"Disarming Voice", 1}, {"Body Slam", 10}, {"Psychic", 20}},
+		"Eevee":      {{"Tackle", 1}, {"Quick Attack", 1}, {"Supersonic", 5}, {"Bite", 10}, {"Body Slam", 20}},
+		"Snorlax":    {{"Tackle", 1}, {"Lick", 1}, {"Headbutt", 10}, {"Body Slam", 20}},
+		"Mewtwo":     {{"Confusion", 1}, {"Psychic", 1}, {"Aura Sphere", 10}, {"Shadow Ball", 20}, {"Ice Beam", 25}},
+		"Gengar":     {{"Hypnosis", 1}, {"Lick", 1}, {"Shadow Ball", 1}, {"Confusion", 10}, {"Sludge Bomb", 20}},
+		"Dragonite":  {{"Wing Attack", 1}, {"Dragon Claw", 1}, {"Headbutt", 10}, {"Thunderbolt", 20}},
+		"Lucario":    {{"Quick Attack", 1}, {"Metal Claw", 1}, {"Bite", 10}, {"Aura Sphere", 20}},
+	}
+
+	monsters, err := monsterRepo.FindAll()
+	if err != nil {
+		log.Printf("Failed to load monsters for learnset seeding: %v", err)
+		return
+	}
+
+	for _, monster := range monsters {
+		for _, seed := range learnsets[monster.Name] {
+			moveID, ok := moveIDs[seed.Move]
+			if !ok {
+				continue
+			}
+			moveRepo.AddToLearnset(&model.LearnsetEntry{MonsterID: monster.ID, MoveID: moveID, Level: seed.Level})
+		}
+	}
+
+	log.Println("Seeded initial Move data")
+}
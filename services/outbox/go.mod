@@ -0,0 +1,21 @@
+module maushold/outbox
+
+go 1.25.3
+
+require (
+	github.com/streadway/amqp v1.1.0
+	gorm.io/gorm v1.31.1
+	maushold/events v0.0.0
+	maushold/rabbitmq v0.0.0
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
+
+replace (
+	maushold/events => ../events
+	maushold/rabbitmq => ../rabbitmq
+)
@@ -0,0 +1,74 @@
+// Package outbox publishes events through a transactional outbox. A service
+// writes events to its outbox_events table in the same transaction as the
+// change they describe, and a Relay publishes them to the service's exchange
+// once that commits, so an event is never lost nor sent for a change that
+// rolled back.
+package outbox
+
+import (
+	"time"
+
+	"maushold/events"
+
+	"gorm.io/gorm"
+)
+
+// Message is an event waiting to be published. It is marked sent once
+// RabbitMQ has confirmed it. A message the broker turns down is tried again
+// after a delay that doubles with every attempt, up to maxRetryDelay; it is
+// never given up on, so an outage only delays events.
+type Message struct {
+	ID            uint      `gorm:"primaryKey"`
+	RoutingKey    string    `gorm:"not null"`
+	SchemaVersion int       `gorm:"not null;default:1"`
+	Payload       string    `gorm:"type:jsonb;not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	NextAttemptAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_outbox_due,where:sent_at IS NULL"` // also pushed back while a relay has it claimed
+	SentAt        *time.Time
+}
+
+func (Message) TableName() string {
+	return "outbox_events"
+}
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// retryDelay is how long to wait before trying a message again after its
+// attempts-th failed attempt
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Pending builds an event to publish once the change it is written with
+// commits. It is called inside the transaction after the change, so the
+// event sees any IDs the database assigned.
+type Pending func() events.Event
+
+// Add writes events to the outbox as part of tx
+func Add(tx *gorm.DB, pending []Pending) error {
+	for _, build := range pending {
+		event := build()
+		payload, err := events.Encode(event)
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&Message{
+			RoutingKey:    event.RoutingKey(),
+			SchemaVersion: event.Version(),
+			Payload:       string(payload),
+			NextAttemptAt: time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
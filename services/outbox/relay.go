@@ -0,0 +1,212 @@
+package outbox
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"maushold/events"
+	"maushold/rabbitmq"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	pollInterval   = time.Second
+	batchSize      = 100
+	confirmTimeout = 10 * time.Second
+	lease          = 3 * confirmTimeout // covers publishing and confirming a batch
+	retention      = 7 * 24 * time.Hour
+	pruneInterval  = time.Hour
+	checkInterval  = time.Minute
+	alertAge       = 5 * time.Minute // warn once the oldest unsent event is this old
+)
+
+// Relay publishes events from the outbox to a service's exchange. It uses
+// its own channel in confirm mode and marks an event sent only once the
+// broker has confirmed it, so every event is delivered at least once.
+type Relay struct {
+	conn     *rabbitmq.Connection
+	repo     Repository
+	exchange string
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	nextTag  uint64
+}
+
+func NewRelay(conn *rabbitmq.Connection, repo Repository, exchange string) *Relay {
+	return &Relay{conn: conn, repo: repo, exchange: exchange}
+}
+
+// Start polls the outbox until the process exits, warning while events are
+// stuck in it and pruning sent events once they are older than retention
+func (r *Relay) Start() {
+	log.Printf("Outbox relay started for %s", r.exchange)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var checked, pruned time.Time
+	for range ticker.C {
+		r.relay()
+
+		if time.Since(checked) >= checkInterval {
+			checked = time.Now()
+			r.checkBacklog()
+		}
+		if time.Since(pruned) >= pruneInterval {
+			pruned = time.Now()
+			if _, err := r.repo.DeleteSentBefore(pruned.Add(-retention)); err != nil {
+				log.Printf("Failed to prune outbox: %v", err)
+			}
+		}
+	}
+}
+
+// relay publishes batches until the outbox is empty or a batch isn't fully
+// confirmed
+func (r *Relay) relay() {
+	for {
+		batch, err := r.repo.Claim(batchSize, lease)
+		if err != nil {
+			log.Printf("Failed to claim outbox events: %v", err)
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		confirmed, unconfirmed := r.publish(batch)
+		if err := r.repo.MarkSent(confirmed); err != nil {
+			log.Printf("Failed to mark outbox events sent: %v", err)
+			return
+		}
+		if err := r.repo.MarkAttempted(unconfirmed); err != nil {
+			log.Printf("Failed to record outbox attempts: %v", err)
+			return
+		}
+		if len(confirmed) < batchSize {
+			return
+		}
+	}
+}
+
+// checkBacklog warns when the oldest event waiting to be sent is older than
+// alertAge, which means the broker has been turning events down for a while
+func (r *Relay) checkBacklog() {
+	waiting, oldest, err := r.repo.Backlog()
+	if err != nil {
+		log.Printf("Failed to check outbox backlog: %v", err)
+		return
+	}
+	if oldest == nil {
+		return
+	}
+	if age := time.Since(*oldest); age >= alertAge {
+		log.Printf("Warning: %d events waiting to be published to %s, the oldest for %s", waiting, r.exchange, age.Round(time.Second))
+	}
+}
+
+// publish sends events and waits for the broker to confirm them. It returns
+// the IDs of those it acked and the events it was handed but did not ack;
+// events it never got to are left to be claimed again once their lease runs
+// out.
+func (r *Relay) publish(batch []Message) ([]uint, []Message) {
+	if err := r.openChannel(); err != nil {
+		log.Printf("Failed to open outbox channel: %v", err)
+		return nil, batch
+	}
+
+	pending := make(map[uint64]Message, len(batch))
+	var unconfirmed []Message
+	healthy := true
+	for _, event := range batch {
+		err := r.channel.Publish(
+			r.exchange,
+			event.RoutingKey,
+			false,
+			false,
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				Headers:      amqp.Table{events.VersionHeader: int32(event.SchemaVersion)},
+				MessageId:    strconv.FormatUint(uint64(event.ID), 10),
+				Timestamp:    event.CreatedAt,
+				Body:         []byte(event.Payload),
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to publish event %s: %v", event.RoutingKey, err)
+			unconfirmed = append(unconfirmed, event)
+			healthy = false
+			break
+		}
+		r.nextTag++
+		pending[r.nextTag] = event
+	}
+
+	confirmed := make([]uint, 0, len(pending))
+	timeout := time.After(confirmTimeout)
+	for len(pending) > 0 && healthy {
+		select {
+		case confirm, ok := <-r.confirms:
+			if !ok {
+				healthy = false
+				continue
+			}
+			event, mine := pending[confirm.DeliveryTag]
+			if !mine {
+				continue
+			}
+			delete(pending, confirm.DeliveryTag)
+			if !confirm.Ack {
+				log.Printf("Broker rejected event %s (outbox %d)", event.RoutingKey, event.ID)
+				unconfirmed = append(unconfirmed, event)
+				continue
+			}
+			confirmed = append(confirmed, event.ID)
+			log.Printf("Published event: %s", event.RoutingKey)
+		case <-timeout:
+			log.Printf("Timed out waiting for %d publisher confirms", len(pending))
+			healthy = false
+		}
+	}
+
+	for _, event := range pending {
+		unconfirmed = append(unconfirmed, event)
+	}
+
+	// Start over on a fresh channel, so late confirms can't be mistaken for
+	// those of the next batch
+	if !healthy {
+		r.closeChannel()
+	}
+	return confirmed, unconfirmed
+}
+
+func (r *Relay) openChannel() error {
+	if r.channel != nil {
+		return nil
+	}
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return err
+	}
+
+	r.channel = ch
+	r.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, batchSize))
+	r.nextTag = 0
+	return nil
+}
+
+func (r *Relay) closeChannel() {
+	if r.channel != nil {
+		r.channel.Close()
+	}
+	r.channel = nil
+	r.confirms = nil
+}
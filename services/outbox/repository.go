@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Claim(limit int, lease time.Duration) ([]Message, error)
+	MarkSent(ids []uint) error
+	MarkAttempted(batch []Message) error
+	Backlog() (int64, *time.Time, error)
+	DeleteSentBefore(before time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Claim takes up to limit messages that are due to be sent, oldest first,
+// and keeps other relays off them for lease by pushing their next attempt
+// back. The rows are only locked while they are claimed, so publishing them
+// doesn't hold a transaction open. A message whose lease runs out before it
+// is marked is claimed again.
+func (r *repository) Claim(limit int, lease time.Duration) ([]Message, error) {
+	var batch []Message
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND next_attempt_at <= ?", now).
+			Order("id ASC").
+			Limit(limit).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		return tx.Model(&Message{}).Where("id IN ?", messageIDs(batch)).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return batch, err
+}
+
+// MarkSent marks messages the broker confirmed as sent
+func (r *repository) MarkSent(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&Message{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"attempts": gorm.Expr("attempts + 1"),
+			"sent_at":  time.Now(),
+		}).Error
+}
+
+// MarkAttempted records a failed attempt at sending each message in batch
+// and schedules the next one after retryDelay
+func (r *repository) MarkAttempted(batch []Message) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, msg := range batch {
+			attempts := msg.Attempts + 1
+			err := tx.Model(&Message{}).Where("id = ?", msg.ID).
+				Updates(map[string]interface{}{
+					"attempts":        attempts,
+					"next_attempt_at": now.Add(retryDelay(attempts)),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Backlog returns how many messages are waiting to be sent and when the
+// oldest of them was written, or nil if there are none
+func (r *repository) Backlog() (int64, *time.Time, error) {
+	var backlog struct {
+		Waiting int64
+		Oldest  *time.Time
+	}
+	err := r.db.Model(&Message{}).
+		Select("COUNT(*) AS waiting, MIN(created_at) AS oldest").
+		Where("sent_at IS NULL").
+		Scan(&backlog).Error
+	return backlog.Waiting, backlog.Oldest, err
+}
+
+func messageIDs(batch []Message) []uint {
+	ids := make([]uint, len(batch))
+	for i, msg := range batch {
+		ids[i] = msg.ID
+	}
+	return ids
+}
+
+// DeleteSentBefore removes messages that were sent before the given time
+func (r *repository) DeleteSentBefore(before time.Time) (int64, error) {
+	result := r.db.Where("sent_at < ?", before).Delete(&Message{})
+	return result.RowsAffected, result.Error
+}
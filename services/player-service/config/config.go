@@ -5,30 +5,40 @@ import (
 )
 
 type Config struct {
-	DBHost        string
-	DBPort        string
-	DBName        string
-	DBUser        string
-	DBPassword    string
-	RedisHost     string
-	RedisPassword string
-	RabbitMQURL   string
-	ServicePort   string
-	ConsulAddr    string
+	DBHost         string
+	DBPort         string
+	DBName         string
+	DBUser         string
+	DBPassword     string
+	RedisHost      string
+	RedisPassword  string
+	RabbitMQURL    string
+	JWTSecret      string
+	AdminUsername  string
+	TrustedProxies string
+	Notifier       string
+	NotifierFile   string
+	ServicePort    string
+	ConsulAddr     string
 }
 
 func LoadConfig() *Config {
 	c := &Config{
-		DBHost:        getEnv("DB_HOST"),
-		DBPort:        getEnv("DB_PORT"),
-		DBName:        getEnv("DB_NAME"),
-		DBUser:        getEnv("DB_USER"),
-		DBPassword:    getEnv("DB_PASSWORD"),
-		RedisHost:     getEnv("REDIS_HOST"),
-		RedisPassword: getEnv("REDIS_PASSWORD"),
-		RabbitMQURL:   getEnv("RABBITMQ_URL"),
-		ServicePort:   getEnv("SERVICE_PORT"),
-		ConsulAddr:    getEnv("CONSUL_ADDR"),
+		DBHost:         getEnv("DB_HOST"),
+		DBPort:         getEnv("DB_PORT"),
+		DBName:         getEnv("DB_NAME"),
+		DBUser:         getEnv("DB_USER"),
+		DBPassword:     getEnv("DB_PASSWORD"),
+		RedisHost:      getEnv("REDIS_HOST"),
+		RedisPassword:  getEnv("REDIS_PASSWORD"),
+		RabbitMQURL:    getEnv("RABBITMQ_URL"),
+		JWTSecret:      getEnv("JWT_SECRET"),
+		AdminUsername:  getEnv("ADMIN_USERNAME"),
+		TrustedProxies: getEnv("TRUSTED_PROXIES"),
+		Notifier:       getEnv("NOTIFIER"),
+		NotifierFile:   getEnv("NOTIFIER_FILE"),
+		ServicePort:    getEnv("SERVICE_PORT"),
+		ConsulAddr:     getEnv("CONSUL_ADDR"),
 	}
 	return c
 }
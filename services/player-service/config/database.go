@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"maushold/outbox"
 	"maushold/player-service/model"
 
 	"gorm.io/driver/postgres"
@@ -14,17 +15,27 @@ func InitDB(cfg *Config) *gorm.DB {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		// So unique violations come back as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
 	// Auto migrate
-	err = db.AutoMigrate(&model.Player{}, &model.PlayerMonster{})
+	err = db.AutoMigrate(&model.Player{}, &model.PlayerMonster{}, &model.PlayerMonsterMove{}, &model.ProcessedBattle{}, &outbox.Message{}, &model.PasswordReset{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Usernames are unique regardless of case. Without the index two players
+	// registering at once could both get the same name.
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_players_username_lower ON players (LOWER(username))").Error
+	if err != nil {
+		log.Fatal("Failed to create case-insensitive username index:", err)
+	}
+
 	log.Println("Database connected and migrated")
 	return db
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	h := &PlayerHandler{trustedProxies: proxies}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"spoofed header from an untrusted client", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"through the gateway", "10.1.2.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client-supplied hops are skipped", "10.1.2.3:5000", []string{"6.6.6.6, 198.51.100.1"}, "198.51.100.1"},
+		{"through two trusted proxies", "10.1.2.3:5000", []string{"198.51.100.1, 192.168.1.5"}, "198.51.100.1"},
+		{"split across headers", "10.1.2.3:5000", []string{"6.6.6.6", "198.51.100.1"}, "198.51.100.1"},
+		{"gateway without the header", "10.1.2.3:5000", nil, "10.1.2.3"},
+		{"garbage hop", "10.1.2.3:5000", []string{"not-an-ip"}, "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/players/login", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := h.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsGarbage(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8,gateway"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -2,34 +2,47 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"strconv"
 
-	"maushold/player-service/messaging"
+	"maushold/auth"
 	"maushold/player-service/model"
 	"maushold/player-service/service"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 type PlayerHandler struct {
 	playerService        service.PlayerService
 	playerMonsterService service.PlayerMonsterService
-	messageProducer      *messaging.Producer
 	serviceDiscovery     *service.ServiceDiscovery
+	keys                 *auth.Keys
+	trustedProxies       []*net.IPNet
+}
+
+// tokenResponse is returned on login and refresh
+type tokenResponse struct {
+	Player *model.Player `json:"player"`
+	auth.TokenPair
 }
 
 func NewPlayerHandler(
 	playerService service.PlayerService,
 	playerMonsterService service.PlayerMonsterService,
-	messageProducer *messaging.Producer,
 	serviceDiscovery *service.ServiceDiscovery,
+	keys *auth.Keys,
+	trustedProxies []*net.IPNet,
 ) *PlayerHandler {
 	return &PlayerHandler{
 		playerService:        playerService,
 		playerMonsterService: playerMonsterService,
-		messageProducer:      messageProducer,
 		serviceDiscovery:     serviceDiscovery,
+		keys:                 keys,
+		trustedProxies:       trustedProxies,
 	}
 }
 
@@ -56,13 +69,14 @@ func (h *PlayerHandler) CreatePlayer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.playerService.CreatePlayer(&player); err != nil {
+		if errors.Is(err, service.ErrUsernameTaken) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Publish event
-	h.messageProducer.PublishPlayerEvent("player.created", player)
-
 	respondJSON(w, http.StatusCreated, player)
 }
 
@@ -90,10 +104,7 @@ func (h *PlayerHandler) UpdatePlayer(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid player ID")
 		return
 	}
-
-	player, err := h.playerService.GetPlayer(uint(id))
-	if err != nil {
-		respondError(w, http.StatusNotFound, "Player not found")
+	if !auth.RequirePlayerOr(w, r, uint(id), auth.RoleModerator) {
 		return
 	}
 
@@ -103,17 +114,83 @@ func (h *PlayerHandler) UpdatePlayer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Apply updates
-	if username, ok := updates["username"].(string); ok {
-		player.Username = username
+	// The username is the only thing players can change here
+	username, ok := updates["username"].(string)
+	if !ok {
+		player, err := h.playerService.GetPlayer(uint(id))
+		if err != nil {
+			respondError(w, http.StatusNotFound, "Player not found")
+			return
+		}
+		respondJSON(w, http.StatusOK, player)
+		return
 	}
 
-	if err := h.playerService.UpdatePlayer(player); err != nil {
+	player, err := h.playerService.UpdatePlayer(uint(id), username)
+	if err != nil {
+		if errors.Is(err, service.ErrUsernameTaken) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, "Player not found")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.messageProducer.PublishPlayerEvent("player.updated", player)
+	respondJSON(w, http.StatusOK, player)
+}
+
+// SetPlayerRole changes a player's role and logs them out everywhere, so
+// tokens with the old role stop working. Admins can't change their own, so
+// there is always at least one left.
+func (h *PlayerHandler) SetPlayerRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Role must be player, moderator or admin")
+		return
+	}
+
+	if claims, _ := auth.FromContext(r.Context()); claims.PlayerID == uint(id) {
+		respondError(w, http.StatusForbidden, "Admins can't change their own role")
+		return
+	}
+
+	if err := h.playerService.SetPlayerRole(uint(id), role); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, "Player not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := h.keys.Sessions().RevokeAll(uint(id)); err != nil {
+		log.Printf("Failed to revoke sessions of player %d: %v", id, err)
+	}
+
+	player, err := h.playerService.GetPlayer(uint(id))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	respondJSON(w, http.StatusOK, player)
 }
@@ -135,13 +212,18 @@ func (h *PlayerHandler) DeletePlayer(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid player ID")
 		return
 	}
+	if !auth.RequirePlayerOr(w, r, uint(id), auth.RoleModerator) {
+		return
+	}
 
 	if err := h.playerService.DeletePlayer(uint(id)); err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.messageProducer.PublishPlayerEvent("player.deleted", map[string]interface{}{"id": id})
+	if err := h.keys.Sessions().RevokeAll(uint(id)); err != nil {
+		log.Printf("Failed to revoke sessions of deleted player %d: %v", id, err)
+	}
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Player deleted successfully"})
 }
@@ -163,13 +245,276 @@ func (h *PlayerHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	player, err := h.playerService.AuthenticatePlayer(req.Username, req.Password)
+	player, err := h.playerService.AuthenticatePlayer(req.Username, req.Password, h.clientIP(r))
 	if err != nil {
-		respondError(w, http.StatusUnauthorized, "Invalid username or password")
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			respondError(w, http.StatusUnauthorized, "Invalid username or password")
+		case errors.Is(err, service.ErrLoginLocked):
+			respondError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	respondJSON(w, http.StatusOK, player)
+	h.respondTokens(w, r, player, nil)
+}
+
+// UnlockPlayer lets a player log in again after too many failed logins.
+// Each ?ip= also lifts the lockout of an address, for a player caught
+// behind one that was locked out.
+func (h *PlayerHandler) UnlockPlayer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+
+	ips := r.URL.Query()["ip"]
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			respondError(w, http.StatusBadRequest, "Invalid IP address")
+			return
+		}
+	}
+
+	if err := h.playerService.UnlockPlayer(uint(id), ips...); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, "Player not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Player unlocked"})
+}
+
+// RefreshToken swaps a refresh token for a new token pair. Each refresh token
+// works once.
+func (h *PlayerHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	claims, session, err := h.keys.Refresh(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		case errors.Is(err, auth.ErrSessionNotFound):
+			respondError(w, http.StatusUnauthorized, "Session has been revoked")
+		case errors.Is(err, auth.ErrSessionReused):
+			respondError(w, http.StatusUnauthorized, "Refresh token was already used, all sessions have been revoked")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// The player may have been deleted since the token was issued
+	player, err := h.playerService.GetPlayer(claims.PlayerID)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	}
+
+	h.respondTokens(w, r, player, session)
+}
+
+// Logout ends the caller's session, so none of its tokens work any more
+func (h *PlayerHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.RequireCaller(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.keys.Sessions().Revoke(claims.PlayerID, claims.SessionID)
+	if err != nil && !errors.Is(err, auth.ErrSessionNotFound) {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// ChangePassword sets a new password for a player who knows their current
+// one, and logs out their other sessions
+func (h *PlayerHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := h.playerService.ChangePassword(uint(id), req.OldPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasswordRequired):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrWrongPassword):
+			respondError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			respondError(w, http.StatusNotFound, "Player not found")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	if err := h.keys.Sessions().RevokeAll(uint(id), claims.SessionID); err != nil {
+		log.Printf("Failed to revoke sessions of player %d: %v", id, err)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Password changed"})
+}
+
+// RequestPasswordReset sends a player a password reset token. It answers
+// the same whether or not the player exists.
+func (h *PlayerHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		respondError(w, http.StatusBadRequest, "Username is required")
+		return
+	}
+
+	if err := h.playerService.RequestPasswordReset(req.Username); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to send password reset")
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the player exists, a password reset token has been sent",
+	})
+}
+
+// ResetPassword sets a new password with a reset token and logs the player
+// out everywhere
+func (h *PlayerHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	id, err := h.playerService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasswordRequired), errors.Is(err, service.ErrResetTokenInvalid):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if err := h.keys.Sessions().RevokeAll(id); err != nil {
+		log.Printf("Failed to revoke sessions of player %d: %v", id, err)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Password reset"})
+}
+
+// GetSessions lists the devices a player is logged in on
+func (h *PlayerHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
+
+	sessions, err := h.keys.Sessions().List(uint(id))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	respondJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession logs a player out of one device, or every device if no
+// session is given
+func (h *PlayerHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
+
+	sessions := h.keys.Sessions()
+	if sessionID, ok := vars["sid"]; ok {
+		err = sessions.Revoke(uint(id), sessionID)
+	} else {
+		err = sessions.RevokeAll(uint(id))
+	}
+	if err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			respondError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+// respondTokens issues tokens for a session, starting a new one if there is
+// none yet
+func (h *PlayerHandler) respondTokens(w http.ResponseWriter, r *http.Request, player *model.Player, session *auth.Session) {
+	if session == nil {
+		var err error
+		session, err = h.keys.Sessions().Start(player.ID, r.UserAgent(), h.clientIP(r))
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to start session")
+			return
+		}
+	}
+
+	tokens, err := h.keys.Issue(player.ID, player.Username, auth.Role(player.Role), session.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to issue tokens")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tokenResponse{Player: player, TokenPair: *tokens})
 }
 
 func (h *PlayerHandler) GetMonsterInfo(w http.ResponseWriter, r *http.Request) {
@@ -223,6 +568,9 @@ func (h *PlayerHandler) AddMonsterToPlayer(w http.ResponseWriter, r *http.Reques
 		respondError(w, http.StatusBadRequest, "Invalid player ID")
 		return
 	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
 
 	var monster model.PlayerMonster
 	if err := json.NewDecoder(r.Body).Decode(&monster); err != nil {
@@ -232,15 +580,58 @@ func (h *PlayerHandler) AddMonsterToPlayer(w http.ResponseWriter, r *http.Reques
 
 	monster.PlayerID = uint(id)
 	if err := h.playerMonsterService.AddMonsterToPlayer(&monster); err != nil {
+		if errors.Is(err, service.ErrInvalidMoveSet) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.messageProducer.PublishPlayerEvent("player.monster.added", monster)
-
 	respondJSON(w, http.StatusCreated, monster)
 }
 
+func (h *PlayerHandler) SetMonsterMoves(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+	if !auth.RequirePlayer(w, r, uint(id)) {
+		return
+	}
+
+	monsterID, err := strconv.ParseUint(vars["monsterId"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid monster ID")
+		return
+	}
+
+	var req struct {
+		MoveIDs []uint `json:"move_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	monster, err := h.playerMonsterService.SetMonsterMoves(uint(id), uint(monsterID), req.MoveIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMoveSet):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrMonsterNotOwned), errors.Is(err, gorm.ErrRecordNotFound):
+			respondError(w, http.StatusNotFound, "Monster not found")
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, monster)
+}
+
 func (h *PlayerHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "player-service"})
 }
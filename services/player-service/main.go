@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 
+	"maushold/outbox"
 	"maushold/player-service/config"
 	"maushold/player-service/handler"
 	"maushold/player-service/messaging"
@@ -26,9 +27,11 @@ func main() {
 	redisClient := config.InitRedis(cfg)
 
 	// Initialize RabbitMQ
-	rabbitConn, rabbitCh := config.InitRabbitMQ(cfg)
+	rabbitConn := config.InitRabbitMQ(cfg)
 	defer rabbitConn.Close()
-	defer rabbitCh.Close()
+
+	// Initialize token signing
+	authKeys := config.InitAuth(cfg, redisClient)
 
 	// Initialize Consul
 	consulClient := config.InitConsul(cfg)
@@ -47,23 +50,47 @@ func main() {
 	playerRepo := repository.NewPlayerRepository(db)
 	playerMonsterRepo := repository.NewPlayerMonsterRepository(db)
 
+	// Initialize clients
+	monsterClient := service.NewMonsterClient(serviceDiscovery)
+
 	// Initialize services
-	playerService := service.NewPlayerService(playerRepo, redisClient)
-	playerMonsterService := service.NewPlayerMonsterService(playerMonsterRepo, redisClient)
+	notifier, err := service.NewNotifier(cfg.Notifier, cfg.NotifierFile)
+	if err != nil {
+		log.Fatal("Invalid NOTIFIER:", err)
+	}
+	playerService := service.NewPlayerService(playerRepo, repository.NewPasswordResetRepository(db), notifier, redisClient)
+	playerMonsterService := service.NewPlayerMonsterService(playerMonsterRepo, monsterClient, redisClient)
+
+	// Make sure someone can hand out roles on a fresh install
+	if cfg.AdminUsername != "" {
+		if err := playerService.BootstrapAdmin(cfg.AdminUsername); err != nil {
+			log.Printf("Failed to make %s an admin: %v", cfg.AdminUsername, err)
+		}
+	}
 
 	// Initialize messaging
-	messageProducer := messaging.NewProducer(rabbitCh)
-	messageConsumer := messaging.NewConsumer(rabbitCh, playerService)
+	messageConsumer := messaging.NewConsumer(rabbitConn, playerService)
 
 	// Start consuming messages
-	go messageConsumer.Start()
+	messageConsumer.Start()
+
+	// Forget applied battles once they can no longer be redelivered
+	go playerService.StartPeriodicCleanup()
+
+	// Publish events written to the outbox
+	outboxRelay := outbox.NewRelay(rabbitConn, outbox.NewRepository(db), "player.events")
+	go outboxRelay.Start()
 
 	// Initialize handlers
-	playerHandler := handler.NewPlayerHandler(playerService, playerMonsterService, messageProducer, serviceDiscovery)
+	trustedProxies, err := handler.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	playerHandler := handler.NewPlayerHandler(playerService, playerMonsterService, serviceDiscovery, authKeys, trustedProxies)
 
 	// Setup routes
 	router := mux.NewRouter()
-	routes.SetupPlayerRoutes(router, playerHandler)
+	routes.SetupPlayerRoutes(router, playerHandler, authKeys)
 
 	// Start server
 	port := os.Getenv("SERVICE_PORT")
@@ -1,71 +1,85 @@
 package messaging
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 
+	"maushold/events"
+	"maushold/player-service/repository"
 	"maushold/player-service/service"
+	"maushold/rabbitmq"
 
 	"github.com/streadway/amqp"
 )
 
+const (
+	updatesQueue = "player.updates"
+	retryQueue   = "player.updates.retry" // dead-letters back to updatesQueue after a delay
+	deadQueue    = "player.updates.dlq"
+
+	maxRetries    = 3
+	prefetchCount = 10
+)
+
+var retryPolicy = rabbitmq.RetryPolicy{
+	RetryQueue: retryQueue,
+	DeadQueue:  deadQueue,
+	MaxRetries: maxRetries,
+}
+
 type Consumer struct {
-	channel       *amqp.Channel
+	conn          *rabbitmq.Connection
 	playerService service.PlayerService
 }
 
-func NewConsumer(channel *amqp.Channel, playerService service.PlayerService) *Consumer {
+func NewConsumer(conn *rabbitmq.Connection, playerService service.PlayerService) *Consumer {
 	return &Consumer{
-		channel:       channel,
+		conn:          conn,
 		playerService: playerService,
 	}
 }
 
+// Start subscribes to updatesQueue, again after every reconnect
 func (c *Consumer) Start() {
-	msgs, err := c.channel.Consume(
-		"player.updates",
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
+	if err := c.conn.Consume(updatesQueue, prefetchCount, c.consume); err != nil {
 		log.Fatal("Failed to register consumer:", err)
 	}
 
 	log.Println("Listening for messages...")
+}
 
+// consume handles deliveries until the channel they arrive on closes
+func (c *Consumer) consume(ch *amqp.Channel, msgs <-chan amqp.Delivery) {
 	for msg := range msgs {
-		log.Printf("Received message: %s", msg.RoutingKey)
+		routingKey := rabbitmq.RoutingKey(msg)
+		log.Printf("Received message: %s", routingKey)
 
-		switch msg.RoutingKey {
-		case "battle.completed":
-			c.handleBattleCompleted(msg.Body)
+		var err error
+		switch routingKey {
+		case events.BattleCompletedKey:
+			err = c.handleBattleCompleted(msg.Body, events.HeaderVersion(msg.Headers))
 		}
+		retryPolicy.Settle(ch, msg, err)
 	}
 }
 
-func (c *Consumer) handleBattleCompleted(body []byte) {
-	var event map[string]interface{}
-	if err := json.Unmarshal(body, &event); err != nil {
-		log.Printf("Error parsing battle event: %v", err)
-		return
+func (c *Consumer) handleBattleCompleted(body []byte, version int) error {
+	var event events.BattleCompleted
+	if err := events.Decode(body, version, &event); err != nil {
+		return fmt.Errorf("%w: %v", rabbitmq.ErrMalformed, err)
 	}
 
 	// Update player points based on battle result
-	if winnerID, ok := event["winner_id"].(float64); ok {
-		if pointsWon, ok := event["points_won"].(float64); ok {
-			c.playerService.UpdatePlayerPoints(uint(winnerID), int(pointsWon))
-		}
+	err := c.playerService.ApplyBattleResult(event.BattleID, event.WinnerID, event.PointsWon, event.LoserID, event.PointsLost)
+	if errors.Is(err, repository.ErrBattleAlreadyApplied) {
+		log.Printf("Skipping battle %d, already applied", event.BattleID)
+		return nil
 	}
-
-	if loserID, ok := event["loser_id"].(float64); ok {
-		if pointsLost, ok := event["points_lost"].(float64); ok {
-			c.playerService.UpdatePlayerPoints(uint(loserID), -int(pointsLost))
-		}
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Battle completed event processed: %v", event)
+	log.Printf("Battle completed event processed: battle %d", event.BattleID)
+	return nil
 }
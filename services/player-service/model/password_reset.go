@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PasswordReset is a single-use token a player can set a new password with.
+// Only a hash of the token is stored, so a leaked table can't be used to
+// take over accounts.
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey"`
+	PlayerID  uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set once redeemed, or superseded by a newer token
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
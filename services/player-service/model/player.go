@@ -7,20 +7,74 @@ type Player struct {
 	Username  string    `gorm:"unique;not null" json:"username"`
 	Password  string    `gorm:"not null" json:"-"` // "-" means don't include in JSON responses
 	Points    int       `gorm:"default:0" json:"points"`
+	Role      string    `gorm:"size:16;not null;default:player" json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ProcessedBattle marks a battle.completed event as applied, so a
+// redelivered event doesn't count the same battle twice. Marks are pruned
+// once no redelivery can still be coming.
+type ProcessedBattle struct {
+	BattleID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	ProcessedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
 type PlayerMonster struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	PlayerID   uint      `gorm:"not null;index" json:"player_id"`
-	MonsterID  int       `gorm:"not null" json:"monster_id"`
-	Nickname   string    `gorm:"size:255" json:"nickname"`
-	Level      int       `gorm:"default:1" json:"level"`
-	Experience int       `gorm:"default:0" json:"experience"`
-	HP         int       `gorm:"not null" json:"hp"`
-	Attack     int       `gorm:"not null" json:"attack"`
-	Defense    int       `gorm:"not null" json:"defense"`
-	Speed      int       `gorm:"not null" json:"speed"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	PlayerID   uint                `gorm:"not null;index" json:"player_id"`
+	MonsterID  int                 `gorm:"not null" json:"monster_id"`
+	Nickname   string              `gorm:"size:255" json:"nickname"`
+	Type1      string              `gorm:"size:32" json:"type1"`
+	Type2      string              `gorm:"size:32" json:"type2"`
+	Level      int                 `gorm:"default:1" json:"level"`
+	Experience int                 `gorm:"default:0" json:"experience"`
+	HP         int                 `gorm:"not null" json:"hp"`
+	Attack     int                 `gorm:"not null" json:"attack"`
+	Defense    int                 `gorm:"not null" json:"defense"`
+	Speed      int                 `gorm:"not null" json:"speed"`
+	Moves      []PlayerMonsterMove `gorm:"foreignKey:PlayerMonsterID;constraint:OnDelete:CASCADE" json:"moves"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// PlayerMonsterMove is one of up to four move slots on a player's monster
+type PlayerMonsterMove struct {
+	ID              uint `gorm:"primaryKey" json:"-"`
+	PlayerMonsterID uint `gorm:"not null;index" json:"-"`
+	MoveID          uint `gorm:"not null" json:"move_id"`
+	Slot            int  `gorm:"not null" json:"slot"`
+}
+
+// Monster is a species from monster-service's catalog
+type Monster struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Type1       string `json:"type1"`
+	Type2       string `json:"type2"`
+	BaseHP      int    `json:"base_hp"`
+	BaseAttack  int    `json:"base_attack"`
+	BaseDefense int    `json:"base_defense"`
+	BaseSpeed   int    `json:"base_speed"`
+}
+
+// Move is a move from monster-service's catalog
+type Move struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Category     string `json:"category"`
+	Power        int    `json:"power"`
+	Accuracy     int    `json:"accuracy"`
+	PP           int    `json:"pp"`
+	Priority     int    `json:"priority"`
+	Effect       string `json:"effect"`
+	EffectChance int    `json:"effect_chance"`
+}
+
+// LearnsetEntry is a move a species can learn at or above Level
+type LearnsetEntry struct {
+	MonsterID int  `json:"monster_id"`
+	MoveID    uint `json:"move_id"`
+	Level     int  `json:"level"`
+	Move      Move `json:"move"`
 }
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"maushold/player-service/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrResetTokenInvalid = errors.New("invalid or expired reset token")
+
+type PasswordResetRepository interface {
+	Create(reset *model.PasswordReset) error
+	Redeem(tokenHash, passwordHash string) (uint, error)
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create saves a new reset token and supersedes the player's unused ones in
+// the same transaction, so only the latest token sent can be redeemed
+func (r *passwordResetRepository) Create(reset *model.PasswordReset) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.PasswordReset{}).
+			Where("player_id = ? AND used_at IS NULL", reset.PlayerID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+// Redeem sets a player's password with an unused, unexpired reset token and
+// uses up every reset token of theirs, in one transaction. It returns the
+// player's ID, or ErrResetTokenInvalid.
+func (r *passwordResetRepository) Redeem(tokenHash, passwordHash string) (uint, error) {
+	var playerID uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var reset model.PasswordReset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrResetTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.Player{}).Where("id = ?", reset.PlayerID).Update("password", passwordHash)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The player was deleted
+			return ErrResetTokenInvalid
+		}
+
+		err = tx.Model(&model.PasswordReset{}).
+			Where("player_id = ? AND used_at IS NULL", reset.PlayerID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		playerID = reset.PlayerID
+		return nil
+	})
+	return playerID, err
+}
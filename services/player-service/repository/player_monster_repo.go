@@ -1,15 +1,18 @@
 package repository
 
 import (
+	"maushold/outbox"
 	"maushold/player-service/model"
 
 	"gorm.io/gorm"
 )
 
 type PlayerMonsterRepository interface {
-	Create(monster *model.PlayerMonster) error
+	Create(monster *model.PlayerMonster, events ...outbox.Pending) error
 	FindByPlayerID(playerID uint) ([]model.PlayerMonster, error)
 	FindByID(id uint) (*model.PlayerMonster, error)
+	Update(monster *model.PlayerMonster) error
+	SetMoves(monsterID uint, moves []model.PlayerMonsterMove, events ...outbox.Pending) error
 }
 
 type playerMonsterRepository struct {
@@ -20,18 +23,53 @@ func NewPlayerMonsterRepository(db *gorm.DB) PlayerMonsterRepository {
 	return &playerMonsterRepository{db: db}
 }
 
-func (r *playerMonsterRepository) Create(monster *model.PlayerMonster) error {
-	return r.db.Create(monster).Error
+// Create adds a monster with its moves, writing any events to the outbox in
+// the same transaction
+func (r *playerMonsterRepository) Create(monster *model.PlayerMonster, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(monster).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
 }
 
 func (r *playerMonsterRepository) FindByPlayerID(playerID uint) ([]model.PlayerMonster, error) {
 	var monster []model.PlayerMonster
-	err := r.db.Where("player_id = ?", playerID).Find(&monster).Error
+	err := r.db.Preload("Moves", orderBySlot).Where("player_id = ?", playerID).Find(&monster).Error
 	return monster, err
 }
 
 func (r *playerMonsterRepository) FindByID(id uint) (*model.PlayerMonster, error) {
 	var monster model.PlayerMonster
-	err := r.db.First(&monster, id).Error
+	err := r.db.Preload("Moves", orderBySlot).First(&monster, id).Error
 	return &monster, err
 }
+
+func (r *playerMonsterRepository) Update(monster *model.PlayerMonster) error {
+	return r.db.Save(monster).Error
+}
+
+// SetMoves replaces a monster's move slots, writing any events to the outbox
+// in the same transaction
+func (r *playerMonsterRepository) SetMoves(monsterID uint, moves []model.PlayerMonsterMove, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("player_monster_id = ?", monsterID).Delete(&model.PlayerMonsterMove{}).Error; err != nil {
+			return err
+		}
+
+		if len(moves) > 0 {
+			for i := range moves {
+				moves[i].PlayerMonsterID = monsterID
+			}
+			if err := tx.Create(&moves).Error; err != nil {
+				return err
+			}
+		}
+		return outbox.Add(tx, events)
+	})
+}
+
+func orderBySlot(db *gorm.DB) *gorm.DB {
+	return db.Order("slot ASC")
+}
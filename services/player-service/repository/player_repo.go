@@ -1,18 +1,31 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
+	"maushold/outbox"
 	"maushold/player-service/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrBattleAlreadyApplied = errors.New("battle already applied")
+
 type PlayerRepository interface {
-	Create(player *model.Player) error
+	Create(player *model.Player, events ...outbox.Pending) error
 	FindByID(id uint) (*model.Player, error)
-	Update(player *model.Player) error
+	FindByUsername(username string) (*model.Player, error)
+	Update(player *model.Player, columns map[string]interface{}, events ...outbox.Pending) error
 	FindAll() ([]model.Player, error)
 	UpdatePoints(id uint, points int) error
-	Delete(player *model.Player) error
+	UpdateRole(id uint, role string) error
+	UpdatePassword(id uint, passwordHash string) error
+	GrantFirstRole(username, role string) (uint, error)
+	Delete(player *model.Player, events ...outbox.Pending) error
+	ApplyBattleResult(battleID, winnerID uint, pointsWon int, loserID uint, pointsLost int) error
+	DeleteProcessedBattlesBefore(before time.Time) (int64, error)
 }
 
 type playerRepository struct {
@@ -23,8 +36,15 @@ func NewPlayerRepository(db *gorm.DB) PlayerRepository {
 	return &playerRepository{db: db}
 }
 
-func (r *playerRepository) Create(player *model.Player) error {
-	return r.db.Create(player).Error
+// Create, Update and Delete write any events to the outbox in the same
+// transaction as the change
+func (r *playerRepository) Create(player *model.Player, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(player).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
 }
 
 func (r *playerRepository) FindByID(id uint) (*model.Player, error) {
@@ -33,8 +53,30 @@ func (r *playerRepository) FindByID(id uint) (*model.Player, error) {
 	return &player, err
 }
 
-func (r *playerRepository) Update(player *model.Player) error {
-	return r.db.Save(player).Error
+// FindByUsername ignores case, as usernames are unique regardless of it
+func (r *playerRepository) FindByUsername(username string) (*model.Player, error) {
+	var player model.Player
+	err := r.db.Where("LOWER(username) = LOWER(?)", username).First(&player).Error
+	return &player, err
+}
+
+// Update sets only the given columns of the player with player.ID, so a
+// change made from a stale copy can't write back anything else, then reloads
+// player, all but its password, before its events are written
+func (r *playerRepository) Update(player *model.Player, columns map[string]interface{}, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Player{}).Where("id = ?", player.ID).Updates(columns)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Omit("password").First(player, player.ID).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
 }
 
 func (r *playerRepository) FindAll() ([]model.Player, error) {
@@ -47,6 +89,93 @@ func (r *playerRepository) UpdatePoints(id uint, points int) error {
 	return r.db.Model(&model.Player{}).Where("id = ?", id).Update("points", points).Error
 }
 
-func (r *playerRepository) Delete(player *model.Player) error {
-	return r.db.Delete(player).Error
+func (r *playerRepository) UpdatePassword(id uint, passwordHash string) error {
+	result := r.db.Model(&model.Player{}).Where("id = ?", id).Update("password", passwordHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *playerRepository) UpdateRole(id uint, role string) error {
+	return r.updateRole(r.db.Where("id = ?", id), role)
+}
+
+// GrantFirstRole gives the named player role if no player has it yet,
+// returning their ID, or 0 if someone already had it. Usernames match in any
+// case.
+func (r *playerRepository) GrantFirstRole(username, role string) (uint, error) {
+	var id uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var held int64
+		if err := tx.Model(&model.Player{}).Where("role = ?", role).Count(&held).Error; err != nil {
+			return err
+		}
+		if held > 0 {
+			return nil
+		}
+
+		var player model.Player
+		if err := tx.Where("LOWER(username) = LOWER(?)", username).First(&player).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&player).Update("role", role).Error; err != nil {
+			return err
+		}
+		id = player.ID
+		return nil
+	})
+	return id, err
+}
+
+// updateRole returns gorm.ErrRecordNotFound if no player matched
+func (r *playerRepository) updateRole(query *gorm.DB, role string) error {
+	result := query.Model(&model.Player{}).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *playerRepository) Delete(player *model.Player, events ...outbox.Pending) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(player).Error; err != nil {
+			return err
+		}
+		return outbox.Add(tx, events)
+	})
+}
+
+// ApplyBattleResult moves a battle's points between the winner and loser and
+// marks the battle processed in one transaction. A battle that was already
+// processed changes nothing and returns ErrBattleAlreadyApplied.
+func (r *playerRepository) ApplyBattleResult(battleID, winnerID uint, pointsWon int, loserID uint, pointsLost int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ProcessedBattle{BattleID: battleID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBattleAlreadyApplied
+		}
+
+		if err := tx.Model(&model.Player{}).Where("id = ?", winnerID).
+			Update("points", gorm.Expr("points + ?", pointsWon)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Player{}).Where("id = ?", loserID).
+			Update("points", gorm.Expr("points - ?", pointsLost)).Error
+	})
+}
+
+// DeleteProcessedBattlesBefore forgets battles applied before the given time
+func (r *playerRepository) DeleteProcessedBattlesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("processed_at < ?", before).Delete(&model.ProcessedBattle{})
+	return result.RowsAffected, result.Error
 }
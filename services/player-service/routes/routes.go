@@ -3,20 +3,40 @@ package routes
 import (
 	"net/http"
 
+	"maushold/auth"
 	"maushold/player-service/handler"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupPlayerRoutes(router *mux.Router, handler *handler.PlayerHandler) {
+func SetupPlayerRoutes(router *mux.Router, handler *handler.PlayerHandler, keys *auth.Keys) {
+	router.Use(keys.Middleware(
+		"POST /players/login",
+		"POST /players/refresh",
+		"POST /players",
+		"POST /players/password-reset",
+		"POST /players/password-reset/confirm",
+	))
+
 	// API routes
 	router.HandleFunc("/players/login", handler.Login).Methods(http.MethodPost)
+	router.HandleFunc("/players/refresh", handler.RefreshToken).Methods(http.MethodPost)
+	router.HandleFunc("/players/logout", handler.Logout).Methods(http.MethodPost)
+	router.HandleFunc("/players/password-reset", handler.RequestPasswordReset).Methods(http.MethodPost)
+	router.HandleFunc("/players/password-reset/confirm", handler.ResetPassword).Methods(http.MethodPost)
 	router.HandleFunc("/players", handler.CreatePlayer).Methods(http.MethodPost)
 	router.HandleFunc("/players/{id}", handler.GetPlayer).Methods(http.MethodGet)
 	router.HandleFunc("/players/{id}", handler.UpdatePlayer).Methods(http.MethodPut)
 	router.HandleFunc("/players/{id}", handler.DeletePlayer).Methods(http.MethodDelete)
+	router.HandleFunc("/players/{id}/password", handler.ChangePassword).Methods(http.MethodPost)
+	router.HandleFunc("/players/{id}/sessions", handler.GetSessions).Methods(http.MethodGet)
+	router.HandleFunc("/players/{id}/sessions", handler.RevokeSession).Methods(http.MethodDelete)
+	router.HandleFunc("/players/{id}/sessions/{sid}", handler.RevokeSession).Methods(http.MethodDelete)
+	router.HandleFunc("/players/{id}/lockout", auth.RequireRole(auth.RoleAdmin, handler.UnlockPlayer)).Methods(http.MethodDelete)
+	router.HandleFunc("/players/{id}/role", auth.RequireRole(auth.RoleAdmin, handler.SetPlayerRole)).Methods(http.MethodPut)
 	router.HandleFunc("/players", handler.GetAllPlayers).Methods(http.MethodGet)
 	router.HandleFunc("/players/{id}/monster", handler.GetPlayerMonster).Methods(http.MethodGet)
 	router.HandleFunc("/players/{id}/monster", handler.AddMonsterToPlayer).Methods(http.MethodPost)
+	router.HandleFunc("/players/{id}/monster/{monsterId}/moves", handler.SetMonsterMoves).Methods(http.MethodPut)
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
 }
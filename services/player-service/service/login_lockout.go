@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Failed logins are counted per account and per IP. Once either reaches its
+// limit within FailedLoginWindow, logins from it are refused until
+// LockoutDuration after the last failure.
+const (
+	MaxFailedLoginsPerAccount = 5
+	MaxFailedLoginsPerIP      = 20
+	FailedLoginWindow         = 15 * time.Minute
+	LockoutDuration           = 15 * time.Minute
+
+	failedLoginAccountKey = "login:failed:account:%s" // lowercased username
+	failedLoginIPKey      = "login:failed:ip:%s"
+)
+
+// lockedFor returns how much longer logins for a username or from an IP are
+// refused, or zero if they aren't
+func (s *playerService) lockedFor(username, ip string) (time.Duration, error) {
+	keys := []string{accountKey(username), fmt.Sprintf(failedLoginIPKey, ip)}
+	limits := []int{MaxFailedLoginsPerAccount, MaxFailedLoginsPerIP}
+
+	pipe := s.redis.Pipeline()
+	counts := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		counts[i] = pipe.Get(s.ctx, key)
+		ttls[i] = pipe.TTL(s.ctx, key)
+	}
+	if _, err := pipe.Exec(s.ctx); err != nil && err != redis.Nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for i := range keys {
+		if count, _ := counts[i].Int(); count >= limits[i] {
+			wait = max(wait, ttls[i].Val())
+		}
+	}
+	return wait, nil
+}
+
+// recordFailedLogin counts a failed login against a username and an IP. The
+// count expires FailedLoginWindow after the first failure, or
+// LockoutDuration after the one that reaches the limit.
+func (s *playerService) recordFailedLogin(username, ip string) error {
+	pipe := s.redis.TxPipeline()
+	account := pipe.Incr(s.ctx, accountKey(username))
+	byIP := pipe.Incr(s.ctx, fmt.Sprintf(failedLoginIPKey, ip))
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		return err
+	}
+
+	s.extendLockout(accountKey(username), account.Val(), MaxFailedLoginsPerAccount)
+	s.extendLockout(fmt.Sprintf(failedLoginIPKey, ip), byIP.Val(), MaxFailedLoginsPerIP)
+	return nil
+}
+
+func (s *playerService) extendLockout(key string, count int64, limit int) {
+	switch {
+	case count == 1:
+		s.redis.Expire(s.ctx, key, FailedLoginWindow)
+	case count >= int64(limit):
+		s.redis.Expire(s.ctx, key, LockoutDuration)
+	}
+}
+
+// clearFailedLogins forgets an account's failed logins, and those from any
+// IPs given
+func (s *playerService) clearFailedLogins(username string, ips ...string) error {
+	keys := []string{accountKey(username)}
+	for _, ip := range ips {
+		keys = append(keys, fmt.Sprintf(failedLoginIPKey, ip))
+	}
+	return s.redis.Del(s.ctx, keys...).Err()
+}
+
+func accountKey(username string) string {
+	return fmt.Sprintf(failedLoginAccountKey, strings.ToLower(username))
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"maushold/player-service/model"
+)
+
+type MonsterClient struct {
+	serviceDiscovery *ServiceDiscovery
+}
+
+func NewMonsterClient(serviceDiscovery *ServiceDiscovery) *MonsterClient {
+	return &MonsterClient{serviceDiscovery: serviceDiscovery}
+}
+
+// GetMonster fetches a species from monster-service's catalog
+func (c *MonsterClient) GetMonster(monsterID int) (*model.Monster, error) {
+	baseURL, err := c.serviceDiscovery.DiscoverService("monster-service")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/monster/%d", baseURL, monsterID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("monster %d not found", monsterID)
+	}
+
+	var monster model.Monster
+	if err := json.NewDecoder(resp.Body).Decode(&monster); err != nil {
+		return nil, err
+	}
+
+	return &monster, nil
+}
+
+// GetLearnset fetches the moves a species can learn, ordered by level
+func (c *MonsterClient) GetLearnset(monsterID int) ([]model.LearnsetEntry, error) {
+	baseURL, err := c.serviceDiscovery.DiscoverService("monster-service")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/monster/%d/moves", baseURL, monsterID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("learnset for monster %d not found", monsterID)
+	}
+
+	var learnset []model.LearnsetEntry
+	if err := json.NewDecoder(resp.Body).Decode(&learnset); err != nil {
+		return nil, err
+	}
+
+	return learnset, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"maushold/player-service/model"
+)
+
+const (
+	NotifierLog  = "log"
+	NotifierFile = "file"
+)
+
+// Notification is a message for a player about their account
+type Notification struct {
+	PlayerID  uint      `json:"player_id"`
+	Username  string    `json:"username"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notifier delivers notifications to players. Email would go here; until
+// then the log and file notifiers stand in for it.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// NewNotifier returns the notifier of the given kind. The file notifier
+// appends to path.
+func NewNotifier(kind, path string) (Notifier, error) {
+	switch kind {
+	case "", NotifierLog:
+		return logNotifier{}, nil
+	case NotifierFile:
+		if path == "" {
+			return nil, fmt.Errorf("file notifier needs a path")
+		}
+		return &fileNotifier{path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q, expected %s or %s", kind, NotifierLog, NotifierFile)
+}
+
+func newNotification(player *model.Player, subject, body string) Notification {
+	return Notification{
+		PlayerID:  player.ID,
+		Username:  player.Username,
+		Subject:   subject,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+}
+
+// logNotifier writes notifications to the service log, reset tokens
+// included, so it is only fit for development
+type logNotifier struct{}
+
+func (logNotifier) Notify(n Notification) error {
+	log.Printf("Notification for %s (player %d): %s: %s", n.Username, n.PlayerID, n.Subject, n.Body)
+	return nil
+}
+
+// fileNotifier appends notifications to a file as JSON lines, so tests and
+// tools can read them back
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (f *fileNotifier) Notify(n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
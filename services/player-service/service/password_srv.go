@@ -0,0 +1,164 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"maushold/player-service/model"
+	"maushold/player-service/repository"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// PasswordResetTTL is how long a password reset token can be used for
+	PasswordResetTTL = time.Hour
+
+	// MaxPasswordResetRequests is how many reset tokens an account is sent
+	// within PasswordResetRequestWindow; further requests are dropped
+	MaxPasswordResetRequests   = 3
+	PasswordResetRequestWindow = time.Hour
+
+	passwordResetTokenBytes  = 32
+	passwordResetRequestsKey = "password_reset:requests:%d" // player ID
+)
+
+var (
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrPasswordRequired  = errors.New("new password is required")
+	ErrResetTokenInvalid = repository.ErrResetTokenInvalid
+)
+
+// ChangePassword sets a new password for a player who knows their current one
+func (s *playerService) ChangePassword(id uint, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrPasswordRequired
+	}
+
+	// Not GetPlayer, as the cached player has no password hash
+	player, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(player.Password), []byte(oldPassword)) != nil {
+		return ErrWrongPassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(id, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	s.notify(player, "Your password was changed",
+		"If you didn't change it, reset your password now.")
+	return nil
+}
+
+// RequestPasswordReset sends a player a reset token, superseding any sent
+// before. Unknown usernames and accounts that have been sent too many tokens
+// lately are ignored without an error, so the response doesn't reveal which
+// exist.
+func (s *playerService) RequestPasswordReset(username string) error {
+	player, err := s.repo.FindByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	allowed, err := s.allowResetRequest(player.ID)
+	if err != nil {
+		// Don't stop players resetting their password while Redis is down
+		log.Printf("Failed to count password reset requests: %v", err)
+	} else if !allowed {
+		log.Printf("Too many password reset requests for player %d, not sending another", player.ID)
+		return nil
+	}
+
+	token := make([]byte, passwordResetTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	encoded := hex.EncodeToString(token)
+
+	reset := &model.PasswordReset{
+		PlayerID:  player.ID,
+		TokenHash: hashResetToken(encoded),
+		ExpiresAt: time.Now().Add(PasswordResetTTL),
+	}
+	if err := s.resets.Create(reset); err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(newNotification(player, "Reset your password",
+		fmt.Sprintf("Use this token to set a new password before %s: %s",
+			reset.ExpiresAt.Format(time.RFC3339), encoded)))
+}
+
+// ResetPassword sets a new password with a reset token, which can't be used
+// again, and lifts any login lockout. It returns the player's ID.
+func (s *playerService) ResetPassword(token, newPassword string) (uint, error) {
+	if newPassword == "" {
+		return 0, ErrPasswordRequired
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.resets.Redeem(hashResetToken(token), string(hashedPassword))
+	if err != nil {
+		return 0, err
+	}
+
+	// The password is reset by now, so the rest is best effort
+	player, err := s.repo.FindByID(id)
+	if err != nil {
+		return id, nil
+	}
+	if err := s.clearFailedLogins(player.Username); err != nil {
+		log.Printf("Failed to clear failed logins of %s: %v", player.Username, err)
+	}
+	s.notify(player, "Your password was reset",
+		"If you didn't reset it, reset your password again now.")
+	return id, nil
+}
+
+// allowResetRequest counts a reset request against an account, reporting
+// whether it is still within MaxPasswordResetRequests. The count expires
+// PasswordResetRequestWindow after the first request.
+func (s *playerService) allowResetRequest(playerID uint) (bool, error) {
+	key := fmt.Sprintf(passwordResetRequestsKey, playerID)
+	count, err := s.redis.Incr(s.ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		s.redis.Expire(s.ctx, key, PasswordResetRequestWindow)
+	}
+	return count <= MaxPasswordResetRequests, nil
+}
+
+// notify sends a notification that the change it reports doesn't depend on
+func (s *playerService) notify(player *model.Player, subject, body string) {
+	if err := s.notifier.Notify(newNotification(player, subject, body)); err != nil {
+		log.Printf("Failed to notify player %d: %v", player.ID, err)
+	}
+}
+
+// Reset tokens are long and random, so a fast hash is enough
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
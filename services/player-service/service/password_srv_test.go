@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"maushold/player-service/model"
+	"maushold/player-service/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// capturingNotifier keeps what would have been sent, so tests can read the
+// reset tokens back
+type capturingNotifier struct {
+	sent []Notification
+}
+
+func (n *capturingNotifier) Notify(notification Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// token is the reset token in the last notification sent
+func (n *capturingNotifier) token(t *testing.T) string {
+	t.Helper()
+	if len(n.sent) == 0 {
+		t.Fatal("no notification was sent")
+	}
+	fields := strings.Fields(n.sent[len(n.sent)-1].Body)
+	return fields[len(fields)-1]
+}
+
+// fakePlayers is the part of the player repository the password flows use
+type fakePlayers struct {
+	repository.PlayerRepository
+	players map[uint]*model.Player
+}
+
+func (r *fakePlayers) FindByID(id uint) (*model.Player, error) {
+	if p, ok := r.players[id]; ok {
+		copied := *p
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePlayers) FindByUsername(username string) (*model.Player, error) {
+	for _, p := range r.players {
+		if strings.EqualFold(p.Username, username) {
+			copied := *p
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakePlayers) UpdatePassword(id uint, passwordHash string) error {
+	p, ok := r.players[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	p.Password = passwordHash
+	return nil
+}
+
+// fakeResets follows the contract of the password reset repository in memory
+type fakeResets struct {
+	players *fakePlayers
+	resets  []*model.PasswordReset
+}
+
+func (r *fakeResets) Create(reset *model.PasswordReset) error {
+	now := time.Now()
+	for _, existing := range r.resets {
+		if existing.PlayerID == reset.PlayerID && existing.UsedAt == nil {
+			existing.UsedAt = &now
+		}
+	}
+	reset.ID = uint(len(r.resets) + 1)
+	r.resets = append(r.resets, reset)
+	return nil
+}
+
+func (r *fakeResets) Redeem(tokenHash, passwordHash string) (uint, error) {
+	now := time.Now()
+	for _, reset := range r.resets {
+		if reset.TokenHash != tokenHash || reset.UsedAt != nil || !reset.ExpiresAt.After(now) {
+			continue
+		}
+		if err := r.players.UpdatePassword(reset.PlayerID, passwordHash); err != nil {
+			return 0, repository.ErrResetTokenInvalid
+		}
+		for _, other := range r.resets {
+			if other.PlayerID == reset.PlayerID && other.UsedAt == nil {
+				other.UsedAt = &now
+			}
+		}
+		return reset.PlayerID, nil
+	}
+	return 0, repository.ErrResetTokenInvalid
+}
+
+type passwordFixture struct {
+	service  *playerService
+	players  *fakePlayers
+	resets   *fakeResets
+	notifier *capturingNotifier
+	redis    *miniredis.Miniredis
+}
+
+func newPasswordFixture(t *testing.T) *passwordFixture {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mr := miniredis.RunT(t)
+	players := &fakePlayers{players: map[uint]*model.Player{
+		1: {ID: 1, Username: "Ash", Password: string(hash)},
+	}}
+	resets := &fakeResets{players: players}
+	notifier := &capturingNotifier{}
+	svc := NewPlayerService(players, resets, notifier, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	return &passwordFixture{service: svc.(*playerService), players: players, resets: resets, notifier: notifier, redis: mr}
+}
+
+func (f *passwordFixture) passwordIs(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(f.players.players[1].Password), []byte(password)) == nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	f := newPasswordFixture(t)
+	f.redis.Set(accountKey("Ash"), "5") // locked out
+
+	if err := f.service.RequestPasswordReset("ash"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	token := f.notifier.token(t)
+	if f.notifier.sent[0].PlayerID != 1 {
+		t.Errorf("token sent to player %d, want 1", f.notifier.sent[0].PlayerID)
+	}
+	if f.resets.resets[0].TokenHash == token {
+		t.Error("the token was stored unhashed")
+	}
+
+	id, err := f.service.ResetPassword(token, "new-password")
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("reset player %d, want 1", id)
+	}
+	if !f.passwordIs("new-password") {
+		t.Error("password was not changed")
+	}
+	if f.redis.Exists(accountKey("Ash")) {
+		t.Error("the login lockout was not lifted")
+	}
+	if n := len(f.notifier.sent); n != 2 {
+		t.Errorf("sent %d notifications, want the token and a confirmation", n)
+	}
+
+	if _, err := f.service.ResetPassword(token, "another-password"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("reusing the token: err = %v, want %v", err, ErrResetTokenInvalid)
+	}
+}
+
+func TestPasswordResetForUnknownUsername(t *testing.T) {
+	f := newPasswordFixture(t)
+
+	if err := f.service.RequestPasswordReset("misty"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if len(f.notifier.sent) != 0 {
+		t.Errorf("sent %d notifications for an unknown username", len(f.notifier.sent))
+	}
+}
+
+func TestPasswordResetSupersedesEarlierTokens(t *testing.T) {
+	f := newPasswordFixture(t)
+
+	f.service.RequestPasswordReset("Ash")
+	first := f.notifier.token(t)
+	f.service.RequestPasswordReset("Ash")
+	second := f.notifier.token(t)
+
+	if _, err := f.service.ResetPassword(first, "new-password"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("superseded token: err = %v, want %v", err, ErrResetTokenInvalid)
+	}
+	if _, err := f.service.ResetPassword(second, "new-password"); err != nil {
+		t.Errorf("latest token: %v", err)
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	f := newPasswordFixture(t)
+
+	f.service.RequestPasswordReset("Ash")
+	f.resets.resets[0].ExpiresAt = time.Now().Add(-time.Second)
+
+	if _, err := f.service.ResetPassword(f.notifier.token(t), "new-password"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("err = %v, want %v", err, ErrResetTokenInvalid)
+	}
+	if !f.passwordIs("old-password") {
+		t.Error("an expired token changed the password")
+	}
+}
+
+func TestPasswordResetRequestsAreRateLimited(t *testing.T) {
+	f := newPasswordFixture(t)
+
+	for i := 0; i < MaxPasswordResetRequests+2; i++ {
+		if err := f.service.RequestPasswordReset("Ash"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	if n := len(f.notifier.sent); n != MaxPasswordResetRequests {
+		t.Errorf("sent %d tokens, want %d", n, MaxPasswordResetRequests)
+	}
+
+	f.redis.FastForward(PasswordResetRequestWindow)
+	f.service.RequestPasswordReset("Ash")
+	if n := len(f.notifier.sent); n != MaxPasswordResetRequests+1 {
+		t.Errorf("no token sent once the window passed")
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	f := newPasswordFixture(t)
+
+	if err := f.service.ChangePassword(1, "wrong", "new-password"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: err = %v, want %v", err, ErrWrongPassword)
+	}
+	if err := f.service.ChangePassword(1, "old-password", ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("empty password: err = %v, want %v", err, ErrPasswordRequired)
+	}
+	if err := f.service.ChangePassword(1, "old-password", "new-password"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if !f.passwordIs("new-password") {
+		t.Error("password was not changed")
+	}
+	if len(f.notifier.sent) != 1 {
+		t.Errorf("sent %d notifications, want 1", len(f.notifier.sent))
+	}
+}
@@ -1,33 +1,212 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"log"
+
+	"maushold/events"
 	"maushold/player-service/model"
 	"maushold/player-service/repository"
 
 	"github.com/go-redis/redis/v8"
 )
 
+const MaxMovesPerMonster = 4
+
+var (
+	ErrMonsterNotOwned = errors.New("monster does not belong to player")
+	ErrInvalidMoveSet  = errors.New("invalid move set")
+)
+
 type PlayerMonsterService interface {
 	AddMonsterToPlayer(monster *model.PlayerMonster) error
 	GetPlayerMonster(playerID uint) ([]model.PlayerMonster, error)
+	SetMonsterMoves(playerID, monsterID uint, moveIDs []uint) (*model.PlayerMonster, error)
 }
 
 type playerMonsterService struct {
-	repo  repository.PlayerMonsterRepository
-	redis *redis.Client
+	repo          repository.PlayerMonsterRepository
+	monsterClient *MonsterClient
+	redis         *redis.Client
 }
 
-func NewPlayerMonsterService(repo repository.PlayerMonsterRepository, redisClient *redis.Client) PlayerMonsterService {
+func NewPlayerMonsterService(repo repository.PlayerMonsterRepository, monsterClient *MonsterClient, redisClient *redis.Client) PlayerMonsterService {
 	return &playerMonsterService{
-		repo:  repo,
-		redis: redisClient,
+		repo:          repo,
+		monsterClient: monsterClient,
+		redis:         redisClient,
 	}
 }
 
 func (s *playerMonsterService) AddMonsterToPlayer(monster *model.PlayerMonster) error {
-	return s.repo.Create(monster)
+	// Copy the species types so battles don't need a catalog lookup
+	species, err := s.monsterClient.GetMonster(monster.MonsterID)
+	if err != nil {
+		return err
+	}
+	monster.Type1 = species.Type1
+	monster.Type2 = species.Type2
+
+	if len(monster.Moves) > 0 {
+		moveIDs := make([]uint, len(monster.Moves))
+		for i, m := range monster.Moves {
+			moveIDs[i] = m.MoveID
+		}
+		moves, err := s.validateMoveSet(monster, moveIDs)
+		if err != nil {
+			return err
+		}
+		monster.Moves = moves
+	} else {
+		monster.Moves = s.defaultMoves(monster)
+	}
+
+	return s.repo.Create(monster, func() events.Event {
+		return events.PlayerMonsterAdded{
+			PlayerID:        monster.PlayerID,
+			PlayerMonsterID: monster.ID,
+			MonsterID:       monster.MonsterID,
+			Nickname:        monster.Nickname,
+			Level:           monster.Level,
+			MoveIDs:         moveIDsOf(monster.Moves),
+		}
+	})
 }
 
 func (s *playerMonsterService) GetPlayerMonster(playerID uint) ([]model.PlayerMonster, error) {
-	return s.repo.FindByPlayerID(playerID)
+	monsters, err := s.repo.FindByPlayerID(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range monsters {
+		s.backfill(&monsters[i])
+	}
+
+	return monsters, nil
+}
+
+// SetMonsterMoves replaces the move slots of a player's monster
+func (s *playerMonsterService) SetMonsterMoves(playerID, monsterID uint, moveIDs []uint) (*model.PlayerMonster, error) {
+	monster, err := s.repo.FindByID(monsterID)
+	if err != nil {
+		return nil, err
+	}
+	if monster.PlayerID != playerID {
+		return nil, ErrMonsterNotOwned
+	}
+
+	moves, err := s.validateMoveSet(monster, moveIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.SetMoves(monster.ID, moves, func() events.Event {
+		return events.PlayerMonsterMovesUpdated{PlayerID: playerID, PlayerMonsterID: monster.ID, MoveIDs: moveIDs}
+	})
+	if err != nil {
+		return nil, err
+	}
+	monster.Moves = moves
+
+	return monster, nil
+}
+
+// validateMoveSet checks the moves are distinct, at most four, and learnable at the monster's level
+func (s *playerMonsterService) validateMoveSet(monster *model.PlayerMonster, moveIDs []uint) ([]model.PlayerMonsterMove, error) {
+	if len(moveIDs) == 0 || len(moveIDs) > MaxMovesPerMonster {
+		return nil, fmt.Errorf("%w: a monster must know between 1 and %d moves", ErrInvalidMoveSet, MaxMovesPerMonster)
+	}
+
+	learnset, err := s.monsterClient.GetLearnset(monster.MonsterID)
+	if err != nil {
+		return nil, err
+	}
+
+	learnLevels := make(map[uint]int)
+	for _, entry := range learnset {
+		learnLevels[entry.MoveID] = entry.Level
+	}
+
+	seen := make(map[uint]bool)
+	moves := make([]model.PlayerMonsterMove, 0, len(moveIDs))
+	for i, moveID := range moveIDs {
+		if seen[moveID] {
+			return nil, fmt.Errorf("%w: move %d listed twice", ErrInvalidMoveSet, moveID)
+		}
+		seen[moveID] = true
+
+		level, ok := learnLevels[moveID]
+		if !ok {
+			return nil, fmt.Errorf("%w: move %d is not in this species' learnset", ErrInvalidMoveSet, moveID)
+		}
+		if level > monster.Level {
+			return nil, fmt.Errorf("%w: move %d requires level %d", ErrInvalidMoveSet, moveID, level)
+		}
+
+		moves = append(moves, model.PlayerMonsterMove{MoveID: moveID, Slot: i + 1})
+	}
+
+	return moves, nil
+}
+
+// defaultMoves picks the four most recently learnable moves for the monster's level
+func (s *playerMonsterService) defaultMoves(monster *model.PlayerMonster) []model.PlayerMonsterMove {
+	learnset, err := s.monsterClient.GetLearnset(monster.MonsterID)
+	if err != nil {
+		log.Printf("Failed to fetch learnset for species %d: %v", monster.MonsterID, err)
+		return nil
+	}
+
+	var learnable []model.LearnsetEntry
+	for _, entry := range learnset {
+		if entry.Level <= monster.Level {
+			learnable = append(learnable, entry)
+		}
+	}
+	if len(learnable) > MaxMovesPerMonster {
+		learnable = learnable[len(learnable)-MaxMovesPerMonster:]
+	}
+
+	moves := make([]model.PlayerMonsterMove, len(learnable))
+	for i, entry := range learnable {
+		moves[i] = model.PlayerMonsterMove{MoveID: entry.MoveID, Slot: i + 1}
+	}
+	return moves
+}
+
+func moveIDsOf(moves []model.PlayerMonsterMove) []uint {
+	ids := make([]uint, len(moves))
+	for i, m := range moves {
+		ids[i] = m.MoveID
+	}
+	return ids
+}
+
+// backfill fills in types and moves for monsters added before they were tracked
+func (s *playerMonsterService) backfill(monster *model.PlayerMonster) {
+	if monster.Type1 == "" {
+		species, err := s.monsterClient.GetMonster(monster.MonsterID)
+		if err != nil {
+			log.Printf("Failed to fetch species %d for player monster %d: %v", monster.MonsterID, monster.ID, err)
+		} else {
+			monster.Type1 = species.Type1
+			monster.Type2 = species.Type2
+			if err := s.repo.Update(monster); err != nil {
+				log.Printf("Failed to save backfilled types for player monster %d: %v", monster.ID, err)
+			}
+		}
+	}
+
+	if len(monster.Moves) == 0 {
+		moves := s.defaultMoves(monster)
+		if len(moves) > 0 {
+			if err := s.repo.SetMoves(monster.ID, moves); err != nil {
+				log.Printf("Failed to save backfilled moves for player monster %d: %v", monster.ID, err)
+			} else {
+				monster.Moves = moves
+			}
+		}
+	}
 }
@@ -5,41 +5,77 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"maushold/auth"
+	"maushold/events"
 	"maushold/player-service/model"
 	"maushold/player-service/repository"
 
 	"github.com/go-redis/redis/v8"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrLoginLocked        = errors.New("too many failed logins")
+)
+
+// ProcessedBattleRetention is how long applied battles are remembered. It is
+// far beyond how long a battle.completed event can be retried or sit in the
+// dead-letter queue before someone replays it.
+const ProcessedBattleRetention = 30 * 24 * time.Hour
+
+// dummyPasswordHash is compared against when a username doesn't exist, so
+// that takes as long as a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
 type PlayerService interface {
 	CreatePlayer(player *model.Player) error
 	GetPlayer(id uint) (*model.Player, error)
-	UpdatePlayer(player *model.Player) error
+	UpdatePlayer(id uint, username string) (*model.Player, error)
 	DeletePlayer(id uint) error
 	GetAllPlayers() ([]model.Player, error)
 	UpdatePlayerPoints(id uint, delta int) error
-	AuthenticatePlayer(username, password string) (*model.Player, error)
+	ApplyBattleResult(battleID, winnerID uint, pointsWon int, loserID uint, pointsLost int) error
+	AuthenticatePlayer(username, password, ip string) (*model.Player, error)
+	UnlockPlayer(id uint, ips ...string) error
+	SetPlayerRole(id uint, role auth.Role) error
+	BootstrapAdmin(username string) error
+	ChangePassword(id uint, oldPassword, newPassword string) error
+	RequestPasswordReset(username string) error
+	ResetPassword(token, newPassword string) (uint, error)
+	StartPeriodicCleanup()
 }
 
 type playerService struct {
-	repo  repository.PlayerRepository
-	redis *redis.Client
-	ctx   context.Context
+	repo     repository.PlayerRepository
+	resets   repository.PasswordResetRepository
+	notifier Notifier
+	redis    *redis.Client
+	ctx      context.Context
 }
 
-func NewPlayerService(repo repository.PlayerRepository, redisClient *redis.Client) PlayerService {
+func NewPlayerService(repo repository.PlayerRepository, resets repository.PasswordResetRepository, notifier Notifier, redisClient *redis.Client) PlayerService {
 	return &playerService{
-		repo:  repo,
-		redis: redisClient,
-		ctx:   context.Background(),
+		repo:     repo,
+		resets:   resets,
+		notifier: notifier,
+		redis:    redisClient,
+		ctx:      context.Background(),
 	}
 }
 
 func (s *playerService) CreatePlayer(player *model.Player) error {
 	player.Points = 0
+	player.Role = string(auth.RolePlayer)
+
+	if err := s.checkUsernameFree(player.Username, 0); err != nil {
+		return err
+	}
 
 	// Hash the password before storing
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(player.Password), bcrypt.DefaultCost)
@@ -48,7 +84,10 @@ func (s *playerService) CreatePlayer(player *model.Player) error {
 	}
 	player.Password = string(hashedPassword)
 
-	return s.repo.Create(player)
+	err = s.repo.Create(player, func() events.Event {
+		return events.PlayerCreated{PlayerID: player.ID, Username: player.Username, CreatedAt: player.CreatedAt}
+	})
+	return usernameTaken(err)
 }
 
 func (s *playerService) GetPlayer(id uint) (*model.Player, error) {
@@ -76,17 +115,30 @@ func (s *playerService) GetPlayer(id uint) (*model.Player, error) {
 	return player, nil
 }
 
-func (s *playerService) UpdatePlayer(player *model.Player) error {
-	err := s.repo.Update(player)
+// UpdatePlayer changes a player's username and returns them as saved
+func (s *playerService) UpdatePlayer(id uint, username string) (*model.Player, error) {
+	if err := s.checkUsernameFree(username, id); err != nil {
+		return nil, err
+	}
+	return s.updatePlayer(id, map[string]interface{}{"username": username})
+}
+
+// updatePlayer sets columns of a player, publishes player.updated and drops
+// them from the cache
+func (s *playerService) updatePlayer(id uint, columns map[string]interface{}) (*model.Player, error) {
+	player := &model.Player{ID: id}
+	err := s.repo.Update(player, columns, func() events.Event {
+		return events.PlayerUpdated{PlayerID: player.ID, Username: player.Username, Points: player.Points}
+	})
 	if err != nil {
-		return err
+		return nil, usernameTaken(err)
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("player:%d", player.ID)
 	s.redis.Del(s.ctx, cacheKey)
 
-	return nil
+	return player, nil
 }
 
 func (s *playerService) GetAllPlayers() ([]model.Player, error) {
@@ -99,7 +151,9 @@ func (s *playerService) DeletePlayer(id uint) error {
 		return err
 	}
 
-	err = s.repo.Delete(player)
+	err = s.repo.Delete(player, func() events.Event {
+		return events.PlayerDeleted{PlayerID: id}
+	})
 	if err != nil {
 		return err
 	}
@@ -112,39 +166,133 @@ func (s *playerService) DeletePlayer(id uint) error {
 }
 
 func (s *playerService) UpdatePlayerPoints(id uint, delta int) error {
-	player, err := s.GetPlayer(id)
-	if err != nil {
+	_, err := s.updatePlayer(id, map[string]interface{}{"points": gorm.Expr("points + ?", delta)})
+	return err
+}
+
+// ApplyBattleResult applies a completed battle's points to both players
+// exactly once, returning repository.ErrBattleAlreadyApplied for a battle
+// that was already applied
+func (s *playerService) ApplyBattleResult(battleID, winnerID uint, pointsWon int, loserID uint, pointsLost int) error {
+	if err := s.repo.ApplyBattleResult(battleID, winnerID, pointsWon, loserID, pointsLost); err != nil {
 		return err
 	}
 
-	player.Points += delta
-	return s.UpdatePlayer(player)
+	// Invalidate cache
+	s.redis.Del(s.ctx, fmt.Sprintf("player:%d", winnerID), fmt.Sprintf("player:%d", loserID))
+	return nil
+}
+
+// StartPeriodicCleanup prunes applied battles older than
+// ProcessedBattleRetention every hour, until the process exits
+func (s *playerService) StartPeriodicCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		pruned, err := s.repo.DeleteProcessedBattlesBefore(time.Now().Add(-ProcessedBattleRetention))
+		if err != nil {
+			log.Printf("Failed to prune processed battles: %v", err)
+			continue
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d processed battles", pruned)
+		}
+	}
 }
 
-func (s *playerService) AuthenticatePlayer(username, password string) (*model.Player, error) {
-	// Find player by username
-	players, err := s.repo.FindAll()
+// AuthenticatePlayer checks a username and password. Unknown usernames and
+// wrong passwords fail the same way and take as long, and too many failures
+// for an account or from an IP lock it out for a while.
+func (s *playerService) AuthenticatePlayer(username, password, ip string) (*model.Player, error) {
+	wait, err := s.lockedFor(username, ip)
 	if err != nil {
+		// Don't lock everyone out while Redis is down
+		log.Printf("Failed to check login lockout: %v", err)
+	}
+	if wait > 0 {
+		return nil, fmt.Errorf("%w, try again in %s", ErrLoginLocked, wait.Round(time.Second))
+	}
+
+	player, err := s.repo.FindByUsername(username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	var player *model.Player
-	for i := range players {
-		if players[i].Username == username {
-			player = &players[i]
-			break
+	hash := dummyPasswordHash
+	if err == nil {
+		hash = []byte(player.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || err != nil {
+		if err := s.recordFailedLogin(username, ip); err != nil {
+			log.Printf("Failed to record failed login: %v", err)
 		}
+		return nil, ErrInvalidCredentials
 	}
 
-	if player == nil {
-		return nil, errors.New("invalid credentials")
+	if err := s.clearFailedLogins(username); err != nil {
+		log.Printf("Failed to clear failed logins of %s: %v", player.Username, err)
 	}
+	return player, nil
+}
 
-	// Compare password with hashed password
-	err = bcrypt.CompareHashAndPassword([]byte(player.Password), []byte(password))
+// UnlockPlayer lifts a player's lockout after too many failed logins, and
+// that of any IPs given
+func (s *playerService) UnlockPlayer(id uint, ips ...string) error {
+	player, err := s.GetPlayer(id)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return err
+	}
+	return s.clearFailedLogins(player.Username, ips...)
+}
+
+// checkUsernameFree returns ErrUsernameTaken if a player other than the
+// given one has the username, in any case
+func (s *playerService) checkUsernameFree(username string, playerID uint) error {
+	existing, err := s.repo.FindByUsername(username)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	case err != nil:
+		return err
+	case existing.ID != playerID:
+		return ErrUsernameTaken
 	}
+	return nil
+}
 
-	return player, nil
+// usernameTaken turns the unique username index rejecting a name, when
+// another player took it after checkUsernameFree, into ErrUsernameTaken
+func usernameTaken(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// SetPlayerRole changes what a player may do. Their tokens keep the old role
+// until their sessions are revoked.
+func (s *playerService) SetPlayerRole(id uint, role auth.Role) error {
+	if err := s.repo.UpdateRole(id, string(role)); err != nil {
+		return err
+	}
+
+	// Invalidate cache
+	s.redis.Del(s.ctx, fmt.Sprintf("player:%d", id))
+	return nil
+}
+
+// BootstrapAdmin makes the named player an admin if there are no admins
+// yet, so a fresh install has someone who can hand out roles. Once there is
+// one, roles only change through the admin endpoints.
+func (s *playerService) BootstrapAdmin(username string) error {
+	id, err := s.repo.GrantFirstRole(username, string(auth.RoleAdmin))
+	if err != nil || id == 0 {
+		return err
+	}
+	log.Printf("Made %s the first admin", username)
+
+	// Invalidate cache
+	s.redis.Del(s.ctx, fmt.Sprintf("player:%d", id))
+	return nil
 }
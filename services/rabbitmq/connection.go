@@ -0,0 +1,230 @@
+// Package rabbitmq keeps a service connected to RabbitMQ. A Connection
+// watches for the broker going away, reconnects with backoff, declares the
+// service's topology again and resubscribes its consumers.
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	startupAttempts = 10
+	minBackoff      = time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+var ErrNotConnected = errors.New("not connected to RabbitMQ")
+
+// Topology declares the exchanges, queues and bindings a service uses. It
+// runs on every new connection, so it must be safe to run again.
+type Topology func(ch *amqp.Channel) error
+
+// Handler processes a consumer's deliveries until they are closed, which
+// happens when the connection is lost. ch is the channel they arrive on, to
+// ack and publish with.
+type Handler func(ch *amqp.Channel, deliveries <-chan amqp.Delivery)
+
+type consumer struct {
+	queue    string
+	prefetch int
+	handle   Handler
+}
+
+// Connection is a supervised RabbitMQ connection with a shared channel for
+// publishing
+type Connection struct {
+	url      string
+	topology Topology
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	consumers []consumer
+	closing   bool
+}
+
+// Dial connects to RabbitMQ, retrying for a while so the broker can start
+// alongside the service, declares the topology and starts supervising the
+// connection
+func Dial(url string, topology Topology) (*Connection, error) {
+	c := &Connection{url: url, topology: topology}
+
+	var closed chan *amqp.Error
+	var err error
+	backoff := minBackoff
+	for i := 0; i < startupAttempts; i++ {
+		closed, err = c.connect()
+		if err == nil {
+			break
+		}
+		log.Printf("Failed to connect to RabbitMQ (attempt %d/%d): %v", i+1, startupAttempts, err)
+		time.Sleep(backoff)
+		backoff = nextBackoff(backoff)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to RabbitMQ after %d attempts: %w", startupAttempts, err)
+	}
+
+	go c.supervise(closed)
+	return c, nil
+}
+
+// connect dials the broker, declares the topology and subscribes every
+// registered consumer. It returns a channel that is notified when the new
+// connection closes.
+func (c *Connection) connect() (chan *amqp.Error, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := c.topology(ch); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("declaring topology: %w", err)
+	}
+
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	c.mu.Lock()
+	c.conn = conn
+	c.channel = ch
+	consumers := append([]consumer(nil), c.consumers...)
+	c.mu.Unlock()
+
+	for _, cons := range consumers {
+		if err := c.subscribe(conn, cons); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	log.Println("RabbitMQ connected")
+	return closed, nil
+}
+
+// supervise reconnects whenever the connection is lost, until Close
+func (c *Connection) supervise(closed chan *amqp.Error) {
+	for {
+		reason := <-closed
+
+		c.mu.Lock()
+		c.conn = nil
+		c.channel = nil
+		c.mu.Unlock()
+		if c.isClosing() {
+			return
+		}
+		log.Printf("RabbitMQ connection lost: %v", reason)
+
+		backoff := minBackoff
+		for {
+			var err error
+			closed, err = c.connect()
+			if err == nil {
+				break
+			}
+			if c.isClosing() {
+				return
+			}
+			log.Printf("Failed to reconnect to RabbitMQ, retrying in %s: %v", backoff, err)
+			time.Sleep(backoff)
+			backoff = nextBackoff(backoff)
+		}
+	}
+}
+
+func (c *Connection) isClosing() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closing
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	return min(2*d, maxBackoff)
+}
+
+// subscribe starts a consumer on its own channel of conn
+func (c *Connection) subscribe(conn *amqp.Connection, cons consumer) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(cons.prefetch, 0, false); err != nil {
+		return fmt.Errorf("setting prefetch on %s: %w", cons.queue, err)
+	}
+
+	deliveries, err := ch.Consume(cons.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("consuming %s: %w", cons.queue, err)
+	}
+
+	go cons.handle(ch, deliveries)
+	return nil
+}
+
+// Consume subscribes handle to a queue with manual acks, now and again after
+// every reconnect
+func (c *Connection) Consume(queue string, prefetch int, handle Handler) error {
+	cons := consumer{queue: queue, prefetch: prefetch, handle: handle}
+
+	c.mu.Lock()
+	c.consumers = append(c.consumers, cons)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil // subscribed once reconnected
+	}
+	return c.subscribe(conn, cons)
+}
+
+// Publish sends a message on the shared channel. It fails with
+// ErrNotConnected while the connection is down.
+func (c *Connection) Publish(exchange, routingKey string, msg amqp.Publishing) error {
+	c.mu.RLock()
+	ch := c.channel
+	c.mu.RUnlock()
+
+	if ch == nil {
+		return ErrNotConnected
+	}
+	return ch.Publish(exchange, routingKey, false, false, msg)
+}
+
+// Channel opens a channel of its own on the current connection, for callers
+// that need to change its mode, such as turning on publisher confirms. It is
+// closed when the connection is lost.
+func (c *Connection) Channel() (*amqp.Channel, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn.Channel()
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	c.closing = true
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
@@ -0,0 +1,5 @@
+module maushold/rabbitmq
+
+go 1.25.3
+
+require github.com/streadway/amqp v1.1.0
@@ -0,0 +1,90 @@
+package rabbitmq
+
+import (
+	"errors"
+	"log"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	retryCountHeader = "x-retry-count"
+	routingKeyHeader = "x-routing-key" // retried messages lose their routing key
+	lastErrorHeader  = "x-last-error"
+)
+
+// ErrMalformed marks messages that can never be processed, so they are
+// dead-lettered without being retried
+var ErrMalformed = errors.New("malformed message")
+
+// RetryPolicy settles a consumer's deliveries. Messages that fail are
+// published to RetryQueue, which should dead-letter them back to the
+// consumer's queue after a delay, up to MaxRetries times; after that, or
+// straight away if they are malformed, they go to DeadQueue.
+type RetryPolicy struct {
+	RetryQueue string
+	DeadQueue  string
+	MaxRetries int
+}
+
+// Settle acks a message once it has been handled, or moves it to the retry
+// or dead-letter queue if handleErr says it failed. A message that can't be
+// moved is requeued.
+func (p RetryPolicy) Settle(ch *amqp.Channel, msg amqp.Delivery, handleErr error) {
+	if handleErr == nil {
+		msg.Ack(false)
+		return
+	}
+
+	routingKey := RoutingKey(msg)
+	retries := retryCount(msg)
+	queue := p.RetryQueue
+	if errors.Is(handleErr, ErrMalformed) || retries >= p.MaxRetries {
+		queue = p.DeadQueue
+		log.Printf("Dead-lettering %s after %d retries: %v", routingKey, retries, handleErr)
+	} else {
+		log.Printf("Retrying %s (attempt %d/%d): %v", routingKey, retries+1, p.MaxRetries, handleErr)
+	}
+
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[retryCountHeader] = int32(retries + 1)
+	headers[routingKeyHeader] = routingKey
+	headers[lastErrorHeader] = handleErr.Error()
+
+	err := ch.Publish("", queue, false, false, amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Headers:      headers,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		log.Printf("Failed to move %s to %s, requeueing: %v", routingKey, queue, err)
+		msg.Nack(false, true)
+		return
+	}
+	msg.Ack(false)
+}
+
+// RoutingKey returns the key a message was first published with, which a
+// retried message carries in its headers
+func RoutingKey(msg amqp.Delivery) string {
+	if key, ok := msg.Headers[routingKeyHeader].(string); ok {
+		return key
+	}
+	return msg.RoutingKey
+}
+
+func retryCount(msg amqp.Delivery) int {
+	switch n := msg.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"log"
+
+	"maushold/auth"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func InitAuth(cfg *Config, redisClient *redis.Client) *auth.Keys {
+	keys, err := auth.NewKeys(cfg.JWTSecret, auth.NewSessions(redisClient))
+	if err != nil {
+		log.Fatal("Invalid JWT_SECRET:", err)
+	}
+	return keys
+}
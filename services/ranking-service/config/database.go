@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"maushold/ranking-service/model"
 
@@ -21,19 +22,27 @@ func InitDB(cfg *Config) *gorm.DB {
 	}
 
 	// Auto migrate
-	err = db.AutoMigrate(&model.PlayerRanking{}, &model.LeaderboardEntry{}, &model.Player{})
+	err = db.AutoMigrate(&model.Season{}, &model.SeasonStanding{}, &model.RankSnapshot{}, &model.ProcessedBattle{}, &model.PlayerRanking{}, &model.LeaderboardEntry{}, &model.Player{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	// Execute SQL migrations (Materialized View, etc.)
-	migrationPath := "migrations/001_add_combat_power_and_optimize.sql"
-	content, err := os.ReadFile(migrationPath)
-	if err == nil {
-		db.Exec(string(content))
-		log.Println("Applied SQL migrations")
-	} else {
-		log.Printf("Warning: Could not read migration file %s: %v", migrationPath, err)
+	// Execute SQL migrations (Materialized View, etc.) in order
+	migrationPaths, _ := filepath.Glob("migrations/*.sql")
+	if len(migrationPaths) == 0 {
+		log.Printf("Warning: No migration files found in migrations/")
+	}
+	for _, migrationPath := range migrationPaths {
+		content, err := os.ReadFile(migrationPath)
+		if err != nil {
+			log.Printf("Warning: Could not read migration file %s: %v", migrationPath, err)
+			continue
+		}
+		if err := db.Exec(string(content)).Error; err != nil {
+			log.Printf("Warning: Migration %s failed: %v", migrationPath, err)
+			continue
+		}
+		log.Printf("Applied SQL migration %s", migrationPath)
 	}
 
 	log.Println("Database connected and migrated")
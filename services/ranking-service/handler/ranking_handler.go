@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"maushold/ranking-service/model"
 	"maushold/ranking-service/service"
 
 	"github.com/gorilla/mux"
@@ -22,7 +25,9 @@ func NewRankingHandler(rankingService service.RankingService, serviceDiscovery *
 	}
 }
 
-// GetLeaderboard returns the top N players with metadata
+// GetLeaderboard returns the top N players with metadata, by combat power
+// or, with ?sort=rating, by conservative rating. ?window=daily, weekly or
+// monthly ranks by points gained in the current window instead.
 func (h *RankingHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
 	limit := 100
 	if l := r.URL.Query().Get("limit"); l != "" {
@@ -31,8 +36,19 @@ func (h *RankingHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	response, err := h.rankingService.GetLeaderboard(limit)
+	var response *model.LeaderboardResponse
+	var err error
+	switch window := r.URL.Query().Get("window"); window {
+	case "", service.WindowAll:
+		response, err = h.rankingService.GetLeaderboard(limit, r.URL.Query().Get("sort"))
+	default:
+		response, err = h.rankingService.GetWindowLeaderboard(window, limit)
+	}
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidSort) || errors.Is(err, service.ErrInvalidWindow) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -83,6 +99,104 @@ func (h *RankingHandler) GetPlayerRankWithContext(w http.ResponseWriter, r *http
 	respondJSON(w, http.StatusOK, context)
 }
 
+// GetRankHistory returns a player's rank over time. from and to are RFC 3339
+// times or dates, defaulting to the last 30 days.
+func (h *RankingHandler) GetRankHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["playerId"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid player ID")
+		return
+	}
+
+	from, err := parseTime(r.URL.Query().Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid from time")
+		return
+	}
+	to, err := parseTime(r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid to time")
+		return
+	}
+
+	history, err := h.rankingService.GetRankHistory(uint(id), from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHistoryRange) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, history)
+}
+
+// parseTime parses an RFC 3339 time or a date, returning the zero time for
+// an empty string
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetSeasons returns every ranked season, newest first
+func (h *RankingHandler) GetSeasons(w http.ResponseWriter, r *http.Request) {
+	seasons, err := h.rankingService.GetSeasons()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, seasons)
+}
+
+// GetCurrentSeason returns the running season
+func (h *RankingHandler) GetCurrentSeason(w http.ResponseWriter, r *http.Request) {
+	season, err := h.rankingService.GetCurrentSeason()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, season)
+}
+
+// GetSeasonStandings returns a season's final standings, or its live ones
+// while it is still running
+func (h *RankingHandler) GetSeasonStandings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid season ID")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 10000 {
+			limit = parsed
+		}
+	}
+
+	standings, err := h.rankingService.GetSeasonStandings(uint(id), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSeasonNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, standings)
+}
+
 // UpdateCombatPower updates a player's combat power (internal endpoint)
 func (h *RankingHandler) UpdateCombatPower(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -21,9 +21,10 @@ func main() {
 
 	db := config.InitDB(cfg)
 	redisClient := config.InitRedis(cfg)
-	rabbitConn, rabbitCh := config.InitRabbitMQ(cfg)
+	rabbitConn := config.InitRabbitMQ(cfg)
 	defer rabbitConn.Close()
-	defer rabbitCh.Close()
+
+	authKeys := config.InitAuth(cfg, redisClient)
 
 	consulClient := config.InitConsul(cfg)
 	err := config.RegisterService(consulClient, "ranking-service", cfg.ServicePort)
@@ -33,16 +34,24 @@ func main() {
 	defer config.DeregisterService(consulClient, "ranking-service")
 
 	rankingRepo := repository.NewRankingRepository(db)
+	seasonRepo := repository.NewSeasonRepository(db)
+	historyRepo := repository.NewHistoryRepository(db)
 	playerClient := service.NewPlayerClient(cfg.PlayerServiceURL)
 	battleClient := service.NewBattleClient(cfg.BattleServiceURL)
 	leaderboardService := service.NewLeaderboardService(redisClient)
-	rankingService := service.NewRankingService(rankingRepo, playerClient, battleClient, leaderboardService)
+	messageProducer := messaging.NewProducer(rabbitConn)
+	rankingService := service.NewRankingService(rankingRepo, seasonRepo, historyRepo, playerClient, battleClient, leaderboardService, messageProducer, cfg.SeasonLength)
+
+	// Start the current season and archive any that ended while we were down
+	if err := rankingService.ArchiveEndedSeasons(); err != nil {
+		log.Printf("Failed to check seasons: %v", err)
+	}
 
 	// Initialize service discovery
 	serviceDiscovery := service.NewServiceDiscovery(consulClient)
 
-	messageConsumer := messaging.NewConsumer(rabbitCh, rankingService)
-	go messageConsumer.Start()
+	messageConsumer := messaging.NewConsumer(rabbitConn, rankingService)
+	messageConsumer.Start()
 
 	// Start periodic sync
 	go rankingService.StartPeriodicSync()
@@ -59,7 +68,7 @@ func main() {
 	rankingHandler := handler.NewRankingHandler(rankingService, serviceDiscovery)
 
 	router := mux.NewRouter()
-	routes.SetupRankingRoutes(router, rankingHandler)
+	routes.SetupRankingRoutes(router, rankingHandler, authKeys)
 
 	port := os.Getenv("SERVICE_PORT")
 	if port == "" {
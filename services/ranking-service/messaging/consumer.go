@@ -1,95 +1,106 @@
 package messaging
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 
+	"maushold/events"
+	"maushold/rabbitmq"
+	"maushold/ranking-service/repository"
 	"maushold/ranking-service/service"
 
 	"github.com/streadway/amqp"
 )
 
+const (
+	updatesQueue = "ranking.updates"
+	retryQueue   = "ranking.updates.retry" // dead-letters back to updatesQueue after a delay
+	deadQueue    = "ranking.updates.dlq"
+
+	maxRetries    = 3
+	prefetchCount = 10
+)
+
+var retryPolicy = rabbitmq.RetryPolicy{
+	RetryQueue: retryQueue,
+	DeadQueue:  deadQueue,
+	MaxRetries: maxRetries,
+}
+
 type Consumer struct {
-	channel        *amqp.Channel
+	conn           *rabbitmq.Connection
 	rankingService service.RankingService
 }
 
-func NewConsumer(channel *amqp.Channel, rankingService service.RankingService) *Consumer {
+func NewConsumer(conn *rabbitmq.Connection, rankingService service.RankingService) *Consumer {
 	return &Consumer{
-		channel:        channel,
+		conn:           conn,
 		rankingService: rankingService,
 	}
 }
 
+// Start subscribes to updatesQueue, again after every reconnect
 func (c *Consumer) Start() {
-	msgs, err := c.channel.Consume(
-		"ranking.updates",
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
+	if err := c.conn.Consume(updatesQueue, prefetchCount, c.consume); err != nil {
 		log.Fatal("Failed to register consumer:", err)
 	}
 
 	log.Println("Listening for battle events...")
+}
 
+// consume handles deliveries until the channel they arrive on closes
+func (c *Consumer) consume(ch *amqp.Channel, msgs <-chan amqp.Delivery) {
 	for msg := range msgs {
-		log.Printf("Received message: %s", msg.RoutingKey)
-
-		switch msg.RoutingKey {
-		case "battle.completed":
-			c.handleBattleCompleted(msg.Body)
-		case "player.deleted":
-			c.handlePlayerDeleted(msg.Body)
+		routingKey := rabbitmq.RoutingKey(msg)
+		log.Printf("Received message: %s", routingKey)
+
+		var err error
+		switch routingKey {
+		case events.BattleCompletedKey:
+			err = c.handleBattleCompleted(msg.Body, events.HeaderVersion(msg.Headers))
+		case events.PlayerDeletedKey:
+			err = c.handlePlayerDeleted(msg.Body, events.HeaderVersion(msg.Headers))
 		}
+		retryPolicy.Settle(ch, msg, err)
 	}
 }
 
-func (c *Consumer) handlePlayerDeleted(body []byte) {
-	var event map[string]interface{}
-	if err := json.Unmarshal(body, &event); err != nil {
-		log.Printf("Error parsing player deleted event: %v", err)
-		return
+func (c *Consumer) handlePlayerDeleted(body []byte, version int) error {
+	var event events.PlayerDeleted
+	if err := events.Decode(body, version, &event); err != nil {
+		return fmt.Errorf("%w: %v", rabbitmq.ErrMalformed, err)
 	}
 
-	playerIDRaw, ok := event["player_id"]
-	if !ok {
-		log.Printf("Error: player_id missing in player.deleted event")
-		return
-	}
-
-	playerID := uint(playerIDRaw.(float64))
+	playerID := event.PlayerID
 	log.Printf("Processing player deletion: PlayerID=%d", playerID)
 
-	err := c.rankingService.DeletePlayerRanking(playerID)
-	if err != nil {
-		log.Printf("Error processing player deletion: %v", err)
-	} else {
-		log.Printf("Player %d ranking deleted successully", playerID)
+	if err := c.rankingService.DeletePlayerRanking(playerID); err != nil {
+		return err
 	}
+
+	log.Printf("Player %d ranking deleted successully", playerID)
+	return nil
 }
 
-func (c *Consumer) handleBattleCompleted(body []byte) {
-	var event map[string]interface{}
-	if err := json.Unmarshal(body, &event); err != nil {
-		log.Printf("Error parsing battle event: %v", err)
-		return
+func (c *Consumer) handleBattleCompleted(body []byte, version int) error {
+	var event events.BattleCompleted
+	if err := events.Decode(body, version, &event); err != nil {
+		return fmt.Errorf("%w: %v", rabbitmq.ErrMalformed, err)
 	}
 
-	winnerID := uint(event["winner_id"].(float64))
-	loserID := uint(event["loser_id"].(float64))
-	pointsWon := int(event["points_won"].(float64))
-	pointsLost := int(event["points_lost"].(float64))
+	log.Printf("Processing battle %d: Winner=%d (+%d), Loser=%d (-%d)",
+		event.BattleID, event.WinnerID, event.PointsWon, event.LoserID, event.PointsLost)
 
-	log.Printf("Processing battle: Winner=%d (+%d), Loser=%d (-%d)",
-		winnerID, pointsWon, loserID, pointsLost)
-
-	c.rankingService.UpdatePlayerRanking(winnerID, pointsWon, true)
-	c.rankingService.UpdatePlayerRanking(loserID, -pointsLost, false)
+	err := c.rankingService.RecordBattle(event.BattleID, event.WinnerID, event.LoserID, event.PointsWon, event.PointsLost)
+	if errors.Is(err, repository.ErrBattleAlreadyRecorded) {
+		log.Printf("Skipping battle %d, already recorded", event.BattleID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
 	log.Printf("Battle completed event processed")
+	return nil
 }
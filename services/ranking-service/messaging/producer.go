@@ -0,0 +1,43 @@
+package messaging
+
+import (
+	"log"
+
+	"maushold/events"
+	"maushold/rabbitmq"
+
+	"github.com/streadway/amqp"
+)
+
+type Producer struct {
+	conn *rabbitmq.Connection
+}
+
+func NewProducer(conn *rabbitmq.Connection) *Producer {
+	return &Producer{conn: conn}
+}
+
+func (p *Producer) PublishRankingEvent(event events.Event) error {
+	body, err := events.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	err = p.conn.Publish(
+		"ranking.events",
+		event.RoutingKey(),
+		amqp.Publishing{
+			ContentType: "application/json",
+			Headers:     amqp.Table{events.VersionHeader: int32(event.Version())},
+			Body:        body,
+		},
+	)
+
+	if err != nil {
+		log.Printf("Failed to publish event %s: %v", event.RoutingKey(), err)
+		return err
+	}
+
+	log.Printf("Published event: %s", event.RoutingKey())
+	return nil
+}
@@ -0,0 +1,39 @@
+-- Migration: Keep rankings per season
+-- Date: 2026-10-16
+
+-- A player now has one ranking per season, unique on (player_id, season_id)
+ALTER TABLE player_rankings DROP CONSTRAINT IF EXISTS player_rankings_player_id_key;
+ALTER TABLE player_rankings DROP CONSTRAINT IF EXISTS uni_player_rankings_player_id;
+
+-- Index for carrying players over and archiving a season
+CREATE INDEX IF NOT EXISTS idx_season_combat_power ON player_rankings(season_id, combat_power DESC, id ASC);
+
+-- Recreate the top 10K view for the current season only
+DROP MATERIALIZED VIEW IF EXISTS top_10k_players;
+CREATE MATERIALIZED VIEW top_10k_players AS
+SELECT 
+    player_id,
+    username,
+    combat_power,
+    total_points,
+    wins,
+    losses,
+    win_rate,
+    updated_at,
+    ROW_NUMBER() OVER (ORDER BY combat_power DESC, id ASC) as rank
+FROM player_rankings
+WHERE total_points > 0
+  AND season_id = (
+    SELECT id FROM seasons
+    WHERE starts_at <= NOW() AND ends_at > NOW()
+    ORDER BY id DESC
+    LIMIT 1
+  )
+ORDER BY combat_power DESC, id ASC
+LIMIT 10000;
+
+CREATE UNIQUE INDEX idx_mv_top_10k_rank ON top_10k_players(rank);
+CREATE INDEX idx_mv_top_10k_player_id ON top_10k_players(player_id);
+CREATE INDEX idx_mv_top_10k_combat_power ON top_10k_players(combat_power DESC);
+
+COMMENT ON MATERIALIZED VIEW top_10k_players IS 'Pre-computed top 10K leaderboard of the current season, refresh periodically';
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// RankSnapshot is a player's place on their season's leaderboard at the
+// time it was taken
+type RankSnapshot struct {
+	ID                 uint      `gorm:"primaryKey" json:"-"`
+	PlayerID           uint      `gorm:"not null;index:idx_snapshot_player_time" json:"-"`
+	SeasonID           uint      `json:"season_id"`
+	Rank               int       `json:"rank"`
+	CombatPower        int64     `json:"combat_power"`
+	TotalPoints        int       `json:"total_points"`
+	ConservativeRating float64   `json:"conservative_rating"`
+	TakenAt            time.Time `gorm:"not null;index:idx_snapshot_player_time;index" json:"taken_at"`
+}
+
+type RankHistory struct {
+	PlayerID  uint           `json:"player_id"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Snapshots []RankSnapshot `json:"snapshots"`
+}
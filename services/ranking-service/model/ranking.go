@@ -4,7 +4,8 @@ import "time"
 
 type PlayerRanking struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
-	PlayerID     uint      `gorm:"unique;not null;index:idx_player_id" json:"player_id"`
+	PlayerID     uint      `gorm:"not null;index:idx_player_id;uniqueIndex:idx_player_season" json:"player_id"`
+	SeasonID     uint      `gorm:"not null;default:0;uniqueIndex:idx_player_season" json:"season_id"`
 	Username     string    `json:"username"`
 	CombatPower  int64     `gorm:"default:0;index:idx_combat_power,sort:desc" json:"combat_power"`
 	TotalPoints  int       `gorm:"default:0" json:"total_points"`
@@ -16,6 +17,31 @@ type PlayerRanking struct {
 	LastBattleAt time.Time `json:"last_battle_at"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	// Glicko-2 skill rating. ConservativeRating is Rating minus two
+	// deviations, stored so the leaderboard can sort on it.
+	Rating             float64   `gorm:"default:1500" json:"rating"`
+	RatingDeviation    float64   `gorm:"default:350" json:"rating_deviation"`
+	Volatility         float64   `gorm:"default:0.06" json:"volatility"`
+	ConservativeRating float64   `gorm:"default:800;index:idx_conservative_rating,sort:desc" json:"conservative_rating"`
+	RatedAt            time.Time `json:"rated_at"` // when the deviation was last brought up to date
+}
+
+// ProcessedBattle marks a battle.completed event as recorded, so a
+// redelivered event doesn't count the same battle twice. It keeps the
+// winner's points, which the daily, weekly and monthly leaderboards are
+// rebuilt from, and is pruned once it is older than the longest window.
+type ProcessedBattle struct {
+	BattleID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	WinnerID    uint      `gorm:"not null;default:0"`
+	LoserID     uint      `gorm:"not null;default:0"`
+	PointsWon   int       `gorm:"not null;default:0"`
+	ProcessedAt time.Time `gorm:"index"`
+}
+
+// PointsGained is how many points a player gained over some period
+type PointsGained struct {
+	PlayerID uint
+	Points   int
 }
 
 type LeaderboardEntry struct {
@@ -28,6 +54,12 @@ type LeaderboardEntry struct {
 	WinRate     float64   `json:"win_rate"`
 	Rank        int       `json:"rank"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// Set on leaderboards sorted by rating
+	Rating             float64 `json:"rating,omitempty"`
+	RatingDeviation    float64 `json:"rating_deviation,omitempty"`
+	ConservativeRating float64 `json:"conservative_rating,omitempty"`
+	// Set on daily, weekly and monthly leaderboards
+	PointsGained int `json:"points_gained,omitempty"`
 }
 
 type LeaderboardMetadata struct {
@@ -35,6 +67,9 @@ type LeaderboardMetadata struct {
 	Top10KThreshold int64     `json:"top_10k_threshold"`
 	LastUpdated     time.Time `json:"last_updated"`
 	CacheHit        bool      `json:"cache_hit"`
+	// Set on daily, weekly and monthly leaderboards
+	Window       string     `json:"window,omitempty"`
+	WindowEndsAt *time.Time `json:"window_ends_at,omitempty"`
 }
 
 type LeaderboardResponse struct {
@@ -65,9 +100,11 @@ type PowerUpdateEvent struct {
 }
 
 type Battle struct {
-	ID        uint   `json:"id"`
-	Player1ID uint   `json:"player1_id"`
-	Player2ID uint   `json:"player2_id"`
-	WinnerID  uint   `json:"winner_id"`
-	Status    string `json:"status"`
+	ID          uint       `json:"id"`
+	Player1ID   uint       `json:"player1_id"`
+	Player2ID   uint       `json:"player2_id"`
+	WinnerID    uint       `json:"winner_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
 }
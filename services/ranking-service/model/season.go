@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Season is a ranked season. Battles count toward the season whose
+// [StartsAt, EndsAt) contains them; once it ends its final standings are
+// archived and ArchivedAt is set.
+type Season struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `json:"name"`
+	StartsAt   time.Time  `gorm:"not null;uniqueIndex" json:"starts_at"`
+	EndsAt     time.Time  `gorm:"not null;index" json:"ends_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// SeasonStanding is a player's final place in an ended season
+type SeasonStanding struct {
+	ID                 uint      `gorm:"primaryKey" json:"-"`
+	SeasonID           uint      `gorm:"not null;uniqueIndex:idx_season_rank" json:"season_id"`
+	Rank               int       `gorm:"not null;uniqueIndex:idx_season_rank" json:"rank"`
+	PlayerID           uint      `gorm:"not null;index" json:"player_id"`
+	Username           string    `json:"username"`
+	CombatPower        int64     `json:"combat_power"`
+	TotalPoints        int       `json:"total_points"`
+	Wins               int       `json:"wins"`
+	Losses             int       `json:"losses"`
+	WinRate            float64   `json:"win_rate"`
+	Rating             float64   `json:"rating"`
+	RatingDeviation    float64   `json:"rating_deviation"`
+	ConservativeRating float64   `json:"conservative_rating"`
+	CreatedAt          time.Time `gorm:"autoCreateTime" json:"-"`
+}
+
+type SeasonStandingsResponse struct {
+	Season    Season           `json:"season"`
+	Final     bool             `json:"final"` // false while the season is still running
+	Standings []SeasonStanding `json:"standings"`
+}
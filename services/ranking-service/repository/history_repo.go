@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"maushold/ranking-service/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HistoryRepository interface {
+	CreateSnapshots(snapshots []model.RankSnapshot) error
+	FindLatestTakenAt() (time.Time, error)
+	FindByPlayerID(playerID uint, from, to time.Time) ([]model.RankSnapshot, error)
+	DeleteBefore(cutoff time.Time) (int64, error)
+	DeleteByPlayerID(playerID uint) error
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+func NewHistoryRepository(db *gorm.DB) HistoryRepository {
+	return &historyRepository{db: db}
+}
+
+func (r *historyRepository) CreateSnapshots(snapshots []model.RankSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(&snapshots, 1000).Error
+}
+
+// FindLatestTakenAt returns when the last snapshot was taken, or the zero
+// time if there are none
+func (r *historyRepository) FindLatestTakenAt() (time.Time, error) {
+	var snapshot model.RankSnapshot
+	err := r.db.Order("taken_at DESC").First(&snapshot).Error
+	if err == gorm.ErrRecordNotFound {
+		return time.Time{}, nil
+	}
+	return snapshot.TakenAt, err
+}
+
+func (r *historyRepository) FindByPlayerID(playerID uint, from, to time.Time) ([]model.RankSnapshot, error) {
+	var snapshots []model.RankSnapshot
+	err := r.db.Where("player_id = ? AND taken_at >= ? AND taken_at <= ?", playerID, from, to).
+		Order("taken_at ASC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+func (r *historyRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("taken_at < ?", cutoff).Delete(&model.RankSnapshot{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *historyRepository) DeleteByPlayerID(playerID uint) error {
+	return r.db.Where("player_id = ?", playerID).Delete(&model.RankSnapshot{}).Error
+}
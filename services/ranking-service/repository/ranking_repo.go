@@ -1,14 +1,23 @@
 package repository
 
 import (
+	"errors"
 	"maushold/ranking-service/model"
+	"sync/atomic"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrBattleAlreadyRecorded = errors.New("battle already recorded")
+
+// RankingRepository reads and writes the rankings of the current season,
+// set with SetSeason, unless a method says otherwise
 type RankingRepository interface {
+	SetSeason(seasonID uint)
 	Create(ranking *model.PlayerRanking) error
+	CreateMissing(rankings []model.PlayerRanking) error
 	Update(ranking *model.PlayerRanking) error
 	UpdateCombatPower(playerID uint, combatPower int64) error
 	FindByPlayerID(playerID uint) (*model.PlayerRanking, error)
@@ -16,6 +25,8 @@ type RankingRepository interface {
 	FindAll() ([]model.PlayerRanking, error)
 	FindTopN(limit int) ([]model.PlayerRanking, error)
 	FindTopNByCombatPower(limit int) ([]model.PlayerRanking, error)
+	FindTopNByConservativeRating(limit int) ([]model.PlayerRanking, error)
+	FindInactiveSince(cutoff time.Time) ([]model.PlayerRanking, error)
 	GetTop10KThreshold() (int64, error)
 	GetTotalPlayerCount() (int64, error)
 	RefreshMaterializedView() error
@@ -23,28 +34,54 @@ type RankingRepository interface {
 	GetPlayerRankFromMaterializedView(playerID uint) (int, error)
 	ResetPlayerStats(playerID uint) error
 	ResetAllStats() error
-	FindByPlayerIDForUpdate(playerID uint) (*model.PlayerRanking, error)
 	DeleteByPlayerID(playerID uint) error
+	FindLatestByPlayerID(playerID uint, beforeSeasonID uint) (*model.PlayerRanking, error)
+	FindBySeason(seasonID uint) ([]model.PlayerRanking, error)
+	FindTopNInSeason(seasonID uint, limit int) ([]model.PlayerRanking, error)
+	AdoptUnseasoned(seasonID uint) error
+	SaveBattle(battle *model.ProcessedBattle, apply func(winner, loser *model.PlayerRanking)) error
+	FindPointsGainedSince(since time.Time) ([]model.PointsGained, error)
+	DeleteProcessedBattlesBefore(before time.Time) (int64, error)
 }
 
 type rankingRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	season atomic.Uint64
 }
 
 func NewRankingRepository(db *gorm.DB) RankingRepository {
 	return &rankingRepository{db: db}
 }
 
+// SetSeason sets the season the repository works in
+func (r *rankingRepository) SetSeason(seasonID uint) {
+	r.season.Store(uint64(seasonID))
+}
+
+// current scopes a query to the current season's rankings
+func (r *rankingRepository) current() *gorm.DB {
+	return r.db.Where("season_id = ?", r.season.Load())
+}
+
 func (r *rankingRepository) Create(ranking *model.PlayerRanking) error {
 	return r.db.Create(ranking).Error
 }
 
+// CreateMissing creates rankings, skipping players who already have one in
+// the ranking's season
+func (r *rankingRepository) CreateMissing(rankings []model.PlayerRanking) error {
+	if len(rankings) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rankings, 500).Error
+}
+
 func (r *rankingRepository) Update(ranking *model.PlayerRanking) error {
 	return r.db.Save(ranking).Error
 }
 
 func (r *rankingRepository) UpdateCombatPower(playerID uint, combatPower int64) error {
-	return r.db.Model(&model.PlayerRanking{}).
+	return r.current().Model(&model.PlayerRanking{}).
 		Where("player_id = ?", playerID).
 		Updates(map[string]interface{}{
 			"combat_power":   combatPower,
@@ -55,37 +92,53 @@ func (r *rankingRepository) UpdateCombatPower(playerID uint, combatPower int64)
 
 func (r *rankingRepository) FindByPlayerID(playerID uint) (*model.PlayerRanking, error) {
 	var ranking model.PlayerRanking
-	err := r.db.Where("player_id = ?", playerID).First(&ranking).Error
+	err := r.current().Where("player_id = ?", playerID).First(&ranking).Error
 	return &ranking, err
 }
 
 func (r *rankingRepository) FindByPlayerIDs(playerIDs []uint) ([]model.PlayerRanking, error) {
 	var rankings []model.PlayerRanking
-	err := r.db.Where("player_id IN ?", playerIDs).Find(&rankings).Error
+	err := r.current().Where("player_id IN ?", playerIDs).Find(&rankings).Error
 	return rankings, err
 }
 
 func (r *rankingRepository) FindAll() ([]model.PlayerRanking, error) {
 	var rankings []model.PlayerRanking
-	err := r.db.Order("combat_power DESC, id ASC").Find(&rankings).Error
+	err := r.current().Order("combat_power DESC, id ASC").Find(&rankings).Error
 	return rankings, err
 }
 
 func (r *rankingRepository) FindTopN(limit int) ([]model.PlayerRanking, error) {
 	var rankings []model.PlayerRanking
-	err := r.db.Order("total_points DESC").Limit(limit).Find(&rankings).Error
+	err := r.current().Order("total_points DESC").Limit(limit).Find(&rankings).Error
 	return rankings, err
 }
 
 func (r *rankingRepository) FindTopNByCombatPower(limit int) ([]model.PlayerRanking, error) {
 	var rankings []model.PlayerRanking
-	err := r.db.Where("total_points > 0").Order("combat_power DESC, id ASC").Limit(limit).Find(&rankings).Error
+	err := r.current().Where("total_points > 0").Order("combat_power DESC, id ASC").Limit(limit).Find(&rankings).Error
+	return rankings, err
+}
+
+func (r *rankingRepository) FindTopNByConservativeRating(limit int) ([]model.PlayerRanking, error) {
+	var rankings []model.PlayerRanking
+	err := r.current().Where("total_battles > 0").Order("conservative_rating DESC, id ASC").Limit(limit).Find(&rankings).Error
+	return rankings, err
+}
+
+// FindInactiveSince returns players who haven't battled since cutoff and
+// whose deviation hasn't been decayed since either
+func (r *rankingRepository) FindInactiveSince(cutoff time.Time) ([]model.PlayerRanking, error) {
+	var rankings []model.PlayerRanking
+	err := r.current().Where("last_battle_at < ? AND (rated_at IS NULL OR rated_at < ?)", cutoff, cutoff).
+		Where("rating_deviation < ?", 350). // already as uncertain as a new player
+		Find(&rankings).Error
 	return rankings, err
 }
 
 func (r *rankingRepository) GetTop10KThreshold() (int64, error) {
 	var threshold int64
-	err := r.db.Model(&model.PlayerRanking{}).
+	err := r.current().Model(&model.PlayerRanking{}).
 		Select("combat_power").
 		Order("combat_power DESC").
 		Limit(1).
@@ -100,7 +153,7 @@ func (r *rankingRepository) GetTop10KThreshold() (int64, error) {
 
 func (r *rankingRepository) GetTotalPlayerCount() (int64, error) {
 	var count int64
-	err := r.db.Model(&model.PlayerRanking{}).Count(&count).Error
+	err := r.current().Model(&model.PlayerRanking{}).Count(&count).Error
 	return count, err
 }
 
@@ -132,7 +185,7 @@ func (r *rankingRepository) GetPlayerRankFromMaterializedView(playerID uint) (in
 }
 
 func (r *rankingRepository) ResetPlayerStats(playerID uint) error {
-	return r.db.Model(&model.PlayerRanking{}).
+	return r.current().Model(&model.PlayerRanking{}).
 		Where("player_id = ?", playerID).
 		Updates(map[string]interface{}{
 			"wins":          0,
@@ -143,8 +196,7 @@ func (r *rankingRepository) ResetPlayerStats(playerID uint) error {
 }
 
 func (r *rankingRepository) ResetAllStats() error {
-	return r.db.Model(&model.PlayerRanking{}).
-		Where("1 = 1").
+	return r.current().Model(&model.PlayerRanking{}).
 		Updates(map[string]interface{}{
 			"wins":          0,
 			"losses":        0,
@@ -153,12 +205,112 @@ func (r *rankingRepository) ResetAllStats() error {
 		}).Error
 }
 
-func (r *rankingRepository) FindByPlayerIDForUpdate(playerID uint) (*model.PlayerRanking, error) {
+// findByPlayerIDForUpdate locks a player's ranking in the current season
+// until tx ends
+func (r *rankingRepository) findByPlayerIDForUpdate(tx *gorm.DB, playerID uint) (*model.PlayerRanking, error) {
 	var ranking model.PlayerRanking
-	err := r.db.Set("gorm:query_option", "FOR UPDATE").Where("player_id = ?", playerID).First(&ranking).Error
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("season_id = ? AND player_id = ?", r.season.Load(), playerID).
+		First(&ranking).Error
 	return &ranking, err
 }
 
+// DeleteByPlayerID deletes a player's rankings in every season
 func (r *rankingRepository) DeleteByPlayerID(playerID uint) error {
 	return r.db.Where("player_id = ?", playerID).Delete(&model.PlayerRanking{}).Error
 }
+
+// FindLatestByPlayerID returns a player's ranking from the last season
+// before beforeSeasonID they were ranked in
+func (r *rankingRepository) FindLatestByPlayerID(playerID uint, beforeSeasonID uint) (*model.PlayerRanking, error) {
+	var ranking model.PlayerRanking
+	err := r.db.Where("player_id = ? AND season_id > 0 AND season_id < ?", playerID, beforeSeasonID).
+		Order("season_id DESC").
+		First(&ranking).Error
+	return &ranking, err
+}
+
+// FindBySeason returns every ranking in a season, highest combat power first
+func (r *rankingRepository) FindBySeason(seasonID uint) ([]model.PlayerRanking, error) {
+	var rankings []model.PlayerRanking
+	err := r.db.Where("season_id = ?", seasonID).Order("combat_power DESC, id ASC").Find(&rankings).Error
+	return rankings, err
+}
+
+// FindTopNInSeason returns the players who battled in a season, highest
+// combat power first
+func (r *rankingRepository) FindTopNInSeason(seasonID uint, limit int) ([]model.PlayerRanking, error) {
+	var rankings []model.PlayerRanking
+	err := r.db.Where("season_id = ? AND total_battles > 0", seasonID).
+		Order("combat_power DESC, id ASC").
+		Limit(limit).
+		Find(&rankings).Error
+	return rankings, err
+}
+
+// SaveBattle marks a battle recorded and applies it to the winner's and
+// loser's rankings in the current season in one transaction. Both rankings
+// must exist; they are locked while apply changes them, so battles recorded
+// at the same time for the same player don't overwrite each other. A battle
+// that was already recorded changes nothing and returns
+// ErrBattleAlreadyRecorded.
+func (r *rankingRepository) SaveBattle(battle *model.ProcessedBattle, apply func(winner, loser *model.PlayerRanking)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(battle)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBattleAlreadyRecorded
+		}
+
+		// Lock in player ID order, so two battles between the same players
+		// can't deadlock
+		first, second := battle.WinnerID, battle.LoserID
+		if first > second {
+			first, second = second, first
+		}
+		locked := make(map[uint]*model.PlayerRanking, 2)
+		for _, playerID := range []uint{first, second} {
+			ranking, err := r.findByPlayerIDForUpdate(tx, playerID)
+			if err != nil {
+				return err
+			}
+			locked[playerID] = ranking
+		}
+
+		winner, loser := locked[battle.WinnerID], locked[battle.LoserID]
+		apply(winner, loser)
+		if err := tx.Save(winner).Error; err != nil {
+			return err
+		}
+		return tx.Save(loser).Error
+	})
+}
+
+// FindPointsGainedSince totals the points each player still ranked has won
+// in battles recorded since the given time
+func (r *rankingRepository) FindPointsGainedSince(since time.Time) ([]model.PointsGained, error) {
+	var gained []model.PointsGained
+	err := r.db.Model(&model.ProcessedBattle{}).
+		Select("winner_id AS player_id, SUM(points_won) AS points").
+		Where("processed_at >= ? AND points_won > 0", since).
+		Where("winner_id IN (?)", r.db.Model(&model.PlayerRanking{}).Select("player_id")).
+		Group("winner_id").
+		Scan(&gained).Error
+	return gained, err
+}
+
+// DeleteProcessedBattlesBefore forgets battles recorded before the given
+// time
+func (r *rankingRepository) DeleteProcessedBattlesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("processed_at < ?", before).Delete(&model.ProcessedBattle{})
+	return result.RowsAffected, result.Error
+}
+
+// AdoptUnseasoned moves rankings from before seasons existed into a season
+func (r *rankingRepository) AdoptUnseasoned(seasonID uint) error {
+	return r.db.Model(&model.PlayerRanking{}).
+		Where("season_id = 0").
+		Update("season_id", seasonID).Error
+}
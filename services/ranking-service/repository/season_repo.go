@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"maushold/ranking-service/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SeasonRepository interface {
+	Create(season *model.Season) error
+	FindByID(id uint) (*model.Season, error)
+	FindAll() ([]model.Season, error)
+	FindCurrent(at time.Time) (*model.Season, error)
+	FindLatest() (*model.Season, error)
+	Count() (int64, error)
+	FindEndedUnarchived(at time.Time) ([]model.Season, error)
+	Archive(season *model.Season, standings []model.SeasonStanding) error
+	FindStandings(seasonID uint, limit int) ([]model.SeasonStanding, error)
+}
+
+type seasonRepository struct {
+	db *gorm.DB
+}
+
+func NewSeasonRepository(db *gorm.DB) SeasonRepository {
+	return &seasonRepository{db: db}
+}
+
+func (r *seasonRepository) Create(season *model.Season) error {
+	return r.db.Create(season).Error
+}
+
+func (r *seasonRepository) FindByID(id uint) (*model.Season, error) {
+	var season model.Season
+	err := r.db.First(&season, id).Error
+	return &season, err
+}
+
+func (r *seasonRepository) FindAll() ([]model.Season, error) {
+	var seasons []model.Season
+	err := r.db.Order("starts_at DESC").Find(&seasons).Error
+	return seasons, err
+}
+
+// FindCurrent returns the season running at the given time
+func (r *seasonRepository) FindCurrent(at time.Time) (*model.Season, error) {
+	var season model.Season
+	err := r.db.Where("starts_at <= ? AND ends_at > ?", at, at).First(&season).Error
+	return &season, err
+}
+
+func (r *seasonRepository) FindLatest() (*model.Season, error) {
+	var season model.Season
+	err := r.db.Order("ends_at DESC").First(&season).Error
+	return &season, err
+}
+
+func (r *seasonRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Season{}).Count(&count).Error
+	return count, err
+}
+
+// FindEndedUnarchived returns seasons that ended by the given time but whose
+// standings haven't been archived yet, oldest first
+func (r *seasonRepository) FindEndedUnarchived(at time.Time) ([]model.Season, error) {
+	var seasons []model.Season
+	err := r.db.Where("ends_at <= ? AND archived_at IS NULL", at).Order("ends_at ASC").Find(&seasons).Error
+	return seasons, err
+}
+
+// Archive stores a season's final standings and marks it archived in one
+// transaction, so a season is never archived twice
+func (r *seasonRepository) Archive(season *model.Season, standings []model.SeasonStanding) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.Season{}).
+			Where("id = ? AND archived_at IS NULL", season.ID).
+			Update("archived_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if len(standings) > 0 {
+			if err := tx.CreateInBatches(&standings, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		season.ArchivedAt = &now
+		return nil
+	})
+}
+
+func (r *seasonRepository) FindStandings(seasonID uint, limit int) ([]model.SeasonStanding, error) {
+	var standings []model.SeasonStanding
+	err := r.db.Where("season_id = ?", seasonID).Order("rank ASC").Limit(limit).Find(&standings).Error
+	return standings, err
+}
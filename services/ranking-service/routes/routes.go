@@ -3,24 +3,30 @@ package routes
 import (
 	"net/http"
 
+	"maushold/auth"
 	"maushold/ranking-service/handler"
 
 	"github.com/gorilla/mux"
 	"github.com/unifuu/lapras"
 )
 
-func SetupRankingRoutes(router *mux.Router, handler *handler.RankingHandler) {
+func SetupRankingRoutes(router *mux.Router, handler *handler.RankingHandler, keys *auth.Keys) {
 	router.Use(lapras.Cors)
+	router.Use(keys.Middleware())
 
 	// Public endpoints
 	router.HandleFunc("/rankings", handler.GetLeaderboard).Methods(http.MethodGet)
 	router.HandleFunc("/rankings/player/{playerId}", handler.GetPlayerRanking).Methods(http.MethodGet)
 	router.HandleFunc("/rankings/player/{playerId}/context", handler.GetPlayerRankWithContext).Methods(http.MethodGet)
+	router.HandleFunc("/rankings/player/{playerId}/history", handler.GetRankHistory).Methods(http.MethodGet)
+	router.HandleFunc("/rankings/seasons", handler.GetSeasons).Methods(http.MethodGet)
+	router.HandleFunc("/rankings/seasons/current", handler.GetCurrentSeason).Methods(http.MethodGet)
+	router.HandleFunc("/rankings/seasons/{id}", handler.GetSeasonStandings).Methods(http.MethodGet)
 
-	// Internal/admin endpoints
-	router.HandleFunc("/rankings/combat-power", handler.UpdateCombatPower).Methods(http.MethodPost)
-	router.HandleFunc("/rankings/sync", handler.SyncRankings).Methods(http.MethodPost)
-	router.HandleFunc("/rankings/refresh-view", handler.RefreshMaterializedView).Methods(http.MethodPost)
+	// Admin endpoints
+	router.HandleFunc("/rankings/combat-power", auth.RequireRole(auth.RoleAdmin, handler.UpdateCombatPower)).Methods(http.MethodPost)
+	router.HandleFunc("/rankings/sync", auth.RequireRole(auth.RoleAdmin, handler.SyncRankings)).Methods(http.MethodPost)
+	router.HandleFunc("/rankings/refresh-view", auth.RequireRole(auth.RoleAdmin, handler.RefreshMaterializedView)).Methods(http.MethodPost)
 
 	// Health check
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
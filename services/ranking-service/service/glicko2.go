@@ -0,0 +1,136 @@
+package service
+
+import (
+	"math"
+	"time"
+)
+
+// Glicko-2 as described by Glickman. Every battle is treated as its own
+// rating period, and a player who sits out whole RatingPeriods has their
+// deviation grow as if they had played a period with no games.
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+	MinDeviation      = 30.0 // keeps ratings from freezing after many games
+	RatingPeriod      = 24 * time.Hour
+
+	glickoTau       = 0.5 // how fast volatility can change
+	glickoScale     = 173.7178
+	glickoEpsilon   = 0.000001
+	conservativeRDs = 2 // deviations subtracted for the conservative rating
+)
+
+type Glicko2Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// GlickoResult is one game against an opponent: 1 for a win, 0 for a loss
+type GlickoResult struct {
+	Opponent Glicko2Rating
+	Score    float64
+}
+
+// Conservative is the rating the player is very likely above, so players
+// with few games don't top the leaderboard on one lucky streak
+func (r Glicko2Rating) Conservative() float64 {
+	return r.Rating - conservativeRDs*r.Deviation
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func glickoE(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-glickoG(phiJ)*(mu-muJ)))
+}
+
+// UpdateGlicko2 returns the player's rating after the given games
+func UpdateGlicko2(player Glicko2Rating, results []GlickoResult) Glicko2Rating {
+	mu := (player.Rating - DefaultRating) / glickoScale
+	phi := player.Deviation / glickoScale
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		return DecayGlicko2(player, 1)
+	}
+
+	var vInv, deltaSum float64
+	for _, result := range results {
+		muJ := (result.Opponent.Rating - DefaultRating) / glickoScale
+		phiJ := result.Opponent.Deviation / glickoScale
+		g := glickoG(phiJ)
+		e := glickoE(mu, muJ, phiJ)
+		vInv += g * g * e * (1 - e)
+		deltaSum += g * (result.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * deltaSum
+
+	sigma = glickoVolatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * deltaSum
+
+	return Glicko2Rating{
+		Rating:     mu*glickoScale + DefaultRating,
+		Deviation:  math.Max(phi*glickoScale, MinDeviation),
+		Volatility: sigma,
+	}
+}
+
+// glickoVolatility finds the new volatility with the Illinois algorithm
+func glickoVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+// DecayGlicko2 grows the deviation of a player who played no games for the
+// given number of rating periods, never past that of a new player
+func DecayGlicko2(player Glicko2Rating, periods float64) Glicko2Rating {
+	phi := player.Deviation / glickoScale
+	phi = math.Sqrt(phi*phi + periods*player.Volatility*player.Volatility)
+	player.Deviation = math.Min(phi*glickoScale, DefaultDeviation)
+	return player
+}
+
+// inactivePeriods counts the whole rating periods between since and now
+func inactivePeriods(since, now time.Time) int {
+	if since.IsZero() || now.Before(since) {
+		return 0
+	}
+	return int(now.Sub(since) / RatingPeriod)
+}
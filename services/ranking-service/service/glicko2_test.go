@@ -0,0 +1,67 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// The worked example from Glickman's "Example of the Glicko-2 system"
+func TestUpdateGlicko2WorkedExample(t *testing.T) {
+	player := Glicko2Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []GlickoResult{
+		{Opponent: Glicko2Rating{Rating: 1400, Deviation: 30, Volatility: 0.06}, Score: 1},
+		{Opponent: Glicko2Rating{Rating: 1550, Deviation: 100, Volatility: 0.06}, Score: 0},
+		{Opponent: Glicko2Rating{Rating: 1700, Deviation: 300, Volatility: 0.06}, Score: 0},
+	}
+
+	got := UpdateGlicko2(player, results)
+	if math.Abs(got.Rating-1464.06) > 0.01 {
+		t.Errorf("rating = %.2f, want 1464.06", got.Rating)
+	}
+	if math.Abs(got.Deviation-151.52) > 0.01 {
+		t.Errorf("deviation = %.2f, want 151.52", got.Deviation)
+	}
+	if math.Abs(got.Volatility-0.05999) > 0.00001 {
+		t.Errorf("volatility = %.5f, want 0.05999", got.Volatility)
+	}
+}
+
+func TestUpdateGlicko2WithoutGamesOnlyDecays(t *testing.T) {
+	player := Glicko2Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+
+	got := UpdateGlicko2(player, nil)
+	if got.Rating != player.Rating || got.Volatility != player.Volatility {
+		t.Errorf("got %+v, want only the deviation to change from %+v", got, player)
+	}
+	// Glickman's example: phi* = sqrt(phi^2 + sigma^2) on the Glicko-2 scale
+	if math.Abs(got.Deviation-200.27) > 0.01 {
+		t.Errorf("deviation = %.2f, want 200.27", got.Deviation)
+	}
+}
+
+func TestDecayGlicko2CapsAtNewPlayerDeviation(t *testing.T) {
+	got := DecayGlicko2(Glicko2Rating{Rating: 1700, Deviation: 340, Volatility: 0.06}, 1000)
+	if got.Deviation != DefaultDeviation {
+		t.Errorf("deviation = %.2f, want %.0f", got.Deviation, DefaultDeviation)
+	}
+}
+
+func TestInactivePeriods(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since time.Time
+		want  int
+	}{
+		{time.Time{}, 0},
+		{now.Add(time.Hour), 0},
+		{now.Add(-23 * time.Hour), 0},
+		{now.Add(-RatingPeriod), 1},
+		{now.Add(-3*RatingPeriod - time.Hour), 3},
+	}
+	for _, tt := range tests {
+		if got := inactivePeriods(tt.since, now); got != tt.want {
+			t.Errorf("inactivePeriods(%s) = %d, want %d", tt.since, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"maushold/ranking-service/model"
+)
+
+const (
+	SnapshotInterval   = time.Hour
+	SnapshotRetention  = 90 * 24 * time.Hour
+	DefaultHistorySpan = 30 * 24 * time.Hour
+)
+
+var ErrInvalidHistoryRange = errors.New("from must be before to")
+
+// SnapshotRanks records every player's rank, combat power and points in the
+// current season, at most once per SnapshotInterval across all instances,
+// and prunes snapshots older than SnapshotRetention
+func (s *rankingService) SnapshotRanks() error {
+	season, err := s.currentSeason()
+	if err != nil {
+		return err
+	}
+
+	locked, err := s.leaderboardService.AcquireLock(HistoryLockKey)
+	if err != nil || !locked {
+		return err
+	}
+	defer s.leaderboardService.ReleaseLock(HistoryLockKey)
+
+	now := time.Now()
+	latest, err := s.historyRepo.FindLatestTakenAt()
+	if err != nil {
+		return err
+	}
+	if now.Sub(latest) < SnapshotInterval {
+		return nil
+	}
+
+	rankings, err := s.repo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	// FindAll is ordered by combat power, the same order as the leaderboard
+	snapshots := make([]model.RankSnapshot, len(rankings))
+	for i, r := range rankings {
+		snapshots[i] = model.RankSnapshot{
+			PlayerID:           r.PlayerID,
+			SeasonID:           season.ID,
+			Rank:               i + 1,
+			CombatPower:        r.CombatPower,
+			TotalPoints:        r.TotalPoints,
+			ConservativeRating: r.ConservativeRating,
+			TakenAt:            now,
+		}
+	}
+	if err := s.historyRepo.CreateSnapshots(snapshots); err != nil {
+		return err
+	}
+
+	pruned, err := s.historyRepo.DeleteBefore(now.Add(-SnapshotRetention))
+	if err != nil {
+		log.Printf("Failed to prune rank history: %v", err)
+	}
+
+	log.Printf("Took rank snapshot of %d players, pruned %d old snapshots", len(snapshots), pruned)
+	return nil
+}
+
+// GetRankHistory returns a player's snapshots between from and to. A zero
+// to means now and a zero from means DefaultHistorySpan before to.
+func (s *rankingService) GetRankHistory(playerID uint, from, to time.Time) (*model.RankHistory, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-DefaultHistorySpan)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidHistoryRange
+	}
+
+	snapshots, err := s.historyRepo.FindByPlayerID(playerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RankHistory{
+		PlayerID:  playerID,
+		From:      from,
+		To:        to,
+		Snapshots: snapshots,
+	}, nil
+}
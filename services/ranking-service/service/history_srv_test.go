@@ -0,0 +1,138 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"maushold/ranking-service/model"
+	"maushold/ranking-service/repository"
+)
+
+type fakeSeasons struct {
+	repository.SeasonRepository
+	season *model.Season
+}
+
+func (f *fakeSeasons) FindCurrent(at time.Time) (*model.Season, error) {
+	return f.season, nil
+}
+
+type fakeRankings struct {
+	repository.RankingRepository
+	rankings []model.PlayerRanking // in FindAll order
+}
+
+func (f *fakeRankings) SetSeason(seasonID uint) {}
+
+func (f *fakeRankings) FindAll() ([]model.PlayerRanking, error) {
+	return f.rankings, nil
+}
+
+type fakeHistory struct {
+	repository.HistoryRepository
+	snapshots []model.RankSnapshot
+}
+
+func (f *fakeHistory) CreateSnapshots(snapshots []model.RankSnapshot) error {
+	f.snapshots = append(f.snapshots, snapshots...)
+	return nil
+}
+
+func (f *fakeHistory) FindLatestTakenAt() (time.Time, error) {
+	var latest time.Time
+	for _, s := range f.snapshots {
+		if s.TakenAt.After(latest) {
+			latest = s.TakenAt
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeHistory) DeleteBefore(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeHistory) FindByPlayerID(playerID uint, from, to time.Time) ([]model.RankSnapshot, error) {
+	var found []model.RankSnapshot
+	for _, s := range f.snapshots {
+		if s.PlayerID == playerID && !s.TakenAt.Before(from) && !s.TakenAt.After(to) {
+			found = append(found, s)
+		}
+	}
+	return found, nil
+}
+
+func newHistoryFixture(t *testing.T, rankings ...model.PlayerRanking) (*rankingService, *fakeHistory) {
+	t.Helper()
+	leaderboard, _ := newTestLeaderboard(t)
+	now := time.Now()
+	history := &fakeHistory{}
+	s := &rankingService{
+		repo:               &fakeRankings{rankings: rankings},
+		seasonRepo:         &fakeSeasons{season: &model.Season{ID: 2, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}},
+		historyRepo:        history,
+		leaderboardService: leaderboard,
+	}
+	return s, history
+}
+
+func TestSnapshotRanksFollowsLeaderboardOrder(t *testing.T) {
+	s, history := newHistoryFixture(t,
+		model.PlayerRanking{PlayerID: 9, CombatPower: 90000, TotalPoints: 900, ConservativeRating: 1700},
+		model.PlayerRanking{PlayerID: 4, CombatPower: 50000, TotalPoints: 500, ConservativeRating: 1750},
+		model.PlayerRanking{PlayerID: 6, CombatPower: 10000, TotalPoints: 100, ConservativeRating: 900},
+	)
+
+	if err := s.SnapshotRanks(); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.snapshots) != 3 {
+		t.Fatalf("took %d snapshots, want 3", len(history.snapshots))
+	}
+	takenAt := history.snapshots[0].TakenAt
+	for i, want := range []struct {
+		playerID uint
+		points   int
+	}{{9, 900}, {4, 500}, {6, 100}} {
+		got := history.snapshots[i]
+		if got.PlayerID != want.playerID || got.Rank != i+1 || got.TotalPoints != want.points || got.SeasonID != 2 {
+			t.Errorf("snapshot %d = player %d rank %d with %d points in season %d, want player %d rank %d with %d points in season 2",
+				i, got.PlayerID, got.Rank, got.TotalPoints, got.SeasonID, want.playerID, i+1, want.points)
+		}
+		if !got.TakenAt.Equal(takenAt) {
+			t.Errorf("snapshot %d taken at %s, want every snapshot taken at %s", i, got.TakenAt, takenAt)
+		}
+	}
+
+	// The next snapshot isn't due for SnapshotInterval
+	if err := s.SnapshotRanks(); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.snapshots) != 3 {
+		t.Errorf("took %d snapshots, want no more until SnapshotInterval has passed", len(history.snapshots))
+	}
+}
+
+func TestGetRankHistory(t *testing.T) {
+	s, history := newHistoryFixture(t)
+	now := time.Now()
+	for _, daysAgo := range []int{40, 20, 10, 1} {
+		history.snapshots = append(history.snapshots, model.RankSnapshot{PlayerID: 4, TakenAt: now.AddDate(0, 0, -daysAgo)})
+	}
+	history.snapshots = append(history.snapshots, model.RankSnapshot{PlayerID: 5, TakenAt: now.AddDate(0, 0, -2)})
+
+	got, err := s.GetRankHistory(4, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Snapshots) != 3 {
+		t.Errorf("got %d snapshots, want the 3 from the last %s", len(got.Snapshots), DefaultHistorySpan)
+	}
+	if span := got.To.Sub(got.From); span != DefaultHistorySpan {
+		t.Errorf("history spans %s, want %s", span, DefaultHistorySpan)
+	}
+
+	if _, err := s.GetRankHistory(4, now, now.Add(-time.Hour)); err != ErrInvalidHistoryRange {
+		t.Errorf("from after to: err = %v, want %v", err, ErrInvalidHistoryRange)
+	}
+}
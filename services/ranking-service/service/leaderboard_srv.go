@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"maushold/ranking-service/model"
@@ -12,22 +13,32 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// Leaderboard keys are per season, formatted with the season ID
 const (
-	LeaderboardKey      = "leaderboard:global"
-	ThresholdKey        = "leaderboard:threshold"
-	TotalPlayersKey     = "leaderboard:total_players"
-	LastSyncKey         = "leaderboard:last_sync"
-	SyncLockKey         = "leaderboard:sync_lock"
-	PlayerDetailsPrefix = "player:"
-	Top10KLimit         = 10000
-	LockTimeout         = 5 * time.Minute
-	PlayerCacheTTL      = 24 * time.Hour
+	LeaderboardKey       = "leaderboard:season:%d:global"
+	RatingLeaderboardKey = "leaderboard:season:%d:rating"
+	ThresholdKey         = "leaderboard:season:%d:threshold"
+	TotalPlayersKey      = "leaderboard:season:%d:total_players"
+	PlayerDetailsPrefix  = "leaderboard:season:%d:player:"
+)
+
+const (
+	LastSyncKey    = "leaderboard:last_sync"
+	SyncLockKey    = "leaderboard:sync_lock"
+	DecayLockKey   = "leaderboard:decay_lock"
+	SeasonLockKey  = "leaderboard:season_lock"
+	HistoryLockKey = "leaderboard:history_lock"
+	Top10KLimit    = 10000
+	LockTimeout    = 5 * time.Minute
+	PlayerCacheTTL = 24 * time.Hour
+	EndedSeasonTTL = 7 * 24 * time.Hour // how long an ended season's leaderboards are kept
 )
 
 type LeaderboardService struct {
 	redis      *redis.Client
 	ctx        context.Context
 	instanceID string
+	season     atomic.Uint64
 }
 
 func NewLeaderboardService(redisClient *redis.Client) *LeaderboardService {
@@ -38,11 +49,25 @@ func NewLeaderboardService(redisClient *redis.Client) *LeaderboardService {
 	}
 }
 
+// SetSeason sets the season whose leaderboards are read and written
+func (s *LeaderboardService) SetSeason(seasonID uint) {
+	s.season.Store(uint64(seasonID))
+}
+
+// key formats a per-season key for the current season
+func (s *LeaderboardService) key(format string) string {
+	return fmt.Sprintf(format, s.season.Load())
+}
+
+func (s *LeaderboardService) playerKey(playerID uint) string {
+	return fmt.Sprintf("%s%d", s.key(PlayerDetailsPrefix), playerID)
+}
+
 // UpdatePlayerScore updates a player's score with threshold checking
 func (s *LeaderboardService) UpdatePlayerScore(playerID uint, combatPower int64) error {
 	// If combat power is 0 or less, remove from leaderboard
 	if combatPower <= 0 {
-		return s.redis.ZRem(s.ctx, LeaderboardKey, fmt.Sprintf("%d", playerID)).Err()
+		return s.redis.ZRem(s.ctx, s.key(LeaderboardKey), fmt.Sprintf("%d", playerID)).Err()
 	}
 
 	// Get current threshold
@@ -54,7 +79,7 @@ func (s *LeaderboardService) UpdatePlayerScore(playerID uint, combatPower int64)
 	// Only update Redis if player is in or above top 10K threshold
 	if combatPower >= threshold {
 		// Add to sorted set
-		err := s.redis.ZAdd(s.ctx, LeaderboardKey, &redis.Z{
+		err := s.redis.ZAdd(s.ctx, s.key(LeaderboardKey), &redis.Z{
 			Score:  float64(combatPower),
 			Member: fmt.Sprintf("%d", playerID),
 		}).Err()
@@ -63,7 +88,7 @@ func (s *LeaderboardService) UpdatePlayerScore(playerID uint, combatPower int64)
 		}
 
 		// Trim to top 10K
-		err = s.redis.ZRemRangeByRank(s.ctx, LeaderboardKey, 0, -Top10KLimit-1).Err()
+		err = s.redis.ZRemRangeByRank(s.ctx, s.key(LeaderboardKey), 0, -Top10KLimit-1).Err()
 		if err != nil {
 			return err
 		}
@@ -77,7 +102,7 @@ func (s *LeaderboardService) UpdatePlayerScore(playerID uint, combatPower int64)
 
 // UpdatePlayerScoreForce forces update regardless of threshold (for initial sync)
 func (s *LeaderboardService) UpdatePlayerScoreForce(playerID uint, combatPower int64) error {
-	return s.redis.ZAdd(s.ctx, LeaderboardKey, &redis.Z{
+	return s.redis.ZAdd(s.ctx, s.key(LeaderboardKey), &redis.Z{
 		Score:  float64(combatPower),
 		Member: fmt.Sprintf("%d", playerID),
 	}).Err()
@@ -92,7 +117,7 @@ func (s *LeaderboardService) BatchUpdatePlayerScores(players []model.PlayerRanki
 	pipe := s.redis.Pipeline()
 
 	for _, player := range players {
-		pipe.ZAdd(s.ctx, LeaderboardKey, &redis.Z{
+		pipe.ZAdd(s.ctx, s.key(LeaderboardKey), &redis.Z{
 			Score:  float64(player.CombatPower),
 			Member: fmt.Sprintf("%d", player.PlayerID),
 		})
@@ -104,12 +129,69 @@ func (s *LeaderboardService) BatchUpdatePlayerScores(players []model.PlayerRanki
 	}
 
 	// Trim to top 10K
-	return s.redis.ZRemRangeByRank(s.ctx, LeaderboardKey, 0, -Top10KLimit-1).Err()
+	return s.redis.ZRemRangeByRank(s.ctx, s.key(LeaderboardKey), 0, -Top10KLimit-1).Err()
+}
+
+// UpdatePlayerRating updates a player's place on the leaderboard sorted by
+// conservative rating
+func (s *LeaderboardService) UpdatePlayerRating(playerID uint, conservativeRating float64) error {
+	err := s.redis.ZAdd(s.ctx, s.key(RatingLeaderboardKey), &redis.Z{
+		Score:  conservativeRating,
+		Member: fmt.Sprintf("%d", playerID),
+	}).Err()
+	if err != nil {
+		return err
+	}
+
+	return s.redis.ZRemRangeByRank(s.ctx, s.key(RatingLeaderboardKey), 0, -Top10KLimit-1).Err()
+}
+
+// BatchUpdatePlayerRatings updates the rating leaderboard for many players
+func (s *LeaderboardService) BatchUpdatePlayerRatings(players []model.PlayerRanking) error {
+	if len(players) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+
+	for _, player := range players {
+		pipe.ZAdd(s.ctx, s.key(RatingLeaderboardKey), &redis.Z{
+			Score:  player.ConservativeRating,
+			Member: fmt.Sprintf("%d", player.PlayerID),
+		})
+	}
+
+	_, err := pipe.Exec(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.redis.ZRemRangeByRank(s.ctx, s.key(RatingLeaderboardKey), 0, -Top10KLimit-1).Err()
+}
+
+// GetTopPlayersByRating returns the top N players by conservative rating
+func (s *LeaderboardService) GetTopPlayersByRating(limit int) ([]model.LeaderboardEntry, error) {
+	result, err := s.redis.ZRevRangeWithScores(s.ctx, s.key(RatingLeaderboardKey), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.LeaderboardEntry, 0, len(result))
+	for i, z := range result {
+		playerID, _ := strconv.ParseUint(z.Member.(string), 10, 64)
+		entries = append(entries, model.LeaderboardEntry{
+			PlayerID:           uint(playerID),
+			ConservativeRating: z.Score,
+			Rank:               i + 1,
+		})
+	}
+
+	return entries, nil
 }
 
 // GetTopPlayers returns top N players from Redis
 func (s *LeaderboardService) GetTopPlayers(limit int) ([]model.LeaderboardEntry, error) {
-	result, err := s.redis.ZRevRangeWithScores(s.ctx, LeaderboardKey, 0, int64(limit-1)).Result()
+	result, err := s.redis.ZRevRangeWithScores(s.ctx, s.key(LeaderboardKey), 0, int64(limit-1)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +211,7 @@ func (s *LeaderboardService) GetTopPlayers(limit int) ([]model.LeaderboardEntry,
 
 // GetPlayerRank returns the rank of a specific player (1-indexed)
 func (s *LeaderboardService) GetPlayerRank(playerID uint) (int64, error) {
-	rank, err := s.redis.ZRevRank(s.ctx, LeaderboardKey, fmt.Sprintf("%d", playerID)).Result()
+	rank, err := s.redis.ZRevRank(s.ctx, s.key(LeaderboardKey), fmt.Sprintf("%d", playerID)).Result()
 	if err == redis.Nil {
 		return 0, nil // Player not in top 10K
 	}
@@ -141,7 +223,7 @@ func (s *LeaderboardService) GetPlayerRank(playerID uint) (int64, error) {
 
 // GetPlayerScore returns the combat power of a specific player
 func (s *LeaderboardService) GetPlayerScore(playerID uint) (int64, error) {
-	score, err := s.redis.ZScore(s.ctx, LeaderboardKey, fmt.Sprintf("%d", playerID)).Result()
+	score, err := s.redis.ZScore(s.ctx, s.key(LeaderboardKey), fmt.Sprintf("%d", playerID)).Result()
 	if err == redis.Nil {
 		return 0, nil
 	}
@@ -159,7 +241,7 @@ func (s *LeaderboardService) GetPlayersAroundRank(rank int64, context int) ([]mo
 	}
 	end := rank + int64(context) - 1
 
-	result, err := s.redis.ZRevRangeWithScores(s.ctx, LeaderboardKey, start, end).Result()
+	result, err := s.redis.ZRevRangeWithScores(s.ctx, s.key(LeaderboardKey), start, end).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +261,7 @@ func (s *LeaderboardService) GetPlayersAroundRank(rank int64, context int) ([]mo
 
 // CachePlayerDetails caches player details in Redis hash
 func (s *LeaderboardService) CachePlayerDetails(player *model.PlayerRanking) error {
-	key := fmt.Sprintf("%s%d", PlayerDetailsPrefix, player.PlayerID)
+	key := s.playerKey(player.PlayerID)
 
 	data := map[string]interface{}{
 		"username":     player.Username,
@@ -189,6 +271,11 @@ func (s *LeaderboardService) CachePlayerDetails(player *model.PlayerRanking) err
 		"losses":       player.Losses,
 		"win_rate":     player.WinRate,
 		"updated_at":   player.UpdatedAt.Format(time.RFC3339),
+
+		"rating":              player.Rating,
+		"rating_deviation":    player.RatingDeviation,
+		"volatility":          player.Volatility,
+		"conservative_rating": player.ConservativeRating,
 	}
 
 	pipe := s.redis.Pipeline()
@@ -201,37 +288,52 @@ func (s *LeaderboardService) CachePlayerDetails(player *model.PlayerRanking) err
 
 // GetPlayerDetails retrieves cached player details
 func (s *LeaderboardService) GetPlayerDetails(playerID uint) (*model.PlayerRanking, error) {
-	key := fmt.Sprintf("%s%d", PlayerDetailsPrefix, playerID)
+	key := s.playerKey(playerID)
 
 	result, err := s.redis.HGetAll(s.ctx, key).Result()
 	if err != nil || len(result) == 0 {
 		return nil, err
 	}
 
-	combatPower, _ := strconv.ParseInt(result["combat_power"], 10, 64)
-	totalPoints, _ := strconv.Atoi(result["total_points"])
-	wins, _ := strconv.Atoi(result["wins"])
-	losses, _ := strconv.Atoi(result["losses"])
-	winRate, _ := strconv.ParseFloat(result["win_rate"], 64)
-	updatedAt, _ := time.Parse(time.RFC3339, result["updated_at"])
+	return playerFromHash(playerID, result), nil
+}
+
+// playerFromHash reads the player details cached by CachePlayerDetails
+func playerFromHash(playerID uint, data map[string]string) *model.PlayerRanking {
+	combatPower, _ := strconv.ParseInt(data["combat_power"], 10, 64)
+	totalPoints, _ := strconv.Atoi(data["total_points"])
+	wins, _ := strconv.Atoi(data["wins"])
+	losses, _ := strconv.Atoi(data["losses"])
+	winRate, _ := strconv.ParseFloat(data["win_rate"], 64)
+	updatedAt, _ := time.Parse(time.RFC3339, data["updated_at"])
+	rating, _ := strconv.ParseFloat(data["rating"], 64)
+	deviation, _ := strconv.ParseFloat(data["rating_deviation"], 64)
+	volatility, _ := strconv.ParseFloat(data["volatility"], 64)
+	conservative, _ := strconv.ParseFloat(data["conservative_rating"], 64)
 
 	return &model.PlayerRanking{
-		PlayerID:    playerID,
-		Username:    result["username"],
-		CombatPower: combatPower,
-		TotalPoints: totalPoints,
-		Wins:        wins,
-		Losses:      losses,
-		WinRate:     winRate,
-		UpdatedAt:   updatedAt,
-	}, nil
+		PlayerID:           playerID,
+		Username:           data["username"],
+		CombatPower:        combatPower,
+		TotalPoints:        totalPoints,
+		Wins:               wins,
+		Losses:             losses,
+		WinRate:            winRate,
+		UpdatedAt:          updatedAt,
+		Rating:             rating,
+		RatingDeviation:    deviation,
+		Volatility:         volatility,
+		ConservativeRating: conservative,
+	}
 }
 
 // RemovePlayer removes a player from the leaderboard and deletes their cached details
 func (s *LeaderboardService) RemovePlayer(playerID uint) error {
-	key := fmt.Sprintf("%s%d", PlayerDetailsPrefix, playerID)
+	key := s.playerKey(playerID)
 	pipe := s.redis.Pipeline()
-	pipe.ZRem(s.ctx, LeaderboardKey, fmt.Sprintf("%d", playerID))
+	pipe.ZRem(s.ctx, s.key(LeaderboardKey), fmt.Sprintf("%d", playerID))
+	pipe.ZRem(s.ctx, s.key(RatingLeaderboardKey), fmt.Sprintf("%d", playerID))
+	s.removeFromWindows(pipe, playerID)
 	pipe.Del(s.ctx, key)
 	_, err := pipe.Exec(s.ctx)
 
@@ -252,7 +354,7 @@ func (s *LeaderboardService) BatchGetPlayerDetails(playerIDs []uint) (map[uint]*
 	cmds := make(map[uint]*redis.StringStringMapCmd)
 
 	for _, playerID := range playerIDs {
-		key := fmt.Sprintf("%s%d", PlayerDetailsPrefix, playerID)
+		key := s.playerKey(playerID)
 		cmds[playerID] = pipe.HGetAll(s.ctx, key)
 	}
 
@@ -268,23 +370,7 @@ func (s *LeaderboardService) BatchGetPlayerDetails(playerIDs []uint) (map[uint]*
 			continue
 		}
 
-		combatPower, _ := strconv.ParseInt(data["combat_power"], 10, 64)
-		totalPoints, _ := strconv.Atoi(data["total_points"])
-		wins, _ := strconv.Atoi(data["wins"])
-		losses, _ := strconv.Atoi(data["losses"])
-		winRate, _ := strconv.ParseFloat(data["win_rate"], 64)
-		updatedAt, _ := time.Parse(time.RFC3339, data["updated_at"])
-
-		result[playerID] = &model.PlayerRanking{
-			PlayerID:    playerID,
-			Username:    data["username"],
-			CombatPower: combatPower,
-			TotalPoints: totalPoints,
-			Wins:        wins,
-			Losses:      losses,
-			WinRate:     winRate,
-			UpdatedAt:   updatedAt,
-		}
+		result[playerID] = playerFromHash(playerID, data)
 	}
 
 	return result, nil
@@ -292,7 +378,7 @@ func (s *LeaderboardService) BatchGetPlayerDetails(playerIDs []uint) (map[uint]*
 
 // GetThreshold returns the current top 10K threshold
 func (s *LeaderboardService) GetThreshold() (int64, error) {
-	val, err := s.redis.Get(s.ctx, ThresholdKey).Int64()
+	val, err := s.redis.Get(s.ctx, s.key(ThresholdKey)).Int64()
 	if err == redis.Nil {
 		return 0, nil
 	}
@@ -302,19 +388,19 @@ func (s *LeaderboardService) GetThreshold() (int64, error) {
 // updateThreshold updates the threshold to the 10,000th player's score
 func (s *LeaderboardService) updateThreshold() error {
 	// Get the score of the 10,000th player (0-indexed: 9999)
-	result, err := s.redis.ZRevRangeWithScores(s.ctx, LeaderboardKey, Top10KLimit-1, Top10KLimit-1).Result()
+	result, err := s.redis.ZRevRangeWithScores(s.ctx, s.key(LeaderboardKey), Top10KLimit-1, Top10KLimit-1).Result()
 	if err != nil || len(result) == 0 {
 		return err
 	}
 
 	threshold := int64(result[0].Score)
-	return s.redis.Set(s.ctx, ThresholdKey, threshold, 0).Err()
+	return s.redis.Set(s.ctx, s.key(ThresholdKey), threshold, 0).Err()
 }
 
 // SetMetadata sets leaderboard metadata
 func (s *LeaderboardService) SetMetadata(totalPlayers int64) error {
 	pipe := s.redis.Pipeline()
-	pipe.Set(s.ctx, TotalPlayersKey, totalPlayers, 0)
+	pipe.Set(s.ctx, s.key(TotalPlayersKey), totalPlayers, 0)
 	pipe.Set(s.ctx, LastSyncKey, time.Now().Format(time.RFC3339), 0)
 	_, err := pipe.Exec(s.ctx)
 	return err
@@ -323,8 +409,8 @@ func (s *LeaderboardService) SetMetadata(totalPlayers int64) error {
 // GetMetadata retrieves leaderboard metadata
 func (s *LeaderboardService) GetMetadata() (*model.LeaderboardMetadata, error) {
 	pipe := s.redis.Pipeline()
-	totalCmd := pipe.Get(s.ctx, TotalPlayersKey)
-	thresholdCmd := pipe.Get(s.ctx, ThresholdKey)
+	totalCmd := pipe.Get(s.ctx, s.key(TotalPlayersKey))
+	thresholdCmd := pipe.Get(s.ctx, s.key(ThresholdKey))
 	lastSyncCmd := pipe.Get(s.ctx, LastSyncKey)
 	_, err := pipe.Exec(s.ctx)
 
@@ -360,14 +446,40 @@ func (s *LeaderboardService) ReleaseSyncLock() error {
 	return s.redis.Del(s.ctx, SyncLockKey).Err()
 }
 
+// AcquireLock acquires a distributed lock so only one instance runs a
+// periodic job at a time
+func (s *LeaderboardService) AcquireLock(key string) (bool, error) {
+	return s.redis.SetNX(s.ctx, key, s.instanceID, LockTimeout).Result()
+}
+
+// ReleaseLock releases a lock taken with AcquireLock if this instance holds it
+func (s *LeaderboardService) ReleaseLock(key string) error {
+	val, err := s.redis.Get(s.ctx, key).Result()
+	if err != nil || val != s.instanceID {
+		return err
+	}
+	return s.redis.Del(s.ctx, key).Err()
+}
+
+// ExpireSeason lets an ended season's leaderboards expire. Its cached player
+// details expire on their own.
+func (s *LeaderboardService) ExpireSeason(seasonID uint) error {
+	pipe := s.redis.Pipeline()
+	for _, format := range []string{LeaderboardKey, RatingLeaderboardKey, ThresholdKey, TotalPlayersKey} {
+		pipe.Expire(s.ctx, fmt.Sprintf(format, seasonID), EndedSeasonTTL)
+	}
+	_, err := pipe.Exec(s.ctx)
+	return err
+}
+
 // ClearLeaderboard clears the entire leaderboard (use with caution)
 func (s *LeaderboardService) ClearLeaderboard() error {
-	return s.redis.Del(s.ctx, LeaderboardKey).Err()
+	return s.redis.Del(s.ctx, s.key(LeaderboardKey), s.key(RatingLeaderboardKey)).Err()
 }
 
 // GetLeaderboardSize returns the current size of the leaderboard
 func (s *LeaderboardService) GetLeaderboardSize() (int64, error) {
-	return s.redis.ZCard(s.ctx, LeaderboardKey).Result()
+	return s.redis.ZCard(s.ctx, s.key(LeaderboardKey)).Result()
 }
 
 // PublishPowerUpdateEvent publishes a power update event (for pub/sub pattern)
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"maushold/ranking-service/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Time-windowed leaderboards rank players by the points they gained in the
+// current day, ISO week or month (UTC). Each window has one key per bucket,
+// which expires a day after the bucket ends.
+const (
+	WindowAll     = "all" // the season leaderboard
+	WindowDaily   = "daily"
+	WindowWeekly  = "weekly"
+	WindowMonthly = "monthly"
+
+	WindowLeaderboardKey = "leaderboard:window:%s:%s" // window, bucket
+	windowGrace          = 24 * time.Hour
+)
+
+var windows = []string{WindowDaily, WindowWeekly, WindowMonthly}
+
+// windowBucket returns the bucket t falls in for a window and when it ends
+func windowBucket(window string, t time.Time) (string, time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch window {
+	case WindowDaily:
+		return day.Format("2006-01-02"), day.AddDate(0, 0, 1), nil
+	case WindowWeekly:
+		year, week := t.ISOWeek()
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return fmt.Sprintf("%d-W%02d", year, week), monday.AddDate(0, 0, 7), nil
+	case WindowMonthly:
+		month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return month.Format("2006-01"), month.AddDate(0, 1, 0), nil
+	}
+	return "", time.Time{}, ErrInvalidWindow
+}
+
+// windowStart returns when the bucket t falls in for a window began
+func windowStart(window string, t time.Time) (time.Time, error) {
+	_, endsAt, err := windowBucket(window, t)
+	switch window {
+	case WindowDaily:
+		return endsAt.AddDate(0, 0, -1), err
+	case WindowWeekly:
+		return endsAt.AddDate(0, 0, -7), err
+	}
+	return endsAt.AddDate(0, -1, 0), err
+}
+
+// AddWindowPoints adds points a player gained at the given time to every
+// windowed leaderboard. The boards only count gains, so points <= 0 are ignored.
+func (s *LeaderboardService) AddWindowPoints(playerID uint, points int, at time.Time) error {
+	if points <= 0 {
+		return nil
+	}
+	member := fmt.Sprintf("%d", playerID)
+
+	pipe := s.redis.Pipeline()
+	for _, window := range windows {
+		bucket, endsAt, _ := windowBucket(window, at)
+		key := fmt.Sprintf(WindowLeaderboardKey, window, bucket)
+		pipe.ZIncrBy(s.ctx, key, float64(points), member)
+		pipe.ExpireAt(s.ctx, key, endsAt.Add(windowGrace))
+	}
+	_, err := pipe.Exec(s.ctx)
+	return err
+}
+
+// ReplaceWindowPoints sets the bucket at falls in for a window to exactly
+// the points gained in it
+func (s *LeaderboardService) ReplaceWindowPoints(window string, gained []model.PointsGained, at time.Time) error {
+	bucket, endsAt, err := windowBucket(window, at)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf(WindowLeaderboardKey, window, bucket)
+
+	members := make([]*redis.Z, 0, len(gained))
+	for _, g := range gained {
+		members = append(members, &redis.Z{Score: float64(g.Points), Member: fmt.Sprintf("%d", g.PlayerID)})
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Del(s.ctx, key)
+	if len(members) > 0 {
+		pipe.ZAdd(s.ctx, key, members...)
+		pipe.ExpireAt(s.ctx, key, endsAt.Add(windowGrace))
+	}
+	_, err = pipe.Exec(s.ctx)
+	return err
+}
+
+// GetTopPlayersInWindow returns the top N players of a window's current
+// bucket by points gained, how many players are on it and when it ends
+func (s *LeaderboardService) GetTopPlayersInWindow(window string, limit int) ([]model.LeaderboardEntry, int64, time.Time, error) {
+	bucket, endsAt, err := windowBucket(window, time.Now())
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	key := fmt.Sprintf(WindowLeaderboardKey, window, bucket)
+
+	pipe := s.redis.Pipeline()
+	rangeCmd := pipe.ZRevRangeWithScores(s.ctx, key, 0, int64(limit-1))
+	countCmd := pipe.ZCard(s.ctx, key)
+	if _, err := pipe.Exec(s.ctx); err != nil && err != redis.Nil {
+		return nil, 0, time.Time{}, err
+	}
+
+	entries := make([]model.LeaderboardEntry, 0, len(rangeCmd.Val()))
+	for i, z := range rangeCmd.Val() {
+		playerID, _ := strconv.ParseUint(z.Member.(string), 10, 64)
+		entries = append(entries, model.LeaderboardEntry{
+			PlayerID:     uint(playerID),
+			PointsGained: int(z.Score),
+			Rank:         i + 1,
+		})
+	}
+
+	return entries, countCmd.Val(), endsAt, nil
+}
+
+// removeFromWindows takes a player off the current bucket of every window
+func (s *LeaderboardService) removeFromWindows(pipe redis.Pipeliner, playerID uint) {
+	now := time.Now()
+	for _, window := range windows {
+		bucket, _, _ := windowBucket(window, now)
+		pipe.ZRem(s.ctx, fmt.Sprintf(WindowLeaderboardKey, window, bucket), fmt.Sprintf("%d", playerID))
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"maushold/ranking-service/model"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestWindowBucket(t *testing.T) {
+	tests := []struct {
+		window     string
+		at         time.Time
+		wantBucket string
+		wantEnds   time.Time
+		wantStarts time.Time
+	}{
+		{WindowDaily, time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC), "2026-03-10",
+			time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		// Buckets are in UTC whatever zone the time is given in
+		{WindowDaily, time.Date(2026, 3, 11, 1, 0, 0, 0, time.FixedZone("CET", 3600)), "2026-03-11",
+			time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{WindowWeekly, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), "2026-W11",
+			time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		// The first days of January can belong to the last ISO week of the year before
+		{WindowWeekly, time.Date(2027, 1, 1, 8, 0, 0, 0, time.UTC), "2026-W53",
+			time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC)},
+		{WindowMonthly, time.Date(2026, 2, 28, 18, 0, 0, 0, time.UTC), "2026-02",
+			time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		bucket, ends, err := windowBucket(tt.window, tt.at)
+		if err != nil || bucket != tt.wantBucket || !ends.Equal(tt.wantEnds) {
+			t.Errorf("windowBucket(%s, %s) = %s ending %s, %v; want %s ending %s",
+				tt.window, tt.at, bucket, ends, err, tt.wantBucket, tt.wantEnds)
+		}
+		if starts, _ := windowStart(tt.window, tt.at); !starts.Equal(tt.wantStarts) {
+			t.Errorf("windowStart(%s, %s) = %s, want %s", tt.window, tt.at, starts, tt.wantStarts)
+		}
+	}
+
+	if _, _, err := windowBucket("yearly", time.Now()); err != ErrInvalidWindow {
+		t.Errorf("unknown window: err = %v, want %v", err, ErrInvalidWindow)
+	}
+}
+
+func newTestLeaderboard(t *testing.T) (*LeaderboardService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewLeaderboardService(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestAddWindowPoints(t *testing.T) {
+	s, mr := newTestLeaderboard(t)
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	mr.SetTime(at)
+
+	if err := s.AddWindowPoints(7, 20, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddWindowPoints(7, 5, at.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddWindowPoints(8, -15, at); err != nil {
+		t.Fatal(err)
+	}
+
+	expiries := map[string]time.Duration{
+		"leaderboard:window:daily:2026-03-10": 12*time.Hour + windowGrace,
+		"leaderboard:window:weekly:2026-W11":  5*24*time.Hour + 12*time.Hour + windowGrace,
+		"leaderboard:window:monthly:2026-03":  21*24*time.Hour + 12*time.Hour + windowGrace,
+	}
+	for key, ttl := range expiries {
+		if score, err := mr.ZScore(key, "7"); err != nil || score != 25 {
+			t.Errorf("%s: player 7 has %v (%v), want 25", key, score, err)
+		}
+		if members, _ := mr.ZMembers(key); len(members) != 1 {
+			t.Errorf("%s has %v, want only player 7; losses don't count", key, members)
+		}
+		if got := mr.TTL(key); got != ttl {
+			t.Errorf("%s expires in %s, want %s", key, got, ttl)
+		}
+	}
+}
+
+func TestReplaceWindowPoints(t *testing.T) {
+	s, mr := newTestLeaderboard(t)
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	mr.SetTime(at)
+	key := "leaderboard:window:daily:2026-03-10"
+
+	s.AddWindowPoints(7, 20, at)
+	s.AddWindowPoints(9, 30, at)
+
+	err := s.ReplaceWindowPoints(WindowDaily, []model.PointsGained{{PlayerID: 7, Points: 45}, {PlayerID: 8, Points: 10}}, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := mr.ZMembers(key); len(members) != 2 {
+		t.Errorf("%s has %v, want players 7 and 8", key, members)
+	}
+	if score, _ := mr.ZScore(key, "7"); score != 45 {
+		t.Errorf("player 7 has %v, want 45", score)
+	}
+	if got, want := mr.TTL(key), 12*time.Hour+windowGrace; got != want {
+		t.Errorf("expires in %s, want %s", got, want)
+	}
+
+	if err := s.ReplaceWindowPoints(WindowDaily, nil, at); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(key) {
+		t.Errorf("%s still exists after replacing it with nothing", key)
+	}
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"errors"
 	"log"
+	"sync"
 	"time"
 
 	"maushold/ranking-service/model"
@@ -10,11 +12,32 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	SortCombatPower = "combat_power"
+	SortRating      = "rating" // conservative Glicko-2 rating
+)
+
+// ProcessedBattleRetention is how long recorded battles are kept: longer
+// than the monthly leaderboard they are rebuilt from, and far beyond how
+// long a battle.completed event can be retried or sit in the dead-letter
+// queue before someone replays it
+const ProcessedBattleRetention = 45 * 24 * time.Hour
+
+var (
+	ErrInvalidSort   = errors.New("sort must be combat_power or rating")
+	ErrInvalidWindow = errors.New("window must be all, daily, weekly or monthly")
+)
+
 type RankingService interface {
 	UpdatePlayerRanking(playerID uint, pointsDelta int, isWin bool) error
+	RecordBattle(battleID, winnerID, loserID uint, pointsWon, pointsLost int) error
+	DecayInactiveRatings() error
 	UpdatePlayerCombatPower(playerID uint, combatPower int64) error
 	GetPlayerRanking(playerID uint) (*model.PlayerRanking, error)
-	GetLeaderboard(limit int) (*model.LeaderboardResponse, error)
+	GetLeaderboard(limit int, sortBy string) (*model.LeaderboardResponse, error)
+	GetWindowLeaderboard(window string, limit int) (*model.LeaderboardResponse, error)
+	RebuildWindowLeaderboards() error
+	PruneProcessedBattles() error
 	GetPlayerRankWithContext(playerID uint, contextSize int) (*model.PlayerRankContext, error)
 	SyncRankings() error
 	StartPeriodicSync()
@@ -22,88 +45,282 @@ type RankingService interface {
 	SyncFromPlayerService() error
 	DeletePlayerRanking(playerID uint) error
 	SyncLeaderboardWithDB() error
+	GetSeasons() ([]model.Season, error)
+	GetCurrentSeason() (*model.Season, error)
+	GetSeasonStandings(seasonID uint, limit int) (*model.SeasonStandingsResponse, error)
+	ArchiveEndedSeasons() error
+	SnapshotRanks() error
+	GetRankHistory(playerID uint, from, to time.Time) (*model.RankHistory, error)
 }
 
 type rankingService struct {
 	repo               repository.RankingRepository
+	seasonRepo         repository.SeasonRepository
+	historyRepo        repository.HistoryRepository
 	playerClient       *PlayerClient
 	battleClient       *BattleClient
 	leaderboardService *LeaderboardService
+	publisher          EventPublisher
+	seasonLength       time.Duration
+
+	seasonMu sync.Mutex
+	season   *model.Season
 }
 
 func NewRankingService(
 	repo repository.RankingRepository,
+	seasonRepo repository.SeasonRepository,
+	historyRepo repository.HistoryRepository,
 	playerClient *PlayerClient,
 	battleClient *BattleClient,
 	leaderboardService *LeaderboardService,
+	publisher EventPublisher,
+	seasonLength time.Duration,
 ) RankingService {
 	return &rankingService{
 		repo:               repo,
+		seasonRepo:         seasonRepo,
+		historyRepo:        historyRepo,
 		playerClient:       playerClient,
 		battleClient:       battleClient,
 		leaderboardService: leaderboardService,
+		publisher:          publisher,
+		seasonLength:       seasonLength,
 	}
 }
 
 // UpdatePlayerRanking updates player stats after a battle
 func (s *rankingService) UpdatePlayerRanking(playerID uint, pointsDelta int, isWin bool) error {
-	ranking, err := s.repo.FindByPlayerID(playerID)
+	ranking, err := s.findOrNewRanking(playerID)
+	if err != nil {
+		return err
+	}
 
-	if err == gorm.ErrRecordNotFound {
-		player, err := s.playerClient.GetPlayer(playerID)
+	applyBattle(ranking, pointsDelta, isWin)
+	return s.saveRanking(ranking)
+}
+
+// RecordBattle updates both players after a battle, moving their Glicko-2
+// ratings against each other's rating from before the battle. Each battle
+// is recorded once; recording it again returns
+// repository.ErrBattleAlreadyRecorded.
+func (s *rankingService) RecordBattle(battleID, winnerID, loserID uint, pointsWon, pointsLost int) error {
+	// Players new to the season get a ranking first, so both can be locked
+	// while the battle is applied
+	if err := s.createMissingRankings(winnerID, loserID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	battle := &model.ProcessedBattle{
+		BattleID:    battleID,
+		WinnerID:    winnerID,
+		LoserID:     loserID,
+		PointsWon:   pointsWon,
+		ProcessedAt: now,
+	}
+	var winner, loser *model.PlayerRanking
+	err := s.repo.SaveBattle(battle, func(w, l *model.PlayerRanking) {
+		winnerRating, _ := decayedRating(w, now)
+		loserRating, _ := decayedRating(l, now)
+
+		setRating(w, UpdateGlicko2(winnerRating, []GlickoResult{{Opponent: loserRating, Score: 1}}), now)
+		setRating(l, UpdateGlicko2(loserRating, []GlickoResult{{Opponent: winnerRating, Score: 0}}), now)
+
+		applyBattle(w, pointsWon, true)
+		applyBattle(l, -pointsLost, false)
+		winner, loser = w, l
+	})
+	if err != nil {
+		return err
+	}
+	s.cacheRanking(winner)
+	s.cacheRanking(loser)
+
+	// Points gained count toward the daily, weekly and monthly leaderboards;
+	// points lost don't take anything off them. If Redis misses them, the
+	// next RebuildWindowLeaderboards puts them back from the recorded battle.
+	if err := s.leaderboardService.AddWindowPoints(winnerID, pointsWon, now); err != nil {
+		log.Printf("Failed to update windowed leaderboards for player %d: %v", winnerID, err)
+	}
+	return nil
+}
+
+// createMissingRankings saves a ranking in the current season for any of
+// the players who don't have one yet
+func (s *rankingService) createMissingRankings(playerIDs ...uint) error {
+	var missing []model.PlayerRanking
+	for _, playerID := range playerIDs {
+		ranking, err := s.findOrNewRanking(playerID)
 		if err != nil {
-			log.Printf("Player %d not found: %v", playerID, err)
 			return err
 		}
-
-		ranking = &model.PlayerRanking{
-			PlayerID:     playerID,
-			Username:     player.Username,
-			TotalPoints:  player.Points + pointsDelta,
-			CombatPower:  int64(player.Points+pointsDelta) * 100, // Combat power = points * 100
-			TotalBattles: 1,
-			LastBattleAt: time.Now(),
+		if ranking.ID == 0 {
+			missing = append(missing, *ranking)
 		}
+	}
+	return s.repo.CreateMissing(missing)
+}
 
-		if isWin {
-			ranking.Wins = 1
-		} else {
-			ranking.Losses = 1
-		}
+// findOrNewRanking returns a player's ranking in the current season. A
+// player who hasn't battled in it yet is carried over from their last season
+// with a soft reset, or seeded from player-service if they have never been
+// ranked; either way the new ranking isn't saved yet.
+func (s *rankingService) findOrNewRanking(playerID uint) (*model.PlayerRanking, error) {
+	season, err := s.currentSeason()
+	if err != nil {
+		return nil, err
+	}
 
-		err = s.repo.Create(ranking)
-	} else if err != nil {
-		return err
+	ranking, err := s.repo.FindByPlayerID(playerID)
+	if err != gorm.ErrRecordNotFound {
+		return ranking, err
+	}
+
+	ranking, err = s.carryOver(playerID, season.ID)
+	if err != gorm.ErrRecordNotFound {
+		return ranking, err
+	}
+
+	player, err := s.playerClient.GetPlayer(playerID)
+	if err != nil {
+		log.Printf("Player %d not found: %v", playerID, err)
+		return nil, err
+	}
+
+	ranking = &model.PlayerRanking{
+		PlayerID:    playerID,
+		SeasonID:    season.ID,
+		Username:    player.Username,
+		TotalPoints: player.Points,
+	}
+	setRating(ranking, Glicko2Rating{DefaultRating, DefaultDeviation, DefaultVolatility}, time.Now())
+	return ranking, nil
+}
+
+// carryOver soft-resets a player's ranking from the last season they were
+// ranked in into seasonID, returning gorm.ErrRecordNotFound if they have
+// never been ranked. The new ranking isn't saved.
+func (s *rankingService) carryOver(playerID, seasonID uint) (*model.PlayerRanking, error) {
+	previous, err := s.repo.FindLatestByPlayerID(playerID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	carried := softReset(previous, seasonID)
+	return &carried, nil
+}
+
+// applyBattle adds a battle's points and result to a player's stats
+func applyBattle(ranking *model.PlayerRanking, pointsDelta int, isWin bool) {
+	ranking.TotalPoints += pointsDelta
+	ranking.CombatPower = int64(ranking.TotalPoints) * 100 // Combat power = points * 100
+	ranking.TotalBattles++
+	ranking.LastBattleAt = time.Now()
+
+	if isWin {
+		ranking.Wins++
 	} else {
-		ranking.TotalPoints += pointsDelta
-		ranking.CombatPower = int64(ranking.TotalPoints) * 100 // Update combat power
-		ranking.TotalBattles++
-		ranking.LastBattleAt = time.Now()
-
-		if isWin {
-			ranking.Wins++
-		} else {
-			ranking.Losses++
-		}
+		ranking.Losses++
+	}
+
+	ranking.WinRate = float64(ranking.Wins) / float64(ranking.TotalBattles) * 100
+}
 
+// saveRanking stores a ranking and updates the Redis leaderboards and cache
+func (s *rankingService) saveRanking(ranking *model.PlayerRanking) error {
+	var err error
+	if ranking.ID == 0 {
+		err = s.repo.Create(ranking)
+	} else {
 		err = s.repo.Update(ranking)
 	}
-
-	if ranking.TotalBattles > 0 {
-		ranking.WinRate = float64(ranking.Wins) / float64(ranking.TotalBattles) * 100
-		s.repo.Update(ranking)
+	if err != nil {
+		return err
 	}
 
-	// Update Redis leaderboard with combat power (threshold-based)
-	s.leaderboardService.UpdatePlayerScore(playerID, ranking.CombatPower)
+	s.cacheRanking(ranking)
+	return nil
+}
+
+// cacheRanking updates the Redis leaderboards and cache with a saved ranking
+func (s *rankingService) cacheRanking(ranking *model.PlayerRanking) {
+	// Update Redis leaderboards (combat power is threshold-based)
+	s.leaderboardService.UpdatePlayerScore(ranking.PlayerID, ranking.CombatPower)
+	s.leaderboardService.UpdatePlayerRating(ranking.PlayerID, ranking.ConservativeRating)
 
 	// Cache player details in Redis
 	s.leaderboardService.CachePlayerDetails(ranking)
 
-	log.Printf("Updated ranking for player %d: CombatPower=%d, Points=%d, W/L=%d/%d",
-		playerID, ranking.CombatPower, ranking.TotalPoints, ranking.Wins, ranking.Losses)
+	log.Printf("Updated ranking for player %d: CombatPower=%d, Points=%d, Rating=%.0f±%.0f, W/L=%d/%d",
+		ranking.PlayerID, ranking.CombatPower, ranking.TotalPoints, ranking.Rating, ranking.RatingDeviation,
+		ranking.Wins, ranking.Losses)
+}
+
+// decayedRating is a player's Glicko-2 rating with the deviation grown for
+// every whole rating period since it was last updated, and the time the
+// decay is counted up to
+func decayedRating(ranking *model.PlayerRanking, now time.Time) (Glicko2Rating, time.Time) {
+	rating := Glicko2Rating{ranking.Rating, ranking.RatingDeviation, ranking.Volatility}
+	if rating.Deviation <= 0 || rating.Volatility <= 0 {
+		rating = Glicko2Rating{DefaultRating, DefaultDeviation, DefaultVolatility}
+	}
+
+	since := ranking.RatedAt
+	if since.IsZero() || since.Before(ranking.LastBattleAt) {
+		since = ranking.LastBattleAt
+	}
 
-	return err
+	periods := inactivePeriods(since, now)
+	if periods == 0 {
+		return rating, since
+	}
+	return DecayGlicko2(rating, float64(periods)), since.Add(time.Duration(periods) * RatingPeriod)
+}
+
+func setRating(ranking *model.PlayerRanking, rating Glicko2Rating, ratedAt time.Time) {
+	ranking.Rating = rating.Rating
+	ranking.RatingDeviation = rating.Deviation
+	ranking.Volatility = rating.Volatility
+	ranking.ConservativeRating = rating.Conservative()
+	ranking.RatedAt = ratedAt
+}
+
+// DecayInactiveRatings grows the deviation of players who haven't battled
+// for a rating period or more, so their rating counts for less until they
+// play again
+func (s *rankingService) DecayInactiveRatings() error {
+	locked, err := s.leaderboardService.AcquireLock(DecayLockKey)
+	if err != nil || !locked {
+		return err
+	}
+	defer s.leaderboardService.ReleaseLock(DecayLockKey)
+
+	now := time.Now()
+	rankings, err := s.repo.FindInactiveSince(now.Add(-RatingPeriod))
+	if err != nil {
+		return err
+	}
+
+	decayed := 0
+	for i := range rankings {
+		ranking := &rankings[i]
+		rating, ratedAt := decayedRating(ranking, now)
+		if !ratedAt.After(ranking.RatedAt) {
+			continue
+		}
+
+		setRating(ranking, rating, ratedAt)
+		if err := s.repo.Update(ranking); err != nil {
+			log.Printf("Failed to decay rating of player %d: %v", ranking.PlayerID, err)
+			continue
+		}
+		s.leaderboardService.UpdatePlayerRating(ranking.PlayerID, ranking.ConservativeRating)
+		s.leaderboardService.CachePlayerDetails(ranking)
+		decayed++
+	}
+
+	log.Printf("Decayed rating deviation of %d inactive players", decayed)
+	return nil
 }
 
 // UpdatePlayerCombatPower directly updates a player's combat power
@@ -130,9 +347,14 @@ func (s *rankingService) UpdatePlayerCombatPower(playerID uint, combatPower int6
 
 // GetPlayerRanking returns a player's ranking information
 func (s *rankingService) GetPlayerRanking(playerID uint) (*model.PlayerRanking, error) {
+	season, err := s.currentSeason()
+	if err != nil {
+		return nil, err
+	}
+
 	// Try Redis cache first (fastest for detail lookup)
 	cachedPlayer, err := s.leaderboardService.GetPlayerDetails(playerID)
-	if err == nil && cachedPlayer != nil {
+	if err == nil && cachedPlayer != nil && cachedPlayer.RatingDeviation > 0 {
 		// Also try to get rank from Redis
 		rank, err := s.leaderboardService.GetPlayerRank(playerID)
 		if err == nil && rank > 0 {
@@ -141,8 +363,12 @@ func (s *rankingService) GetPlayerRanking(playerID uint) (*model.PlayerRanking,
 		}
 	}
 
-	// Fallback to DB
+	// Fallback to DB. A player who hasn't battled since the season rolled
+	// over is shown with the ranking they will carry into it.
 	ranking, err := s.repo.FindByPlayerID(playerID)
+	if err == gorm.ErrRecordNotFound {
+		ranking, err = s.carryOver(playerID, season.ID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +401,21 @@ func (s *rankingService) GetPlayerRanking(playerID uint) (*model.PlayerRanking,
 }
 
 // GetLeaderboard returns the top N players with metadata
-func (s *rankingService) GetLeaderboard(limit int) (*model.LeaderboardResponse, error) {
+func (s *rankingService) GetLeaderboard(limit int, sortBy string) (*model.LeaderboardResponse, error) {
+	switch sortBy {
+	case "", SortCombatPower:
+	case SortRating:
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	if _, err := s.currentSeason(); err != nil {
+		return nil, err
+	}
+	if sortBy == SortRating {
+		return s.getRatingLeaderboard(limit)
+	}
+
 	// Try Redis cache first (highest performance for high traffic)
 	redisEntries, err := s.leaderboardService.GetTopPlayers(limit)
 	if err == nil && len(redisEntries) > 0 {
@@ -226,8 +466,102 @@ func (s *rankingService) GetLeaderboard(limit int) (*model.LeaderboardResponse,
 	}, nil
 }
 
+// getRatingLeaderboard returns the top N players by conservative rating
+func (s *rankingService) getRatingLeaderboard(limit int) (*model.LeaderboardResponse, error) {
+	redisEntries, err := s.leaderboardService.GetTopPlayersByRating(limit)
+	if err == nil && len(redisEntries) > 0 {
+		metadata, _ := s.getMetadata(true)
+		return &model.LeaderboardResponse{
+			Leaderboard: s.enrichLeaderboardEntries(redisEntries),
+			Metadata:    *metadata,
+		}, nil
+	}
+
+	rankings, err := s.repo.FindTopNByConservativeRating(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.LeaderboardEntry, len(rankings))
+	for i, r := range rankings {
+		entries[i] = leaderboardEntry(&r, i+1)
+	}
+
+	metadata, _ := s.getMetadata(false)
+	return &model.LeaderboardResponse{
+		Leaderboard: entries,
+		Metadata:    *metadata,
+	}, nil
+}
+
+// GetWindowLeaderboard returns the top N players by points gained in the
+// current day, week or month
+func (s *rankingService) GetWindowLeaderboard(window string, limit int) (*model.LeaderboardResponse, error) {
+	if _, err := s.currentSeason(); err != nil {
+		return nil, err
+	}
+
+	entries, total, endsAt, err := s.leaderboardService.GetTopPlayersInWindow(window, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LeaderboardResponse{
+		Leaderboard: s.enrichLeaderboardEntries(entries),
+		Metadata: model.LeaderboardMetadata{
+			TotalPlayers: total,
+			LastUpdated:  time.Now(),
+			CacheHit:     true,
+			Window:       window,
+			WindowEndsAt: &endsAt,
+		},
+	}, nil
+}
+
+// RebuildWindowLeaderboards recomputes the current bucket of every windowed
+// leaderboard from the battles recorded in it, putting back any points Redis
+// missed
+func (s *rankingService) RebuildWindowLeaderboards() error {
+	now := time.Now()
+	for _, window := range windows {
+		start, err := windowStart(window, now)
+		if err != nil {
+			return err
+		}
+		gained, err := s.repo.FindPointsGainedSince(start)
+		if err != nil {
+			return err
+		}
+		if err := s.leaderboardService.ReplaceWindowPoints(window, gained, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func leaderboardEntry(r *model.PlayerRanking, rank int) model.LeaderboardEntry {
+	return model.LeaderboardEntry{
+		PlayerID:           r.PlayerID,
+		Username:           r.Username,
+		CombatPower:        r.CombatPower,
+		TotalPoints:        r.TotalPoints,
+		Wins:               r.Wins,
+		Losses:             r.Losses,
+		WinRate:            r.WinRate,
+		Rank:               rank,
+		UpdatedAt:          r.UpdatedAt,
+		Rating:             r.Rating,
+		RatingDeviation:    r.RatingDeviation,
+		ConservativeRating: r.ConservativeRating,
+	}
+}
+
 // GetPlayerRankWithContext returns a player's rank with surrounding players
 func (s *rankingService) GetPlayerRankWithContext(playerID uint, contextSize int) (*model.PlayerRankContext, error) {
+	if _, err := s.currentSeason(); err != nil {
+		return nil, err
+	}
+
 	// Get player's rank
 	rank, err := s.leaderboardService.GetPlayerRank(playerID)
 	if err != nil || rank == 0 {
@@ -283,6 +617,10 @@ func (s *rankingService) GetPlayerRankWithContext(playerID uint, contextSize int
 
 // SyncRankings syncs all rankings from DB to Redis (full rebuild)
 func (s *rankingService) SyncRankings() error {
+	if _, err := s.currentSeason(); err != nil {
+		return err
+	}
+
 	// Acquire distributed lock
 	locked, err := s.leaderboardService.AcquireSyncLock()
 	if err != nil || !locked {
@@ -313,6 +651,15 @@ func (s *rankingService) SyncRankings() error {
 		s.leaderboardService.CachePlayerDetails(&ranking)
 	}
 
+	// Rebuild the rating leaderboard
+	rated, err := s.repo.FindTopNByConservativeRating(Top10KLimit)
+	if err != nil {
+		return err
+	}
+	if err := s.leaderboardService.BatchUpdatePlayerRatings(rated); err != nil {
+		return err
+	}
+
 	// Update metadata
 	totalPlayers, _ := s.repo.GetTotalPlayerCount()
 	s.leaderboardService.SetMetadata(totalPlayers)
@@ -342,25 +689,37 @@ func (s *rankingService) SyncFromPlayerService() error {
 		return err
 	}
 
+	season, err := s.currentSeason()
+	if err != nil {
+		return err
+	}
+
+	// Points are per season, so only players new to the season take theirs
+	// from player-service, or from their last season with a soft reset
 	for _, p := range players {
 		ranking, err := s.repo.FindByPlayerID(p.ID)
 		if err == gorm.ErrRecordNotFound {
-			ranking = &model.PlayerRanking{
-				PlayerID:    p.ID,
-				Username:    p.Username,
-				TotalPoints: p.Points,
-				CombatPower: int64(p.Points) * 100,
+			if carried, err := s.carryOver(p.ID, season.ID); err == nil {
+				ranking = carried
+			} else {
+				ranking = &model.PlayerRanking{
+					PlayerID:    p.ID,
+					SeasonID:    season.ID,
+					Username:    p.Username,
+					TotalPoints: p.Points,
+					CombatPower: int64(p.Points) * 100,
+				}
+				setRating(ranking, Glicko2Rating{DefaultRating, DefaultDeviation, DefaultVolatility}, time.Now())
 			}
 			s.repo.Create(ranking)
-		} else if err == nil {
-			ranking.TotalPoints = p.Points
-			ranking.CombatPower = int64(p.Points) * 100
+		} else if err == nil && ranking.Username != p.Username {
+			ranking.Username = p.Username
 			s.repo.Update(ranking)
 		}
 	}
 
 	// Reset all player stats before re-playing history to avoid accumulation
-	log.Println("Resetting all player statistics before history sync...")
+	log.Println("Resetting this season's player statistics before history sync...")
 	if err := s.repo.ResetAllStats(); err != nil {
 		log.Printf("Warning: Failed to reset player stats: %v", err)
 	}
@@ -377,6 +736,15 @@ func (s *rankingService) SyncFromPlayerService() error {
 				continue
 			}
 
+			// Only battles from this season count toward it
+			completedAt := b.CreatedAt
+			if b.CompletedAt != nil {
+				completedAt = *b.CompletedAt
+			}
+			if completedAt.Before(season.StartsAt) {
+				continue
+			}
+
 			// Update winner
 			if b.WinnerID != 0 {
 				s.UpdatePlayerRanking(b.WinnerID, 0, true)
@@ -435,6 +803,24 @@ func (s *rankingService) DeletePlayerRanking(playerID uint) error {
 		return err
 	}
 
+	if err := s.historyRepo.DeleteByPlayerID(playerID); err != nil {
+		log.Printf("Warning: Failed to delete rank history of player %d: %v", playerID, err)
+	}
+
+	return nil
+}
+
+// PruneProcessedBattles deletes recorded battles older than
+// ProcessedBattleRetention
+func (s *rankingService) PruneProcessedBattles() error {
+	pruned, err := s.repo.DeleteProcessedBattlesBefore(time.Now().Add(-ProcessedBattleRetention))
+	if err != nil {
+		log.Printf("Failed to prune processed battles: %v", err)
+		return err
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %d processed battles", pruned)
+	}
 	return nil
 }
 
@@ -445,11 +831,15 @@ func (s *rankingService) SyncLeaderboardWithDB() error {
 
 // StartPeriodicSync starts periodic sync tasks
 func (s *rankingService) StartPeriodicSync() {
-	// Sync Redis every 5 minutes
+	// Sync Redis every 5 minutes, taking a rank snapshot when one is due
 	redisSyncTicker := time.NewTicker(5 * time.Minute)
 	go func() {
 		for range redisSyncTicker.C {
 			s.SyncRankings()
+			s.SnapshotRanks()
+			if err := s.RebuildWindowLeaderboards(); err != nil {
+				log.Printf("Failed to rebuild windowed leaderboards: %v", err)
+			}
 		}
 	}()
 
@@ -461,6 +851,30 @@ func (s *rankingService) StartPeriodicSync() {
 		}
 	}()
 
+	// Start new seasons and archive ended ones
+	seasonTicker := time.NewTicker(seasonCheckInterval)
+	go func() {
+		for range seasonTicker.C {
+			s.ArchiveEndedSeasons()
+		}
+	}()
+
+	// Decay the rating deviation of inactive players every hour
+	decayTicker := time.NewTicker(time.Hour)
+	go func() {
+		for range decayTicker.C {
+			s.DecayInactiveRatings()
+		}
+	}()
+
+	// Forget recorded battles once they can't be redelivered or rebuilt from
+	pruneTicker := time.NewTicker(time.Hour)
+	go func() {
+		for range pruneTicker.C {
+			s.PruneProcessedBattles()
+		}
+	}()
+
 	log.Printf("Periodic sync tasks started")
 }
 
@@ -498,6 +912,12 @@ func (s *rankingService) enrichLeaderboardEntries(entries []model.LeaderboardEnt
 			entries[i].Losses = player.Losses
 			entries[i].WinRate = player.WinRate
 			entries[i].UpdatedAt = player.UpdatedAt
+			entries[i].Rating = player.Rating
+			entries[i].RatingDeviation = player.RatingDeviation
+			entries[i].ConservativeRating = player.ConservativeRating
+			if entries[i].CombatPower == 0 {
+				entries[i].CombatPower = player.CombatPower
+			}
 		}
 	}
 
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"maushold/events"
+	"maushold/ranking-service/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// SoftResetFactor is how much of a player's rating, deviation and points
+	// carries into the next season, measured from where a new player starts
+	SoftResetFactor = 0.5
+
+	// SeasonEndedStandings is how many of the top players season.ended lists
+	SeasonEndedStandings = 100
+
+	seasonCheckInterval = time.Minute
+)
+
+var ErrSeasonNotFound = errors.New("season not found")
+
+// EventPublisher publishes ranking-service events
+type EventPublisher interface {
+	PublishRankingEvent(event events.Event) error
+}
+
+// currentSeason returns the running season, starting the next one if the
+// last has ended, and points the repository and leaderboards at it
+func (s *rankingService) currentSeason() (*model.Season, error) {
+	s.seasonMu.Lock()
+	defer s.seasonMu.Unlock()
+
+	now := time.Now()
+	if s.season != nil && !now.Before(s.season.StartsAt) && now.Before(s.season.EndsAt) {
+		return s.season, nil
+	}
+
+	season, err := s.seasonRepo.FindCurrent(now)
+	if err == gorm.ErrRecordNotFound {
+		season, err = s.startSeason(now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.season = season
+	s.repo.SetSeason(season.ID)
+	s.leaderboardService.SetSeason(season.ID)
+	log.Printf("Current season is %s, ending %s", season.Name, season.EndsAt.Format(time.RFC3339))
+	return season, nil
+}
+
+// startSeason creates the season running at now. Seasons follow each other
+// back to back, skipping any that would have started and ended while the
+// service was down. The first season starts at the beginning of the day so
+// every instance agrees on it.
+func (s *rankingService) startSeason(now time.Time) (*model.Season, error) {
+	var start time.Time
+	latest, err := s.seasonRepo.FindLatest()
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		start = now.Truncate(min(s.seasonLength, 24*time.Hour))
+	case err != nil:
+		return nil, err
+	default:
+		start = latest.EndsAt
+		for !now.Before(start.Add(s.seasonLength)) {
+			start = start.Add(s.seasonLength)
+		}
+	}
+
+	count, err := s.seasonRepo.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	season := &model.Season{
+		Name:     fmt.Sprintf("Season %d", count+1),
+		StartsAt: start,
+		EndsAt:   start.Add(s.seasonLength),
+	}
+	if err := s.seasonRepo.Create(season); err != nil {
+		// Another instance may have started it first
+		if existing, findErr := s.seasonRepo.FindCurrent(now); findErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	if count == 0 {
+		// Rankings from before there were seasons count toward the first
+		if err := s.repo.AdoptUnseasoned(season.ID); err != nil {
+			log.Printf("Failed to move existing rankings into %s: %v", season.Name, err)
+		}
+	}
+
+	log.Printf("Started %s: %s to %s", season.Name,
+		season.StartsAt.Format(time.RFC3339), season.EndsAt.Format(time.RFC3339))
+	return season, nil
+}
+
+// softReset carries a player's ranking into a new season. Their rating,
+// deviation and points keep SoftResetFactor of their distance from a new
+// player's, and their record starts over.
+func softReset(prev *model.PlayerRanking, seasonID uint) model.PlayerRanking {
+	rating, _ := decayedRating(prev, time.Now())
+	rating.Rating = DefaultRating + (rating.Rating-DefaultRating)*SoftResetFactor
+	rating.Deviation = DefaultDeviation - (DefaultDeviation-rating.Deviation)*SoftResetFactor
+
+	points := int(math.Round(float64(prev.TotalPoints) * SoftResetFactor))
+	ranking := model.PlayerRanking{
+		PlayerID:     prev.PlayerID,
+		SeasonID:     seasonID,
+		Username:     prev.Username,
+		TotalPoints:  points,
+		CombatPower:  int64(points) * 100,
+		LastBattleAt: prev.LastBattleAt,
+	}
+	setRating(&ranking, rating, time.Now())
+	return ranking
+}
+
+// ArchiveEndedSeasons archives the final standings of seasons that have
+// ended, carries their players into the current season and publishes
+// season.ended for each
+func (s *rankingService) ArchiveEndedSeasons() error {
+	current, err := s.currentSeason()
+	if err != nil {
+		return err
+	}
+
+	locked, err := s.leaderboardService.AcquireLock(SeasonLockKey)
+	if err != nil || !locked {
+		return err
+	}
+	defer s.leaderboardService.ReleaseLock(SeasonLockKey)
+
+	ended, err := s.seasonRepo.FindEndedUnarchived(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range ended {
+		if err := s.archiveSeason(&ended[i], current); err != nil {
+			log.Printf("Failed to archive %s: %v", ended[i].Name, err)
+			return err
+		}
+	}
+
+	if len(ended) > 0 {
+		s.SyncRankings()
+		s.RefreshMaterializedView()
+	}
+	return nil
+}
+
+func (s *rankingService) archiveSeason(season, next *model.Season) error {
+	rankings, err := s.repo.FindBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+
+	// Carry players over before archiving, so if this fails part way the
+	// season is archived again on the next check rather than left behind.
+	// Players who battled in the next season before this ran were carried
+	// over by findOrNewRanking when their ranking was created, so theirs is
+	// kept rather than reset a second time.
+	carried := make([]model.PlayerRanking, len(rankings))
+	for i := range rankings {
+		carried[i] = softReset(&rankings[i], next.ID)
+	}
+	if err := s.repo.CreateMissing(carried); err != nil {
+		return err
+	}
+
+	standings := seasonStandings(season.ID, rankings)
+	if err := s.seasonRepo.Archive(season, standings); err != nil {
+		return err
+	}
+
+	if err := s.leaderboardService.ExpireSeason(season.ID); err != nil {
+		log.Printf("Failed to expire leaderboards of %s: %v", season.Name, err)
+	}
+
+	top := standings
+	if len(top) > SeasonEndedStandings {
+		top = top[:SeasonEndedStandings]
+	}
+	err = s.publisher.PublishRankingEvent(events.SeasonEnded{
+		SeasonID:     season.ID,
+		Name:         season.Name,
+		StartsAt:     season.StartsAt,
+		EndsAt:       season.EndsAt,
+		TotalPlayers: len(standings),
+		Standings:    eventStandings(top),
+	})
+	if err != nil {
+		log.Printf("Failed to publish season.ended for %s: %v", season.Name, err)
+	}
+
+	log.Printf("Archived %s with %d ranked players", season.Name, len(standings))
+	return nil
+}
+
+// seasonStandings ranks the players who battled in a season from its
+// rankings, which are ordered by combat power
+func seasonStandings(seasonID uint, rankings []model.PlayerRanking) []model.SeasonStanding {
+	standings := make([]model.SeasonStanding, 0, len(rankings))
+	for _, r := range rankings {
+		if r.TotalBattles == 0 {
+			continue
+		}
+		standings = append(standings, model.SeasonStanding{
+			SeasonID:           seasonID,
+			Rank:               len(standings) + 1,
+			PlayerID:           r.PlayerID,
+			Username:           r.Username,
+			CombatPower:        r.CombatPower,
+			TotalPoints:        r.TotalPoints,
+			Wins:               r.Wins,
+			Losses:             r.Losses,
+			WinRate:            r.WinRate,
+			Rating:             r.Rating,
+			RatingDeviation:    r.RatingDeviation,
+			ConservativeRating: r.ConservativeRating,
+		})
+	}
+	return standings
+}
+
+func eventStandings(standings []model.SeasonStanding) []events.SeasonStanding {
+	out := make([]events.SeasonStanding, len(standings))
+	for i, s := range standings {
+		out[i] = events.SeasonStanding{
+			Rank:               s.Rank,
+			PlayerID:           s.PlayerID,
+			Username:           s.Username,
+			CombatPower:        s.CombatPower,
+			TotalPoints:        s.TotalPoints,
+			Wins:               s.Wins,
+			Losses:             s.Losses,
+			WinRate:            s.WinRate,
+			Rating:             s.Rating,
+			RatingDeviation:    s.RatingDeviation,
+			ConservativeRating: s.ConservativeRating,
+		}
+	}
+	return out
+}
+
+// GetSeasons returns every season, newest first
+func (s *rankingService) GetSeasons() ([]model.Season, error) {
+	if _, err := s.currentSeason(); err != nil {
+		return nil, err
+	}
+	return s.seasonRepo.FindAll()
+}
+
+// GetCurrentSeason returns the running season
+func (s *rankingService) GetCurrentSeason() (*model.Season, error) {
+	return s.currentSeason()
+}
+
+// GetSeasonStandings returns a season's top players: the archived final
+// standings once it has ended, the live ones until then
+func (s *rankingService) GetSeasonStandings(seasonID uint, limit int) (*model.SeasonStandingsResponse, error) {
+	season, err := s.seasonRepo.FindByID(seasonID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrSeasonNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if season.ArchivedAt != nil {
+		standings, err := s.seasonRepo.FindStandings(seasonID, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &model.SeasonStandingsResponse{Season: *season, Final: true, Standings: standings}, nil
+	}
+
+	rankings, err := s.repo.FindTopNInSeason(seasonID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SeasonStandingsResponse{
+		Season:    *season,
+		Standings: seasonStandings(seasonID, rankings),
+	}, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"maushold/ranking-service/model"
+)
+
+func TestSoftResetPullsTowardNewPlayer(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name                      string
+		rating, deviation         float64
+		points                    int
+		wantRating, wantDeviation float64
+		wantPoints                int
+	}{
+		{"above the mean", 1900, 50, 1001, 1700, 200, 501},
+		{"below the mean", 1300, 150, 200, 1400, 250, 100},
+		{"new player", DefaultRating, DefaultDeviation, 0, DefaultRating, DefaultDeviation, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := &model.PlayerRanking{
+				PlayerID: 7, SeasonID: 3, Username: "ash",
+				TotalPoints: tt.points, CombatPower: int64(tt.points) * 100,
+				TotalBattles: 40, Wins: 30, Losses: 10, WinRate: 75,
+				Rating: tt.rating, RatingDeviation: tt.deviation, Volatility: DefaultVolatility,
+				LastBattleAt: now, RatedAt: now,
+			}
+
+			got := softReset(prev, 4)
+			if got.PlayerID != 7 || got.SeasonID != 4 || got.Username != "ash" || got.ID != 0 {
+				t.Errorf("carried into %+v, want an unsaved ranking of player 7 in season 4", got)
+			}
+			if math.Abs(got.Rating-tt.wantRating) > 0.001 || math.Abs(got.RatingDeviation-tt.wantDeviation) > 0.001 {
+				t.Errorf("rating %.1f±%.1f, want %.1f±%.1f", got.Rating, got.RatingDeviation, tt.wantRating, tt.wantDeviation)
+			}
+			if got.TotalPoints != tt.wantPoints || got.CombatPower != int64(tt.wantPoints)*100 {
+				t.Errorf("points %d (combat power %d), want %d", got.TotalPoints, got.CombatPower, tt.wantPoints)
+			}
+			if got.TotalBattles != 0 || got.Wins != 0 || got.Losses != 0 || got.WinRate != 0 {
+				t.Errorf("record %d battles %d/%d, want it to start over", got.TotalBattles, got.Wins, got.Losses)
+			}
+			if got.ConservativeRating != got.Rating-2*got.RatingDeviation {
+				t.Errorf("conservative rating %.1f doesn't match %.1f±%.1f", got.ConservativeRating, got.Rating, got.RatingDeviation)
+			}
+		})
+	}
+}
+
+func TestSoftResetDecaysInactivePlayersFirst(t *testing.T) {
+	lastBattle := time.Now().Add(-10 * RatingPeriod)
+	prev := &model.PlayerRanking{
+		PlayerID: 7, Rating: 1900, RatingDeviation: 50, Volatility: DefaultVolatility,
+		LastBattleAt: lastBattle, RatedAt: lastBattle,
+	}
+
+	got := softReset(prev, 4)
+	decayed := DecayGlicko2(Glicko2Rating{1900, 50, DefaultVolatility}, 10)
+	want := DefaultDeviation - (DefaultDeviation-decayed.Deviation)*SoftResetFactor
+	if math.Abs(got.RatingDeviation-want) > 0.001 {
+		t.Errorf("deviation %.2f, want %.2f from the decayed %.2f", got.RatingDeviation, want, decayed.Deviation)
+	}
+}